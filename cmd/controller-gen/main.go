@@ -16,21 +16,30 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
+	"sigs.k8s.io/controller-tools/pkg/apidocs"
+	"sigs.k8s.io/controller-tools/pkg/compat"
 	"sigs.k8s.io/controller-tools/pkg/crd"
 	"sigs.k8s.io/controller-tools/pkg/deepcopy"
 	"sigs.k8s.io/controller-tools/pkg/genall"
 	"sigs.k8s.io/controller-tools/pkg/genall/help"
 	prettyhelp "sigs.k8s.io/controller-tools/pkg/genall/help/pretty"
+	"sigs.k8s.io/controller-tools/pkg/jsonschema"
 	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/openapi"
 	"sigs.k8s.io/controller-tools/pkg/rbac"
+	"sigs.k8s.io/controller-tools/pkg/sample"
 	"sigs.k8s.io/controller-tools/pkg/schemapatcher"
 	"sigs.k8s.io/controller-tools/pkg/version"
 	"sigs.k8s.io/controller-tools/pkg/webhook"
@@ -53,6 +62,11 @@ var (
 		"object":      deepcopy.Generator{},
 		"webhook":     webhook.Generator{},
 		"schemapatch": schemapatcher.Generator{},
+		"compat":      compat.Generator{},
+		"sample":      sample.Generator{},
+		"apidocs":     apidocs.Generator{},
+		"jsonschema":  jsonschema.Generator{},
+		"openapi":     openapi.Generator{},
 	}
 
 	// allOutputRules defines the list of all known output rules, giving
@@ -126,6 +140,16 @@ func main() {
 	helpLevel := 0
 	whichLevel := 0
 	showVersion := false
+	verify := false
+	configFile := ""
+	parallel := false
+	watch := false
+	prune := false
+	failOnUnknownMarkers := false
+	warningsAsErrors := false
+	explain := false
+	diagnosticsFormat := ""
+	diagnosticsOutput := ""
 
 	cmd := &cobra.Command{
 		Use:   "controller-gen",
@@ -141,11 +165,55 @@ func main() {
 	# Generate OpenAPI v3 schemas for API packages and merge them into existing CRD manifests
 	controller-gen schemapatch:manifests=./manifests output:dir=./manifests paths=./pkg/apis/... 
 
+	# Check the API types for breaking changes against the CRDs from the last release
+	controller-gen compat:baseline=./release/crds paths=./apis/...
+
+	# Generate sample custom resources for each kind, with every field documented
+	controller-gen sample:full=true paths=./apis/... output:sample:dir=./config/samples
+
+	# Generate API reference documentation as HTML
+	controller-gen apidocs:format=html paths=./apis/... output:apidocs:dir=./docs/reference
+
+	# Export the schema for each kind as a JSON Schema document, for editors
+	controller-gen jsonschema paths=./apis/... output:jsonschema:dir=./schemas
+
+	# Generate an OpenAPI v3 document with the schemata for all the types in ./apis
+	controller-gen openapi:title=widgets,version=v1 paths=./apis/... output:openapi:dir=./openapi
+
 	# Run all the generators for a given project
 	controller-gen paths=./apis/...
 
 	# Explain the markers for generating CRDs, and their arguments
 	controller-gen crd -ww
+
+	# Check that the CRDs and RBAC manifests on disk are up to date, without writing anything
+	controller-gen rbac:roleName=<role name> crd paths=./apis/... output:crd:dir=./config/crd --verify
+
+	# Generate CRDs, removing those for types that no longer exist
+	controller-gen crd paths=./apis/... output:crd:dir=./config/crd/bases --prune
+
+	# Generate CRDs as JSON instead of YAML, and the RBAC role as a single JSON List
+	controller-gen crd:format=json rbac:roleName=<role name>,format=list paths=./apis/...
+
+	# Generate CRDs and deepcopy implementations, both starting with the same license header
+	controller-gen crd:headerFile=./hack/boilerplate.go.txt object:headerFile=./hack/boilerplate.go.txt paths=./apis/...
+
+	# Find out why no CRDs or deepcopy implementations are generated for some packages,
+	# without writing anything
+	controller-gen crd object paths=./apis/... output:none --explain
+
+	# Regenerate CRDs and deepcopy implementations whenever the API types change
+	controller-gen crd object paths=./apis/... --watch
+
+	# Generate docs using an out-of-process plugin (controller-gen-docs on the PATH),
+	# along with CRDs
+	controller-gen plugin:name=docs docs:format=markdown crd paths=./apis/... output:docs:dir=./docs
+
+	# Report problems as SARIF for code scanning tools, instead of as plain text
+	controller-gen crd paths=./apis/... --diagnostics-format=sarif --diagnostics-output=controller-gen.sarif
+
+	# Run the generators listed in a configuration file, overriding its input paths
+	controller-gen --config controller-gen.yaml paths=./apis/v1/...
 `,
 		RunE: func(c *cobra.Command, rawOpts []string) error {
 			// print version if asked for it
@@ -154,6 +222,20 @@ func main() {
 				return nil
 			}
 
+			// merge in options from the config file, if we have one
+			if configFile != "" {
+				fileOpts, err := genall.OptionsFromConfigFile(optionsRegistry, configFile)
+				if err != nil {
+					return noUsageError{err}
+				}
+				rawOpts = genall.MergeOptions(optionsRegistry, fileOpts, rawOpts)
+			}
+
+			// register any plugins, so that their options are known (and show up in help)
+			if err := genall.RegisterPlugins(optionsRegistry, rawOpts); err != nil {
+				return noUsageError{err}
+			}
+
 			// print the help if we asked for it (since we've got a different help flag :-/), then bail
 			if helpLevel > 0 {
 				return c.Usage()
@@ -164,16 +246,63 @@ func main() {
 				return printMarkerDocs(c, rawOpts, whichLevel)
 			}
 
+			// stop (cleanly) when interrupted
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
 			// otherwise, set up the runtime for actually running the generators
-			rt, err := genall.FromOptions(optionsRegistry, rawOpts)
+			rt, err := genall.FromOptionsContext(ctx, optionsRegistry, rawOpts)
 			if err != nil {
+				if errors.As(err, &genall.CanceledError{}) {
+					return noUsageError{err}
+				}
 				return err
 			}
 			if len(rt.Generators) == 0 {
 				return fmt.Errorf("no generators specified")
 			}
+			rt.Verify = verify
+			rt.Parallel = parallel
+			rt.Prune = prune
+			rt.Collector.FailOnUnknownMarkers = failOnUnknownMarkers
+			rt.WarningsAsErrors = warningsAsErrors
+			rt.Explain = explain
+			if verify && prune {
+				return fmt.Errorf("--verify and --prune may not be used together")
+			}
+
+			switch format := genall.DiagnosticsFormat(diagnosticsFormat); format {
+			case genall.DiagnosticsText, genall.DiagnosticsJSON, genall.DiagnosticsSARIF:
+				rt.DiagnosticsFormat = format
+			default:
+				return fmt.Errorf("unknown diagnostics format %q (expected json or sarif)", diagnosticsFormat)
+			}
+			if diagnosticsOutput != "" {
+				out, err := os.Create(diagnosticsOutput)
+				if err != nil {
+					return noUsageError{err}
+				}
+				defer out.Close()
+				rt.ErrorWriter = out
+			}
+
+			if watch {
+				if verify {
+					return fmt.Errorf("--watch and --verify may not be used together")
+				}
+				if rt.DiagnosticsFormat != genall.DiagnosticsText {
+					return fmt.Errorf("--watch and --diagnostics-format may not be used together")
+				}
+				if err := rt.Watch(ctx.Done()); err != nil {
+					return noUsageError{err}
+				}
+				return nil
+			}
 
-			if hadErrs := rt.Run(); hadErrs {
+			if hadErrs := rt.RunContext(ctx); hadErrs {
+				if verify {
+					return noUsageError{fmt.Errorf("generated artifacts are out of date, or not all generators ran successfully")}
+				}
 				// don't obscure the actual error with a bunch of usage
 				return noUsageError{fmt.Errorf("not all generators ran successfully")}
 			}
@@ -184,6 +313,16 @@ func main() {
 	cmd.Flags().CountVarP(&whichLevel, "which-markers", "w", "print out all markers available with the requested generators\n(up to -www for the most detailed output, or -wwww for json output)")
 	cmd.Flags().CountVarP(&helpLevel, "detailed-help", "h", "print out more detailed help\n(up to -hhh for the most detailed output, or -hhhh for json output)")
 	cmd.Flags().BoolVar(&showVersion, "version", false, "show version")
+	cmd.Flags().BoolVar(&verify, "verify", false, "compare generated artifacts with those on disk instead of writing them,\nfailing if any are out of date or missing")
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove files from output directories that an earlier run with --prune (with\nthe same generators and paths) generated, but that are no longer generated\n(e.g. CRDs for removed types)")
+	cmd.Flags().BoolVar(&failOnUnknownMarkers, "fail-on-unknown-markers", false, "treat markers that look like misspellings of known markers as errors,\ninstead of just warning about them")
+	cmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "treat warnings (like uses of deprecated markers) as errors, failing the run")
+	cmd.Flags().BoolVar(&explain, "explain", false, "after generating, explain why nothing was generated for packages that got\nno output (e.g. missing +groupName or +kubebuilder:object:generate markers)")
+	cmd.Flags().BoolVar(&watch, "watch", false, "after generating, watch the source files of the input packages,\nregenerating whenever they change (until interrupted)")
+	cmd.Flags().BoolVar(&parallel, "parallel", false, "run generators concurrently (errors and shared output are still\nreported in the order the generators were specified)")
+	cmd.Flags().StringVar(&diagnosticsFormat, "diagnostics-format", "", "report errors in a machine-readable format instead of as plain text:\n\"json\" (one JSON object per line) or \"sarif\" (SARIF 2.1.0)")
+	cmd.Flags().StringVar(&diagnosticsOutput, "diagnostics-output", "", "write errors to the given file instead of stderr")
+	cmd.Flags().StringVar(&configFile, "config", "", "read options from the given YAML configuration file,\nwith options on the command line taking precedence")
 	cmd.Flags().Bool("help", false, "print out usage and a summary of options")
 	oldUsage := cmd.UsageFunc()
 	cmd.SetUsageFunc(func(c *cobra.Command) error {
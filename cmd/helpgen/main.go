@@ -3,10 +3,13 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"go/format"
 	"io"
 	"os"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 
 	flag "github.com/spf13/pflag"
@@ -14,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-tools/pkg/genall"
 	"sigs.k8s.io/controller-tools/pkg/genall/help"
 	prettyhelp "sigs.k8s.io/controller-tools/pkg/genall/help/pretty"
+	"sigs.k8s.io/controller-tools/pkg/loader"
 	"sigs.k8s.io/controller-tools/pkg/markers"
 )
 
@@ -47,7 +51,7 @@ package %[1]s
 
 import (
 	"sigs.k8s.io/controller-tools/pkg/markers"
-)
+%[3]s)
 
 `
 	funcBlock = `
@@ -63,6 +67,29 @@ func (%[1]s) Help() *markers.DefinitionHelp {
 		},
 	}
 }
+`
+	// embeddingFuncBlock is funcBlock for types that embed other structs,
+	// whose fields are marker fields too, and so share their help.
+	embeddingFuncBlock = `
+func (%[1]s) Help() *markers.DefinitionHelp {
+	help := &markers.DefinitionHelp{
+		Category: %[2]q,
+		DetailedHelp: markers.DetailedHelp{
+			Summary: %[3]q,
+			Details: %[4]q,
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			%[5]s
+		},
+	}
+	%[6]s
+	return help
+}
+`
+	embeddedHelpBlock = `
+	for field, fieldHelp := range (%[1]s{}).Help().FieldHelp {
+		help.FieldHelp[field] = fieldHelp
+	}
 `
 )
 
@@ -125,20 +152,36 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 
 	for _, root := range ctx.Roots {
 		byType := make(map[string]string)
+		imports := make(map[string]string)
 		if err := markers.EachType(ctx.Collector, root, func(info *markers.TypeInfo) {
 			markerVal, hadMarker := info.Markers.Get(markerName).(generateHelp)
 			if !hadMarker {
 				return
 			}
 			outContent := new(bytes.Buffer)
+			embeddedContent := new(bytes.Buffer)
 
 			for _, field := range info.Fields {
+				if field.Name == "" {
+					// embedded structs need help of their own, which we share
+					embedded, err := embeddedTypeName(info.RawFile, field.RawField, imports)
+					if err != nil {
+						root.AddError(loader.ErrFromNode(err, field.RawField))
+						continue
+					}
+					fmt.Fprintf(embeddedContent, embeddedHelpBlock, embedded)
+					continue
+				}
 				summary, details := godocToDetails(field.Name, field.Doc)
 				fmt.Fprintf(outContent, "%[1]q: {\nSummary: %[2]q,\n Details: %[3]q,\n},\n", field.Name, summary, details)
 			}
 
 			summary, details := godocToDetails(info.Name, info.Doc)
-			byType[info.Name] = fmt.Sprintf(funcBlock, info.Name, markerVal.Category, summary, details, outContent.String())
+			if embeddedContent.Len() == 0 {
+				byType[info.Name] = fmt.Sprintf(funcBlock, info.Name, markerVal.Category, summary, details, outContent.String())
+				return
+			}
+			byType[info.Name] = fmt.Sprintf(embeddingFuncBlock, info.Name, markerVal.Category, summary, details, outContent.String(), embeddedContent.String())
 		}); err != nil {
 			return err
 		}
@@ -158,7 +201,16 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 		if headerText == "" {
 			panic("at the disco!")
 		}
-		fmt.Fprintf(outContent, header, root.Name, headerText)
+		importPaths := make([]string, 0, len(imports))
+		for path := range imports {
+			importPaths = append(importPaths, path)
+		}
+		sort.Strings(importPaths)
+		importContent := new(bytes.Buffer)
+		for _, path := range importPaths {
+			fmt.Fprintf(importContent, "%s %q\n", imports[path], path)
+		}
+		fmt.Fprintf(outContent, header, root.Name, headerText, importContent.String())
 
 		for _, typ := range typeNames {
 			fmt.Fprintln(outContent, byType[typ])
@@ -189,6 +241,37 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 	return nil
 }
 
+// embeddedTypeName returns the name of the struct embedded by the given
+// field, qualified if it's from another package (whose import from the
+// given file is then added to the given imports).
+func embeddedTypeName(file *ast.File, field *ast.Field, imports map[string]string) (string, error) {
+	switch typ := field.Type.(type) {
+	case *ast.Ident:
+		return typ.Name, nil
+	case *ast.SelectorExpr:
+		pkgIdent, isIdent := typ.X.(*ast.Ident)
+		if !isIdent {
+			break
+		}
+		for _, imp := range file.Imports {
+			impPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return "", err
+			}
+			impName := path.Base(impPath)
+			if imp.Name != nil {
+				impName = imp.Name.Name
+			}
+			if impName == pkgIdent.Name {
+				imports[impPath] = impName
+				return impName + "." + typ.Sel.Name, nil
+			}
+		}
+		return "", fmt.Errorf("unable to find the import for embedded struct %s.%s", pkgIdent.Name, typ.Sel.Name)
+	}
+	return "", fmt.Errorf("embedded fields must be named structs")
+}
+
 func (Generator) Help() *markers.DefinitionHelp {
 	// need to write this by hand, otherwise we'd have a bootstrap issue
 	return &markers.DefinitionHelp{
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apidocs generates API reference documentation from Go types.
+//
+// It writes a page for each group-version in the roots, with a section for
+// each kind and each type they use.  Each section has the documentation for
+// the type, and a table of its fields with their types, whether they're
+// required, their defaults, and their validation constraints (including
+// enum values), as produced by the same markers the crd generator uses.
+// Types link to the sections (or pages) documenting them, and each section
+// lists the types that use it.
+//
+// Pages are written in Markdown by default, or optionally in HTML or
+// AsciiDoc.
+package apidocs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// Format is a markup language that reference pages can be written in.
+type Format string
+
+const (
+	// FormatMarkdown writes (GitHub-flavored) Markdown pages.  It's the default.
+	FormatMarkdown Format = "markdown"
+	// FormatHTML writes standalone HTML pages.
+	FormatHTML Format = "html"
+	// FormatAsciiDoc writes AsciiDoc pages.
+	FormatAsciiDoc Format = "asciidoc"
+)
+
+// rendering returns the renderer and file extension for this format.
+func (f Format) rendering() (renderer, string, error) {
+	switch f {
+	case "", FormatMarkdown:
+		return markdownRenderer{}, "md", nil
+	case FormatHTML:
+		return htmlRenderer{}, "html", nil
+	case FormatAsciiDoc:
+		return asciidocRenderer{}, "adoc", nil
+	default:
+		return nil, "", fmt.Errorf("unknown format %q (expected %s, %s, or %s)", f, FormatMarkdown, FormatHTML, FormatAsciiDoc)
+	}
+}
+
+// +controllertools:marker:generateHelp
+
+// Generator generates API reference documentation, with a page for each
+// group-version, named <group>_<version>.<extension>.
+type Generator struct {
+	// Format is the markup language to write pages in: markdown (the
+	// default), html, or asciidoc.
+	Format Format `marker:",optional"`
+
+
+	// ParserOptions are the options of the crd generator that affect the
+	// schemata that fields are documented from.
+	crdgen.ParserOptions
+}
+
+var _ genall.Generator = &Generator{}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	render, ext, err := g.Format.rendering()
+	if err != nil {
+		return err
+	}
+
+	parser := g.ParserForRoots(ctx)
+
+	var packages []*loader.Package
+	for _, root := range ctx.Roots {
+		if _, hasGV := parser.GroupVersions[root]; hasGV {
+			packages = append(packages, root)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return parser.GroupVersions[packages[i]].String() < parser.GroupVersions[packages[j]].String()
+	})
+
+	kinds := make(map[crdgen.TypeIdent]struct{})
+	for _, groupKind := range crdgen.FindKubeKindsInRoots(parser, ctx.Roots) {
+		for _, pkg := range packages {
+			if parser.GroupVersions[pkg].Group == groupKind.Group {
+				kinds[crdgen.TypeIdent{Package: pkg, Name: groupKind.Kind}] = struct{}{}
+			}
+		}
+	}
+	isKind := func(ident crdgen.TypeIdent) bool {
+		_, isKind := kinds[ident]
+		return isKind
+	}
+
+	fileFor := func(gv schema.GroupVersion) string {
+		return fmt.Sprintf("%s_%s.%s", gv.Group, gv.Version, ext)
+	}
+	docs := newDocumenter(parser, packages, fileFor)
+	for _, pkg := range packages {
+		page := docs.pageFor(pkg, isKind)
+		if err := writePage(ctx, fileFor(page.GroupVersion), render, page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePage renders the given page, and writes it out using the context's
+// OutputRule.
+func writePage(ctx *genall.GenerationContext, fileName string, r renderer, p *page) error {
+	var out strings.Builder
+	title := fmt.Sprintf("%s API Reference", p.GroupVersion)
+	r.Begin(&out, title)
+	r.Heading(&out, 1, r.Text(title), "")
+	for _, para := range p.Doc {
+		r.Paragraph(&out, r.Text(para))
+	}
+
+	if len(p.Kinds) > 0 {
+		r.Heading(&out, 2, "Resource Types", "")
+		items := make([]string, len(p.Kinds))
+		for i, kind := range p.Kinds {
+			items[i] = r.Link(r.Text(kind.Name), "#"+anchorFor(kind.Name))
+		}
+		r.List(&out, items)
+	}
+
+	for _, typ := range append(append([]*typeDoc{}, p.Kinds...), p.Types...) {
+		writeType(&out, r, typ)
+	}
+	r.End(&out)
+
+	file, err := ctx.Open(nil, fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.WriteString(file, out.String())
+	return err
+}
+
+// writeType renders the section for the given type.
+func writeType(out *strings.Builder, r renderer, typ *typeDoc) {
+	r.Heading(out, 2, r.Text(typ.Name), anchorFor(typ.Name))
+	for _, para := range typ.Doc {
+		r.Paragraph(out, r.Text(para))
+	}
+	if typ.Underlying != nil {
+		r.Paragraph(out, "Underlying type: "+renderTypeRef(r, *typ.Underlying))
+	}
+	if len(typ.Validation) > 0 {
+		r.Paragraph(out, "Validation:")
+		r.List(out, renderConstraints(r, typ.Validation))
+	}
+	if len(typ.AppearsIn) > 0 {
+		items := make([]string, len(typ.AppearsIn))
+		for i, user := range typ.AppearsIn {
+			items[i] = renderTypeRef(r, user)
+		}
+		r.Paragraph(out, "Appears in:")
+		r.List(out, items)
+	}
+	if len(typ.Fields) == 0 {
+		return
+	}
+
+	rows := make([][]string, len(typ.Fields))
+	for i, field := range typ.Fields {
+		required := "Optional"
+		if field.Required {
+			required = "Required"
+		}
+		var defaultVal string
+		if field.Default != "" {
+			defaultVal = r.Code(field.Default)
+		}
+		docs := make([]string, len(field.Doc))
+		for j, para := range field.Doc {
+			docs[j] = r.Text(para)
+		}
+		rows[i] = []string{
+			r.Code(field.Name),
+			renderTypeRef(r, field.Type),
+			required,
+			defaultVal,
+			strings.Join(renderConstraints(r, field.Validation), r.LineBreak()),
+			strings.Join(docs, r.LineBreak()),
+		}
+	}
+	r.Table(out, []string{"Field", "Type", "Required", "Default", "Validation", "Description"}, rows)
+}
+
+// renderTypeRef renders a (possibly linked) reference to a type.
+func renderTypeRef(r renderer, ref typeRef) string {
+	name := r.Text(ref.Name)
+	if ref.Target != "" {
+		name = r.Link(name, ref.Target)
+	}
+	return r.Text(ref.Prefix) + name
+}
+
+// renderConstraints renders each of the given constraints.
+func renderConstraints(r renderer, constraints []constraint) []string {
+	res := make([]string, len(constraints))
+	for i, c := range constraints {
+		text := r.Text(c.Label)
+		if len(c.Values) > 0 {
+			vals := make([]string, len(c.Values))
+			for j, val := range c.Values {
+				vals[j] = r.Code(val)
+			}
+			text += ": " + strings.Join(vals, ", ")
+		}
+		if c.Note != "" {
+			text += " (" + r.Text(c.Note) + ")"
+		}
+		res[i] = text
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "sigs.k8s.io/controller-tools/pkg/apidocs"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+var _ = Describe("API Reference Documentation From Parsing to Writing", func() {
+	for _, tc := range []struct {
+		format Format
+		ext    string
+	}{
+		{format: "", ext: ".md"},
+		{format: FormatHTML, ext: ".html"},
+		{format: FormatAsciiDoc, ext: ".adoc"},
+	} {
+		tc := tc
+		It("should generate a page per group-version in the "+string(tc.format)+" format", func() {
+			By("switching into testdata to appease go modules")
+			cwd, err := os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+			defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+			By("loading the generation runtime")
+			var docsGen genall.Generator = &Generator{Format: tc.format}
+			rt, err := genall.Generators{&docsGen}.ForRoots("./apis/...")
+			Expect(err).NotTo(HaveOccurred())
+
+			outputDir, err := ioutil.TempDir("", "controller-tools-test")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(outputDir)
+			rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+			rt.ErrorWriter = GinkgoWriter
+
+			By("running the generator")
+			Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+			By("loading the expected and actual files")
+			expectedFiles, err := filepath.Glob(filepath.Join("expected", "*"+tc.ext))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expectedFiles).To(HaveLen(2))
+			actualFiles, err := filepath.Glob(filepath.Join(outputDir, "*"))
+			Expect(err).NotTo(HaveOccurred())
+			var actualNames, expectedNames []string
+			for _, file := range actualFiles {
+				actualNames = append(actualNames, filepath.Base(file))
+			}
+			for _, file := range expectedFiles {
+				expectedNames = append(expectedNames, filepath.Base(file))
+			}
+			sort.Strings(actualNames)
+			Expect(actualNames).To(Equal(expectedNames))
+
+			for _, name := range expectedNames {
+				By("checking that the expected and actual files for " + name + " are identical")
+				actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, name))
+				Expect(err).NotTo(HaveOccurred())
+				expectedContents, err := ioutil.ReadFile(filepath.Join("expected", name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actualContents)).To(Equal(string(expectedContents)), "contents not as expected, check pkg/apidocs/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(string(actualContents), string(expectedContents)))
+			}
+		})
+	}
+
+	It("should reject unknown formats", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var docsGen genall.Generator = &Generator{Format: "pdf"}
+		rt, err := genall.Generators{&docsGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeTrue(), "unexpectedly succeeded")
+	})
+})
@@ -0,0 +1,550 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// metav1Path is the import path of the package with TypeMeta and ObjectMeta.
+const metav1Path = "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// page is the reference documentation for a single group-version.
+type page struct {
+	// GroupVersion is the group-version being documented.
+	GroupVersion schema.GroupVersion
+	// Doc is the package documentation, split into paragraphs.
+	Doc []string
+	// Kinds are the kinds in the group-version.
+	Kinds []*typeDoc
+	// Types are the other types in the group-version.
+	Types []*typeDoc
+}
+
+// typeDoc is the documentation for a single type.
+type typeDoc struct {
+	// Name is the name of the type.
+	Name string
+	// Doc is the documentation for the type, split into paragraphs.
+	Doc []string
+	// Underlying is the type this type is based on, for non-struct types.
+	Underlying *typeRef
+	// Validation lists the constraints on values of this type.
+	Validation []constraint
+	// AppearsIn lists the types with fields of this type.
+	AppearsIn []typeRef
+	// Fields are the (serialized) fields of this type, for struct types.
+	Fields []fieldDoc
+}
+
+// fieldDoc is the documentation for a single field.
+type fieldDoc struct {
+	// Name is the serialized name of the field.
+	Name string
+	// Type is the type of the field.
+	Type typeRef
+	// Doc is the documentation for the field, split into paragraphs.
+	Doc []string
+	// Required indicates that the field must be set.
+	Required bool
+	// Default is the default value for the field, as JSON.
+	Default string
+	// Validation lists the constraints on the field.
+	Validation []constraint
+}
+
+// typeRef refers to a type, possibly with a link to its documentation.
+type typeRef struct {
+	// Prefix wraps the named type, like "[]" or "map[string]".
+	Prefix string
+	// Name is the name of the innermost type.
+	Name string
+	// Target is where the documentation for the named type is, if anywhere.
+	// It's either an anchor on the same page (#name), an anchor on another
+	// page, or an absolute URL.
+	Target string
+}
+
+// constraint is a single validation constraint, like a maximum.
+type constraint struct {
+	// Label describes the kind of constraint.
+	Label string
+	// Values are the values for the constraint, which are rendered as code.
+	Values []string
+	// Note is extra text to go after the values.
+	Note string
+}
+
+// documenter builds reference documentation pages from a Parser.
+type documenter struct {
+	parser *crdgen.Parser
+	// pages maps the package for each group-version to the name of the
+	// file its page is written to.
+	pages map[*loader.Package]string
+	// documented holds the types that get documented.
+	documented map[crdgen.TypeIdent]struct{}
+	// appearsIn maps each documented type to the types with fields of it.
+	appearsIn map[crdgen.TypeIdent]map[crdgen.TypeIdent]struct{}
+	// fileFor returns the file name for a group-version's page.
+	fileFor func(gv schema.GroupVersion) string
+}
+
+// newDocumenter prepares to document all the types in the given
+// group-version packages.
+func newDocumenter(parser *crdgen.Parser, packages []*loader.Package, fileFor func(gv schema.GroupVersion) string) *documenter {
+	d := &documenter{
+		parser:     parser,
+		pages:      make(map[*loader.Package]string, len(packages)),
+		documented: make(map[crdgen.TypeIdent]struct{}),
+		appearsIn:  make(map[crdgen.TypeIdent]map[crdgen.TypeIdent]struct{}),
+		fileFor:    fileFor,
+	}
+	for _, pkg := range packages {
+		d.pages[pkg] = fileFor(parser.GroupVersions[pkg])
+	}
+	for ident, info := range parser.Types {
+		if _, hasPage := d.pages[ident.Package]; !hasPage || !ast.IsExported(ident.Name) {
+			continue
+		}
+		if embedsTypeMeta(ident.Package, info) && !embedsObjectMeta(ident.Package, info) {
+			// lists aren't interesting on their own
+			continue
+		}
+		d.documented[ident] = struct{}{}
+	}
+	for ident := range d.documented {
+		for _, field := range parser.Types[ident].Fields {
+			if fieldType := ident.Package.TypesInfo.TypeOf(field.RawField.Type); fieldType != nil {
+				if ref, isDocumented := d.documentedIdent(ident.Package, fieldType); isDocumented {
+					if d.appearsIn[ref] == nil {
+						d.appearsIn[ref] = make(map[crdgen.TypeIdent]struct{})
+					}
+					d.appearsIn[ref][ident] = struct{}{}
+				}
+			}
+		}
+	}
+	return d
+}
+
+// pageFor builds the documentation page for the given group-version package.
+func (d *documenter) pageFor(pkg *loader.Package, isKind func(crdgen.TypeIdent) bool) *page {
+	res := &page{
+		GroupVersion: d.parser.GroupVersions[pkg],
+		Doc:          packageDoc(pkg),
+	}
+
+	var names []string
+	for ident := range d.documented {
+		if ident.Package == pkg {
+			names = append(names, ident.Name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ident := crdgen.TypeIdent{Package: pkg, Name: name}
+		doc := d.typeDocFor(ident, isKind(ident))
+		if isKind(ident) {
+			res.Kinds = append(res.Kinds, doc)
+		} else {
+			res.Types = append(res.Types, doc)
+		}
+	}
+	return res
+}
+
+// typeDocFor builds the documentation for the given type.
+func (d *documenter) typeDocFor(ident crdgen.TypeIdent, isKind bool) *typeDoc {
+	info := d.parser.Types[ident]
+	d.parser.NeedFlattenedSchemaFor(ident)
+	flattened := d.parser.FlattenedSchemata[ident]
+
+	res := &typeDoc{
+		Name: ident.Name,
+		Doc:  paragraphs(info.Doc),
+	}
+
+	var appearsIn []crdgen.TypeIdent
+	for user := range d.appearsIn[ident] {
+		appearsIn = append(appearsIn, user)
+	}
+	sort.Slice(appearsIn, func(i, j int) bool {
+		if appearsIn[i].Package.PkgPath != appearsIn[j].Package.PkgPath {
+			return appearsIn[i].Package.PkgPath < appearsIn[j].Package.PkgPath
+		}
+		return appearsIn[i].Name < appearsIn[j].Name
+	})
+	for _, user := range appearsIn {
+		res.AppearsIn = append(res.AppearsIn, typeRef{Name: user.Name, Target: d.targetFor(ident.Package, user)})
+	}
+
+	res.Validation = constraintsFor(&flattened)
+	if _, isStruct := info.RawSpec.Type.(*ast.StructType); !isStruct {
+		underlying := d.typeRefFor(ident.Package, ident.Package.TypesInfo.TypeOf(info.RawSpec.Type))
+		res.Underlying = &underlying
+		return res
+	}
+
+	if isKind {
+		gv := d.parser.GroupVersions[ident.Package]
+		res.Fields = append(res.Fields,
+			fieldDoc{Name: "apiVersion", Type: typeRef{Name: "string"}, Required: true, Validation: []constraint{{Label: "Value", Values: []string{gv.String()}}}},
+			fieldDoc{Name: "kind", Type: typeRef{Name: "string"}, Required: true, Validation: []constraint{{Label: "Value", Values: []string{ident.Name}}}},
+		)
+	}
+	res.Fields = append(res.Fields, d.fieldDocsFor(ident.Package, info, &flattened)...)
+	return res
+}
+
+// fieldDocsFor builds the documentation for the serialized fields of the
+// given struct type, given the flattened schema for the type it's part of.
+// Fields of embedded types are documented as if they were declared here.
+func (d *documenter) fieldDocsFor(pkg *loader.Package, info *markers.TypeInfo, flattened *apiext.JSONSchemaProps) []fieldDoc {
+	var res []fieldDoc
+	required := make(map[string]struct{}, len(flattened.Required))
+	for _, name := range flattened.Required {
+		required[name] = struct{}{}
+	}
+
+	for _, field := range info.Fields {
+		if field.Name != "" && d.parser.IgnoreUnexportedFields && !ast.IsExported(field.Name) {
+			continue
+		}
+		fieldName, inline := jsonFieldName(field.Tag.Get("json"))
+		fieldType := pkg.TypesInfo.TypeOf(field.RawField.Type)
+		if fieldType == nil {
+			continue
+		}
+		if inline {
+			if named, isNamed := derefNamed(fieldType); isNamed && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == metav1Path {
+				// TypeMeta is documented specially, and ObjectMeta isn't inlined
+				continue
+			}
+			if embeddedPkg, embeddedInfo := d.infoFor(pkg, fieldType); embeddedInfo != nil {
+				res = append(res, d.fieldDocsFor(embeddedPkg, embeddedInfo, flattened)...)
+			}
+			continue
+		}
+		if fieldName == "" {
+			continue
+		}
+
+		doc := fieldDoc{
+			Name: fieldName,
+			Type: d.typeRefFor(pkg, fieldType),
+			Doc:  paragraphs(field.Doc),
+		}
+		_, doc.Required = required[fieldName]
+		if prop, hasProp := flattened.Properties[fieldName]; hasProp {
+			if prop.Default != nil {
+				doc.Default = compactJSON(prop.Default)
+			}
+			doc.Validation = constraintsFor(&prop)
+		}
+		res = append(res, doc)
+	}
+	return res
+}
+
+// typeRefFor describes the given type, linking to its documentation if it
+// has any.  Pointers are ignored, since they don't matter once serialized.
+func (d *documenter) typeRefFor(pkg *loader.Package, typ types.Type) typeRef {
+	switch typ := typ.(type) {
+	case *types.Pointer:
+		return d.typeRefFor(pkg, typ.Elem())
+	case *types.Slice:
+		elem := d.typeRefFor(pkg, typ.Elem())
+		elem.Prefix = "[]" + elem.Prefix
+		return elem
+	case *types.Array:
+		elem := d.typeRefFor(pkg, typ.Elem())
+		elem.Prefix = "[" + strconv.FormatInt(typ.Len(), 10) + "]" + elem.Prefix
+		return elem
+	case *types.Map:
+		key := d.typeRefFor(pkg, typ.Key())
+		elem := d.typeRefFor(pkg, typ.Elem())
+		elem.Prefix = "map[" + key.Prefix + key.Name + "]" + elem.Prefix
+		return elem
+	case *types.Named:
+		obj := typ.Obj()
+		if obj.Pkg() == nil {
+			return typeRef{Name: obj.Name()}
+		}
+		if ident, isDocumented := d.documentedIdent(pkg, typ); isDocumented {
+			return typeRef{Name: obj.Name(), Target: d.targetFor(pkg, ident)}
+		}
+		if obj.Pkg().Path() == pkg.PkgPath {
+			return typeRef{Name: obj.Name()}
+		}
+		pkgPath := loader.NonVendorPath(obj.Pkg().Path())
+		return typeRef{
+			Name:   obj.Pkg().Name() + "." + obj.Name(),
+			Target: "https://pkg.go.dev/" + pkgPath + "#" + obj.Name(),
+		}
+	default:
+		return typeRef{Name: types.TypeString(typ, func(other *types.Package) string { return other.Name() })}
+	}
+}
+
+// documentedIdent finds the documented type that the given type (possibly
+// wrapped in pointers, slices or maps) refers to, if any.
+func (d *documenter) documentedIdent(pkg *loader.Package, typ types.Type) (crdgen.TypeIdent, bool) {
+	for {
+		switch inner := typ.(type) {
+		case *types.Pointer:
+			typ = inner.Elem()
+			continue
+		case *types.Slice:
+			typ = inner.Elem()
+			continue
+		case *types.Array:
+			typ = inner.Elem()
+			continue
+		case *types.Map:
+			typ = inner.Elem()
+			continue
+		}
+		break
+	}
+	named, isNamed := typ.(*types.Named)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return crdgen.TypeIdent{}, false
+	}
+	typePkg := pkg
+	if named.Obj().Pkg().Path() != pkg.PkgPath {
+		typePkg = pkg.Imports()[named.Obj().Pkg().Path()]
+		if typePkg == nil {
+			return crdgen.TypeIdent{}, false
+		}
+	}
+	ident := crdgen.TypeIdent{Package: typePkg, Name: named.Obj().Name()}
+	_, isDocumented := d.documented[ident]
+	return ident, isDocumented
+}
+
+// infoFor finds the type information for the given named type (or pointer
+// to one), along with the package it's in.
+func (d *documenter) infoFor(pkg *loader.Package, typ types.Type) (*loader.Package, *markers.TypeInfo) {
+	named, isNamed := derefNamed(typ)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return nil, nil
+	}
+	typePkg := pkg
+	if named.Obj().Pkg().Path() != pkg.PkgPath {
+		typePkg = pkg.Imports()[named.Obj().Pkg().Path()]
+		if typePkg == nil {
+			return nil, nil
+		}
+	}
+	d.parser.NeedPackage(typePkg)
+	return typePkg, d.parser.Types[crdgen.TypeIdent{Package: typePkg, Name: named.Obj().Name()}]
+}
+
+// targetFor returns the link to the documentation for the given type, as
+// seen from the page for the given package.
+func (d *documenter) targetFor(from *loader.Package, ident crdgen.TypeIdent) string {
+	anchor := "#" + anchorFor(ident.Name)
+	if ident.Package == from {
+		return anchor
+	}
+	return d.pages[ident.Package] + anchor
+}
+
+// anchorFor returns the anchor for the section documenting the given type.
+func anchorFor(typeName string) string {
+	return strings.ToLower(typeName)
+}
+
+// constraintsFor lists the validation constraints declared directly on the
+// given schema node (constraints on items or properties aren't included).
+func constraintsFor(schema *apiext.JSONSchemaProps) []constraint {
+	var res []constraint
+	if schema.Format != "" && schema.Format != "int32" && schema.Format != "int64" {
+		res = append(res, constraint{Label: "Format", Values: []string{schema.Format}})
+	}
+	if len(schema.Enum) > 0 {
+		vals := make([]string, len(schema.Enum))
+		for i := range schema.Enum {
+			vals[i] = compactJSON(&schema.Enum[i])
+		}
+		res = append(res, constraint{Label: "Enum", Values: vals})
+	}
+	if schema.Pattern != "" {
+		res = append(res, constraint{Label: "Pattern", Values: []string{schema.Pattern}})
+	}
+	if schema.Minimum != nil {
+		res = append(res, boundConstraint("Minimum", *schema.Minimum, schema.ExclusiveMinimum))
+	}
+	if schema.Maximum != nil {
+		res = append(res, boundConstraint("Maximum", *schema.Maximum, schema.ExclusiveMaximum))
+	}
+	if schema.MultipleOf != nil {
+		res = append(res, constraint{Label: "Multiple of", Values: []string{strconv.FormatFloat(*schema.MultipleOf, 'f', -1, 64)}})
+	}
+	res = appendLimit(res, "Min length", schema.MinLength)
+	res = appendLimit(res, "Max length", schema.MaxLength)
+	res = appendLimit(res, "Min items", schema.MinItems)
+	res = appendLimit(res, "Max items", schema.MaxItems)
+	res = appendLimit(res, "Min properties", schema.MinProperties)
+	res = appendLimit(res, "Max properties", schema.MaxProperties)
+	if schema.UniqueItems {
+		res = append(res, constraint{Label: "Items must be unique"})
+	}
+	if schema.XListType != nil && *schema.XListType != "atomic" {
+		listType := constraint{Label: "List type", Values: []string{*schema.XListType}}
+		if len(schema.XListMapKeys) > 0 {
+			listType.Note = "keyed by " + strings.Join(schema.XListMapKeys, ", ")
+		}
+		res = append(res, listType)
+	}
+	if schema.XMapType != nil && *schema.XMapType != "granular" {
+		res = append(res, constraint{Label: "Map type", Values: []string{*schema.XMapType}})
+	}
+	for _, rule := range schema.XValidations {
+		res = append(res, constraint{Label: "Rule", Values: []string{rule.Rule}, Note: rule.Message})
+	}
+	return res
+}
+
+// boundConstraint describes a minimum or maximum.
+func boundConstraint(label string, bound float64, exclusive bool) constraint {
+	res := constraint{Label: label, Values: []string{strconv.FormatFloat(bound, 'f', -1, 64)}}
+	if exclusive {
+		res.Note = "exclusive"
+	}
+	return res
+}
+
+// appendLimit appends a constraint for the given limit, if it's set.
+func appendLimit(constraints []constraint, label string, limit *int64) []constraint {
+	if limit == nil {
+		return constraints
+	}
+	return append(constraints, constraint{Label: label, Values: []string{strconv.FormatInt(*limit, 10)}})
+}
+
+// compactJSON renders the given JSON value compactly.
+func compactJSON(val *apiext.JSON) string {
+	var parsed interface{}
+	if err := json.Unmarshal(val.Raw, &parsed); err != nil {
+		return string(val.Raw)
+	}
+	out, err := json.Marshal(parsed)
+	if err != nil {
+		return string(val.Raw)
+	}
+	return string(out)
+}
+
+// paragraphs splits the given documentation (as extracted by the marker
+// collector, which marks paragraph breaks with newlines) into paragraphs.
+func paragraphs(doc string) []string {
+	var res []string
+	for _, para := range strings.Split(doc, "\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			res = append(res, para)
+		}
+	}
+	return res
+}
+
+// packageDoc extracts the package documentation from the given package,
+// skipping markers.
+func packageDoc(pkg *loader.Package) []string {
+	var res []string
+	for _, file := range pkg.Syntax {
+		if file.Doc == nil {
+			continue
+		}
+		var lines []string
+		for _, line := range strings.Split(file.Doc.Text(), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "+") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		for _, para := range strings.Split(strings.Join(lines, "\n"), "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				res = append(res, strings.Join(strings.Fields(para), " "))
+			}
+		}
+	}
+	return res
+}
+
+// jsonFieldName returns the name of a field in its serialized form, given
+// its JSON tag, and whether or not it's inlined into its parent.  Fields
+// that aren't serialized have an empty name.
+func jsonFieldName(jsonTag string) (string, bool) {
+	jsonOpts := strings.Split(jsonTag, ",")
+	if jsonTag == "" || (len(jsonOpts) == 1 && jsonOpts[0] == "-") {
+		return "", false
+	}
+	for _, opt := range jsonOpts[1:] {
+		if opt == "inline" {
+			return jsonOpts[0], true
+		}
+	}
+	return jsonOpts[0], jsonOpts[0] == ""
+}
+
+// derefNamed returns the named type that the given type is (or points to).
+func derefNamed(typ types.Type) (*types.Named, bool) {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	named, isNamed := typ.(*types.Named)
+	return named, isNamed
+}
+
+// embedsTypeMeta checks if the given type embeds metav1.TypeMeta.
+func embedsTypeMeta(pkg *loader.Package, info *markers.TypeInfo) bool {
+	return embedsMeta(pkg, info, "TypeMeta")
+}
+
+// embedsObjectMeta checks if the given type embeds metav1.ObjectMeta.
+func embedsObjectMeta(pkg *loader.Package, info *markers.TypeInfo) bool {
+	return embedsMeta(pkg, info, "ObjectMeta")
+}
+
+// embedsMeta checks if the given type embeds the given type from metav1.
+func embedsMeta(pkg *loader.Package, info *markers.TypeInfo, name string) bool {
+	pkg.NeedTypesInfo()
+	for _, field := range info.Fields {
+		if field.Name != "" {
+			continue
+		}
+		named, isNamed := derefNamed(pkg.TypesInfo.TypeOf(field.RawField.Type))
+		if isNamed && named.Obj().Pkg() != nil && loader.NonVendorPath(named.Obj().Pkg().Path()) == metav1Path && named.Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// renderer renders documentation pages in a particular markup language.
+type renderer interface {
+	// Begin starts a page with the given title.
+	Begin(out *strings.Builder, title string)
+	// End finishes a page.
+	End(out *strings.Builder)
+	// Heading writes a section heading at the given level (1 being the
+	// title), with the given anchor (if any).
+	Heading(out *strings.Builder, level int, text, anchor string)
+	// Paragraph writes a paragraph of already-rendered inline text.
+	Paragraph(out *strings.Builder, text string)
+	// List writes a bulleted list of already-rendered inline text.
+	List(out *strings.Builder, items []string)
+	// Table writes a table of already-rendered inline text.
+	Table(out *strings.Builder, headers []string, rows [][]string)
+
+	// Text escapes plain text.
+	Text(text string) string
+	// Code renders text as inline code.
+	Code(text string) string
+	// Link renders a link with already-rendered inline text.
+	Link(text, target string) string
+	// LineBreak separates lines within a table cell.
+	LineBreak() string
+}
+
+// markdownRenderer renders (GitHub-flavored) Markdown.
+type markdownRenderer struct{}
+
+func (markdownRenderer) Begin(out *strings.Builder, title string) {}
+func (markdownRenderer) End(out *strings.Builder)                 {}
+
+func (markdownRenderer) Heading(out *strings.Builder, level int, text, anchor string) {
+	// headings are automatically given anchors based on their text
+	fmt.Fprintf(out, "%s %s\n\n", strings.Repeat("#", level), text)
+}
+
+func (markdownRenderer) Paragraph(out *strings.Builder, text string) {
+	fmt.Fprintf(out, "%s\n\n", text)
+}
+
+func (markdownRenderer) List(out *strings.Builder, items []string) {
+	for _, item := range items {
+		fmt.Fprintf(out, "- %s\n", item)
+	}
+	out.WriteString("\n")
+}
+
+func (markdownRenderer) Table(out *strings.Builder, headers []string, rows [][]string) {
+	fmt.Fprintf(out, "| %s |\n", strings.Join(headers, " | "))
+	fmt.Fprintf(out, "|%s\n", strings.Repeat(" --- |", len(headers)))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		fmt.Fprintf(out, "| %s |\n", strings.Join(cells, " | "))
+	}
+	out.WriteString("\n")
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "<", "&lt;", ">", "&gt;", "`", "\\`",
+)
+
+func (markdownRenderer) Text(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func (markdownRenderer) Code(text string) string {
+	// use enough backticks that none in the text end the span early
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+func (markdownRenderer) Link(text, target string) string {
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+func (markdownRenderer) LineBreak() string {
+	return "<br />"
+}
+
+// htmlRenderer renders standalone HTML pages.
+type htmlRenderer struct{}
+
+func (htmlRenderer) Begin(out *strings.Builder, title string) {
+	fmt.Fprintf(out, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+}
+
+func (htmlRenderer) End(out *strings.Builder) {
+	out.WriteString("</body>\n</html>\n")
+}
+
+func (htmlRenderer) Heading(out *strings.Builder, level int, text, anchor string) {
+	if anchor != "" {
+		fmt.Fprintf(out, "<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(anchor), text, level)
+		return
+	}
+	fmt.Fprintf(out, "<h%d>%s</h%d>\n", level, text, level)
+}
+
+func (htmlRenderer) Paragraph(out *strings.Builder, text string) {
+	fmt.Fprintf(out, "<p>%s</p>\n", text)
+}
+
+func (htmlRenderer) List(out *strings.Builder, items []string) {
+	out.WriteString("<ul>\n")
+	for _, item := range items {
+		fmt.Fprintf(out, "<li>%s</li>\n", item)
+	}
+	out.WriteString("</ul>\n")
+}
+
+func (htmlRenderer) Table(out *strings.Builder, headers []string, rows [][]string) {
+	out.WriteString("<table>\n<thead>\n<tr>")
+	for _, header := range headers {
+		fmt.Fprintf(out, "<th>%s</th>", header)
+	}
+	out.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range rows {
+		out.WriteString("<tr>")
+		for _, cell := range row {
+			fmt.Fprintf(out, "<td>%s</td>", cell)
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</tbody>\n</table>\n")
+}
+
+func (htmlRenderer) Text(text string) string {
+	return html.EscapeString(text)
+}
+
+func (htmlRenderer) Code(text string) string {
+	return "<code>" + html.EscapeString(text) + "</code>"
+}
+
+func (htmlRenderer) Link(text, target string) string {
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(target), text)
+}
+
+func (htmlRenderer) LineBreak() string {
+	return "<br />"
+}
+
+// asciidocRenderer renders AsciiDoc.
+type asciidocRenderer struct{}
+
+func (asciidocRenderer) Begin(out *strings.Builder, title string) {}
+func (asciidocRenderer) End(out *strings.Builder)                 {}
+
+func (asciidocRenderer) Heading(out *strings.Builder, level int, text, anchor string) {
+	if anchor != "" {
+		fmt.Fprintf(out, "[[%s]]\n", anchor)
+	}
+	fmt.Fprintf(out, "%s %s\n\n", strings.Repeat("=", level), text)
+}
+
+func (asciidocRenderer) Paragraph(out *strings.Builder, text string) {
+	fmt.Fprintf(out, "%s\n\n", text)
+}
+
+func (asciidocRenderer) List(out *strings.Builder, items []string) {
+	for _, item := range items {
+		fmt.Fprintf(out, "* %s\n", item)
+	}
+	out.WriteString("\n")
+}
+
+func (asciidocRenderer) Table(out *strings.Builder, headers []string, rows [][]string) {
+	out.WriteString("[options=\"header\"]\n|===\n")
+	for _, header := range headers {
+		fmt.Fprintf(out, "| %s ", header)
+	}
+	out.WriteString("\n")
+	for _, row := range rows {
+		out.WriteString("\n")
+		for _, cell := range row {
+			fmt.Fprintf(out, "| %s\n", strings.ReplaceAll(cell, "|", "\\|"))
+		}
+	}
+	out.WriteString("|===\n\n")
+}
+
+// asciidocEscaper replaces characters that might be interpreted as
+// formatting with the built-in attributes for them.
+var asciidocEscaper = strings.NewReplacer(
+	"{", "\\{", "\\", "{backslash}", "[", "{startsb}", "]", "{endsb}", "*", "{asterisk}", "`", "{backtick}",
+	"+", "{plus}", "^", "{caret}", "~", "{tilde}", "<", "{lt}", ">", "{gt}",
+)
+
+func (asciidocRenderer) Text(text string) string {
+	return asciidocEscaper.Replace(text)
+}
+
+func (asciidocRenderer) Code(text string) string {
+	return "`+" + text + "+`"
+}
+
+func (asciidocRenderer) Link(text, target string) string {
+	if strings.HasPrefix(target, "#") {
+		return fmt.Sprintf("<<%s,%s>>", strings.TrimPrefix(target, "#"), text)
+	}
+	if strings.Contains(target, "://") {
+		return fmt.Sprintf("%s[%s]", target, text)
+	}
+	return fmt.Sprintf("xref:%s[%s]", target, text)
+}
+
+func (asciidocRenderer) LineBreak() string {
+	return " +\n"
+}
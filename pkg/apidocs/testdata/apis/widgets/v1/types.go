@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	partsv1 "testdata.kubebuilder.io/apidocs/apis/parts/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Widget is a widget.
+//
+// It's assembled from parts.
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WidgetSpec   `json:"spec,omitempty"`
+	Status WidgetStatus `json:"status,omitempty"`
+}
+
+// Size is the size of a widget.
+// +kubebuilder:validation:Enum=small;medium;large
+type Size string
+
+// WidgetSpec is the spec for a Widget.
+// +kubebuilder:validation:XValidation:rule="self.replicas <= 5 || self.size != 'large'",message="at most 5 large widgets"
+type WidgetSpec struct {
+	Common `json:",inline"`
+
+	// Size is the size of the widget.
+	Size Size `json:"size"`
+
+	// Replicas is the number of widgets.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:ExclusiveMaximum=true
+	// +kubebuilder:validation:Maximum=10
+	Replicas *int32 `json:"replicas"`
+
+	// Parts are the parts of the widget, keyed by name.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Parts []partsv1.PartReference `json:"parts,omitempty"`
+
+	// Labels are extra labels for the widget's pods.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Interval is how often to check on the widget.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// Common holds fields shared by several specs.
+type Common struct {
+	// Paused pauses reconciliation.
+	// +kubebuilder:default=false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// WidgetStatus is the status of a Widget.
+type WidgetStatus struct {
+	// Conditions describe the state of the widget.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WidgetList contains a list of Widget.
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
@@ -0,0 +1,409 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// Change is a single difference between two revisions of a CRD.
+type Change struct {
+	// Version is the version of the CRD that the change applies to, or empty
+	// if it applies to the CRD as a whole.
+	Version string
+	// Path is the path to the changed schema node within the version's
+	// schema (e.g. `.spec.replicas`), if any.
+	Path string
+	// Description describes the change.
+	Description string
+	// Breaking indicates that objects (or clients) that worked with the old
+	// revision might not work with the new one.
+	Breaking bool
+}
+
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	switch {
+	case c.Version == "":
+		return fmt.Sprintf("%s change: %s", kind, c.Description)
+	case c.Path == "":
+		return fmt.Sprintf("%s change in version %s: %s", kind, c.Version, c.Description)
+	default:
+		return fmt.Sprintf("%s change in version %s at %s: %s", kind, c.Version, c.Path, c.Description)
+	}
+}
+
+// Compare lists the differences between an old and a new revision of a CRD,
+// classifying each as breaking or non-breaking.  Changes to descriptions are
+// ignored.
+func Compare(oldCRD, newCRD *apiext.CustomResourceDefinition) []Change {
+	c := &comparer{}
+
+	if oldCRD.Spec.Scope != newCRD.Spec.Scope {
+		c.breaking("", "", "scope changed from %s to %s", oldCRD.Spec.Scope, newCRD.Spec.Scope)
+	}
+	if oldCRD.Spec.Names.Plural != newCRD.Spec.Names.Plural {
+		c.breaking("", "", "plural name changed from %q to %q", oldCRD.Spec.Names.Plural, newCRD.Spec.Names.Plural)
+	}
+
+	newVersions := make(map[string]*apiext.CustomResourceDefinitionVersion, len(newCRD.Spec.Versions))
+	for i := range newCRD.Spec.Versions {
+		newVersions[newCRD.Spec.Versions[i].Name] = &newCRD.Spec.Versions[i]
+	}
+	oldVersions := make(map[string]struct{}, len(oldCRD.Spec.Versions))
+	for i := range oldCRD.Spec.Versions {
+		oldVer := &oldCRD.Spec.Versions[i]
+		oldVersions[oldVer.Name] = struct{}{}
+		newVer, exists := newVersions[oldVer.Name]
+		switch {
+		case !exists && oldVer.Served:
+			c.breaking(oldVer.Name, "", "served version was removed")
+		case !exists:
+			c.nonBreaking(oldVer.Name, "unserved version was removed")
+		case oldVer.Served && !newVer.Served:
+			c.breaking(oldVer.Name, "", "version is no longer served")
+		default:
+			if !oldVer.Served && newVer.Served {
+				c.nonBreaking(oldVer.Name, "version is now served")
+			}
+			if oldVer.Storage != newVer.Storage && newVer.Storage {
+				c.nonBreaking(oldVer.Name, "version is now the storage version")
+			}
+			c.compareSchemata(oldVer.Name, "", versionSchema(oldVer), versionSchema(newVer))
+		}
+	}
+	for _, newVer := range newCRD.Spec.Versions {
+		if _, existed := oldVersions[newVer.Name]; !existed {
+			c.nonBreaking(newVer.Name, "version was added")
+		}
+	}
+
+	return c.changes
+}
+
+// versionSchema returns the schema for the given version, if any.
+func versionSchema(ver *apiext.CustomResourceDefinitionVersion) *apiext.JSONSchemaProps {
+	if ver.Schema == nil {
+		return nil
+	}
+	return ver.Schema.OpenAPIV3Schema
+}
+
+// comparer accumulates changes between two CRDs.
+type comparer struct {
+	changes []Change
+}
+
+func (c *comparer) breaking(version, path, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{Version: version, Path: path, Description: fmt.Sprintf(format, args...), Breaking: true})
+}
+
+func (c *comparer) nonBreaking(version, format string, args ...interface{}) {
+	c.nonBreakingAt(version, "", format, args...)
+}
+
+func (c *comparer) nonBreakingAt(version, path, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{Version: version, Path: path, Description: fmt.Sprintf(format, args...)})
+}
+
+// compareSchemata compares the given schema nodes at the given path, and all
+// the nodes below them.
+func (c *comparer) compareSchemata(version, path string, oldSchema, newSchema *apiext.JSONSchemaProps) {
+	switch {
+	case oldSchema == nil && newSchema == nil:
+		return
+	case oldSchema == nil:
+		c.breaking(version, path, "schema was added")
+		return
+	case newSchema == nil:
+		c.nonBreakingAt(version, path, "schema was removed")
+		return
+	}
+
+	if oldSchema.Type != newSchema.Type {
+		c.breaking(version, path, "type changed from %q to %q", oldSchema.Type, newSchema.Type)
+		// everything else is probably different too, and not interesting
+		return
+	}
+	if oldSchema.Format != newSchema.Format {
+		c.breaking(version, path, "format changed from %q to %q", oldSchema.Format, newSchema.Format)
+	}
+	if oldSchema.XIntOrString != newSchema.XIntOrString {
+		c.breaking(version, path, "x-kubernetes-int-or-string changed from %t to %t", oldSchema.XIntOrString, newSchema.XIntOrString)
+	}
+	if oldSchema.Nullable && !newSchema.Nullable {
+		c.breaking(version, path, "is no longer nullable")
+	} else if !oldSchema.Nullable && newSchema.Nullable {
+		c.nonBreakingAt(version, path, "is now nullable")
+	}
+	c.comparePreserveUnknownFields(version, path, oldSchema, newSchema)
+
+	c.compareEnums(version, path, oldSchema.Enum, newSchema.Enum)
+	if oldSchema.Pattern != newSchema.Pattern {
+		switch {
+		case newSchema.Pattern == "":
+			c.nonBreakingAt(version, path, "pattern %q was removed", oldSchema.Pattern)
+		case oldSchema.Pattern == "":
+			c.breaking(version, path, "pattern %q was added", newSchema.Pattern)
+		default:
+			c.breaking(version, path, "pattern changed from %q to %q", oldSchema.Pattern, newSchema.Pattern)
+		}
+	}
+
+	c.compareMaximum(version, path, oldSchema.Maximum, oldSchema.ExclusiveMaximum, newSchema.Maximum, newSchema.ExclusiveMaximum)
+	c.compareMinimum(version, path, oldSchema.Minimum, oldSchema.ExclusiveMinimum, newSchema.Minimum, newSchema.ExclusiveMinimum)
+	c.compareUpperBound(version, path, "maxLength", oldSchema.MaxLength, newSchema.MaxLength)
+	c.compareLowerBound(version, path, "minLength", oldSchema.MinLength, newSchema.MinLength)
+	c.compareUpperBound(version, path, "maxItems", oldSchema.MaxItems, newSchema.MaxItems)
+	c.compareLowerBound(version, path, "minItems", oldSchema.MinItems, newSchema.MinItems)
+	c.compareUpperBound(version, path, "maxProperties", oldSchema.MaxProperties, newSchema.MaxProperties)
+	c.compareLowerBound(version, path, "minProperties", oldSchema.MinProperties, newSchema.MinProperties)
+	if !oldSchema.UniqueItems && newSchema.UniqueItems {
+		c.breaking(version, path, "items must now be unique")
+	}
+
+	c.compareRules(version, path, oldSchema.XValidations, newSchema.XValidations)
+	if jsonOrNone(oldSchema.Default) != jsonOrNone(newSchema.Default) {
+		c.nonBreakingAt(version, path, "default changed from %s to %s", jsonOrNone(oldSchema.Default), jsonOrNone(newSchema.Default))
+	}
+
+	c.compareProperties(version, path, oldSchema, newSchema)
+	if oldSchema.Items != nil || newSchema.Items != nil {
+		c.compareSchemata(version, path+"[*]", itemSchema(oldSchema.Items), itemSchema(newSchema.Items))
+	}
+	if oldSchema.AdditionalProperties != nil || newSchema.AdditionalProperties != nil {
+		c.compareSchemata(version, path+"[*]", additionalSchema(oldSchema.AdditionalProperties), additionalSchema(newSchema.AdditionalProperties))
+	}
+}
+
+// comparePreserveUnknownFields compares whether unknown fields are kept
+// at the given node (no longer keeping them silently drops data).
+func (c *comparer) comparePreserveUnknownFields(version, path string, oldSchema, newSchema *apiext.JSONSchemaProps) {
+	oldPreserves := oldSchema.XPreserveUnknownFields != nil && *oldSchema.XPreserveUnknownFields
+	newPreserves := newSchema.XPreserveUnknownFields != nil && *newSchema.XPreserveUnknownFields
+	if oldPreserves && !newPreserves {
+		c.breaking(version, path, "unknown fields are no longer preserved")
+	} else if !oldPreserves && newPreserves {
+		c.nonBreakingAt(version, path, "unknown fields are now preserved")
+	}
+}
+
+// compareProperties compares the properties of the given object schemata,
+// along with which ones are required.
+func (c *comparer) compareProperties(version, path string, oldSchema, newSchema *apiext.JSONSchemaProps) {
+	oldRequired := stringSet(oldSchema.Required)
+	for _, name := range newSchema.Required {
+		if _, wasRequired := oldRequired[name]; !wasRequired {
+			c.breaking(version, path+"."+name, "field is newly required")
+		}
+	}
+	newRequired := stringSet(newSchema.Required)
+	for _, name := range oldSchema.Required {
+		if _, isRequired := newRequired[name]; !isRequired {
+			c.nonBreakingAt(version, path+"."+name, "field is no longer required")
+		}
+	}
+
+	for _, name := range sortedKeys(oldSchema.Properties) {
+		oldProp := oldSchema.Properties[name]
+		newProp, exists := newSchema.Properties[name]
+		if !exists {
+			c.breaking(version, path+"."+name, "field was removed")
+			continue
+		}
+		c.compareSchemata(version, path+"."+name, &oldProp, &newProp)
+	}
+	for _, name := range sortedKeys(newSchema.Properties) {
+		if _, existed := oldSchema.Properties[name]; !existed {
+			c.nonBreakingAt(version, path+"."+name, "field was added")
+		}
+	}
+}
+
+// compareEnums compares the allowed values of two schema nodes.
+func (c *comparer) compareEnums(version, path string, oldEnum, newEnum []apiext.JSON) {
+	switch {
+	case len(oldEnum) == 0 && len(newEnum) == 0:
+		return
+	case len(oldEnum) == 0:
+		c.breaking(version, path, "values are now restricted to an enum")
+		return
+	case len(newEnum) == 0:
+		c.nonBreakingAt(version, path, "values are no longer restricted to an enum")
+		return
+	}
+
+	oldValues := make(map[string]struct{}, len(oldEnum))
+	for i := range oldEnum {
+		oldValues[jsonOrNone(&oldEnum[i])] = struct{}{}
+	}
+	newValues := make(map[string]struct{}, len(newEnum))
+	for i := range newEnum {
+		val := jsonOrNone(&newEnum[i])
+		newValues[val] = struct{}{}
+		if _, existed := oldValues[val]; !existed {
+			c.nonBreakingAt(version, path, "enum value %s was added", val)
+		}
+	}
+	for i := range oldEnum {
+		val := jsonOrNone(&oldEnum[i])
+		if _, exists := newValues[val]; !exists {
+			c.breaking(version, path, "enum value %s was removed", val)
+		}
+	}
+}
+
+// compareMaximum compares the maxima of two schema nodes.
+func (c *comparer) compareMaximum(version, path string, oldMax *float64, oldExclusive bool, newMax *float64, newExclusive bool) {
+	switch {
+	case oldMax == nil && newMax == nil:
+	case oldMax == nil:
+		c.breaking(version, path, "maximum of %v was added", *newMax)
+	case newMax == nil:
+		c.nonBreakingAt(version, path, "maximum of %v was removed", *oldMax)
+	case *newMax < *oldMax || (*newMax == *oldMax && newExclusive && !oldExclusive):
+		c.breaking(version, path, "maximum was lowered from %v to %v", *oldMax, *newMax)
+	case *newMax > *oldMax || (*newMax == *oldMax && oldExclusive && !newExclusive):
+		c.nonBreakingAt(version, path, "maximum was raised from %v to %v", *oldMax, *newMax)
+	}
+}
+
+// compareMinimum compares the minima of two schema nodes.
+func (c *comparer) compareMinimum(version, path string, oldMin *float64, oldExclusive bool, newMin *float64, newExclusive bool) {
+	switch {
+	case oldMin == nil && newMin == nil:
+	case oldMin == nil:
+		c.breaking(version, path, "minimum of %v was added", *newMin)
+	case newMin == nil:
+		c.nonBreakingAt(version, path, "minimum of %v was removed", *oldMin)
+	case *newMin > *oldMin || (*newMin == *oldMin && newExclusive && !oldExclusive):
+		c.breaking(version, path, "minimum was raised from %v to %v", *oldMin, *newMin)
+	case *newMin < *oldMin || (*newMin == *oldMin && oldExclusive && !newExclusive):
+		c.nonBreakingAt(version, path, "minimum was lowered from %v to %v", *oldMin, *newMin)
+	}
+}
+
+// compareUpperBound compares limits like maxLength, where lowering (or adding)
+// the limit is breaking.
+func (c *comparer) compareUpperBound(version, path, name string, oldLimit, newLimit *int64) {
+	switch {
+	case oldLimit == nil && newLimit == nil:
+	case oldLimit == nil:
+		c.breaking(version, path, "%s of %d was added", name, *newLimit)
+	case newLimit == nil:
+		c.nonBreakingAt(version, path, "%s of %d was removed", name, *oldLimit)
+	case *newLimit < *oldLimit:
+		c.breaking(version, path, "%s was lowered from %d to %d", name, *oldLimit, *newLimit)
+	case *newLimit > *oldLimit:
+		c.nonBreakingAt(version, path, "%s was raised from %d to %d", name, *oldLimit, *newLimit)
+	}
+}
+
+// compareLowerBound compares limits like minLength, where raising (or adding)
+// the limit is breaking.
+func (c *comparer) compareLowerBound(version, path, name string, oldLimit, newLimit *int64) {
+	switch {
+	case oldLimit == nil && newLimit == nil:
+	case oldLimit == nil:
+		c.breaking(version, path, "%s of %d was added", name, *newLimit)
+	case newLimit == nil:
+		c.nonBreakingAt(version, path, "%s of %d was removed", name, *oldLimit)
+	case *newLimit > *oldLimit:
+		c.breaking(version, path, "%s was raised from %d to %d", name, *oldLimit, *newLimit)
+	case *newLimit < *oldLimit:
+		c.nonBreakingAt(version, path, "%s was lowered from %d to %d", name, *oldLimit, *newLimit)
+	}
+}
+
+// compareRules compares the CEL validation rules of two schema nodes.  Any
+// new rule might reject objects that used to be valid, so adding one is
+// breaking.
+func (c *comparer) compareRules(version, path string, oldRules, newRules apiext.ValidationRules) {
+	oldSet := make(map[string]struct{}, len(oldRules))
+	for _, rule := range oldRules {
+		oldSet[rule.Rule] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newRules))
+	for _, rule := range newRules {
+		newSet[rule.Rule] = struct{}{}
+		if _, existed := oldSet[rule.Rule]; !existed {
+			c.breaking(version, path, "validation rule %q was added", rule.Rule)
+		}
+	}
+	for _, rule := range oldRules {
+		if _, exists := newSet[rule.Rule]; !exists {
+			c.nonBreakingAt(version, path, "validation rule %q was removed", rule.Rule)
+		}
+	}
+}
+
+// itemSchema returns the schema for the items of an array, if any.
+func itemSchema(items *apiext.JSONSchemaPropsOrArray) *apiext.JSONSchemaProps {
+	if items == nil {
+		return nil
+	}
+	return items.Schema
+}
+
+// additionalSchema returns the schema for the values of a map, if any.
+func additionalSchema(props *apiext.JSONSchemaPropsOrBool) *apiext.JSONSchemaProps {
+	if props == nil {
+		return nil
+	}
+	return props.Schema
+}
+
+// jsonOrNone renders the given value as JSON, or "none" if it's not set.
+func jsonOrNone(val *apiext.JSON) string {
+	if val == nil {
+		return "none"
+	}
+	// reformat so that differences in whitespace don't show up
+	var parsed interface{}
+	if err := json.Unmarshal(val.Raw, &parsed); err != nil {
+		return string(val.Raw)
+	}
+	out, err := json.Marshal(parsed)
+	if err != nil {
+		return string(val.Raw)
+	}
+	return string(out)
+}
+
+func stringSet(vals []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(vals))
+	for _, val := range vals {
+		res[val] = struct{}{}
+	}
+	return res
+}
+
+func sortedKeys(props map[string]apiext.JSONSchemaProps) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
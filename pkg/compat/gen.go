@@ -0,0 +1,208 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compat checks CRDs generated from Go types for breaking changes
+// against a baseline of previously generated CRDs.
+//
+// Each difference between a baseline CRD and the newly generated one is
+// classified as breaking (e.g. a field was removed, changed type, became
+// required, or had its enum, pattern or bounds tightened, or a served version
+// was removed) or non-breaking (e.g. a field was added, or a bound was
+// loosened).  Breaking changes are reported as errors, and non-breaking
+// changes as warnings, on the type for the kind in the affected version.
+//
+// The CRDs are generated with the same schema options as the crd generator
+// takes (like generateEmbeddedObjectMeta), which should match the ones the
+// baseline was generated with.
+package compat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	kyaml "sigs.k8s.io/yaml"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// +controllertools:marker:generateHelp
+
+// Generator checks the CRDs for the roots for breaking changes against a
+// baseline of previously generated CRDs.
+//
+// It doesn't write anything: breaking changes fail the run, and non-breaking
+// ones are reported as warnings.
+type Generator struct {
+	// Baseline is the directory containing the CRD manifests to compare
+	// against (e.g. the manifests from the last release, or the ones
+	// currently checked in).
+	Baseline string `marker:"baseline"`
+
+	// ParserOptions are the options of the crd generator that affect the
+	// schemata, which should match the ones the baseline was generated with.
+	crdgen.ParserOptions
+}
+
+var _ genall.Generator = &Generator{}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	parser := g.ParserForRoots(ctx)
+
+	baseline, err := crdsFromDirectory(ctx, g.Baseline)
+	if err != nil {
+		return err
+	}
+
+	kinds := crdgen.FindKubeKindsInRoots(parser, ctx.Roots)
+	generated := make(map[schema.GroupKind]struct{}, len(kinds))
+	for _, groupKind := range kinds {
+		generated[groupKind] = struct{}{}
+		oldCRD, inBaseline := baseline[groupKind]
+		if !inBaseline {
+			// a brand new CRD can't break anything
+			continue
+		}
+
+		parser.NeedCRDFor(groupKind, nil)
+		newCRD, wasGenerated := parser.CustomResourceDefinitions[groupKind]
+		if !wasGenerated {
+			continue
+		}
+		// the baseline will have been written like this
+		crdgen.FixTopLevelMetadata(newCRD)
+
+		for _, change := range Compare(&oldCRD, &newCRD) {
+			pkg, node := kindNode(parser, groupKind, change.Version, &newCRD)
+			if pkg == nil {
+				continue
+			}
+			err := loader.ErrFromNode(fmt.Errorf("%s: %s", groupKind, change), node)
+			if change.Breaking {
+				pkg.AddError(err)
+			} else {
+				pkg.AddWarning(err)
+			}
+		}
+	}
+
+	// CRDs can only disappear from groups we're still generating, otherwise
+	// they're just out of scope of the roots
+	groups := make(map[string]*loader.Package)
+	for pkg, gv := range parser.GroupVersions {
+		if _, known := groups[gv.Group]; !known || pkg.ID < groups[gv.Group].ID {
+			groups[gv.Group] = pkg
+		}
+	}
+	var removed []schema.GroupKind
+	for groupKind := range baseline {
+		if _, stillGenerated := generated[groupKind]; stillGenerated {
+			continue
+		}
+		if _, inScope := groups[groupKind.Group]; inScope {
+			removed = append(removed, groupKind)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].String() < removed[j].String() })
+	for _, groupKind := range removed {
+		groups[groupKind.Group].AddError(fmt.Errorf("%s: breaking change: CRD was removed", groupKind))
+	}
+
+	return nil
+}
+
+// kindNode finds the type for the given kind in the given version of the
+// given CRD, returning it along with the package it's in.  If there's no
+// such version, the storage version is used instead.
+func kindNode(parser *crdgen.Parser, groupKind schema.GroupKind, version string, crd *apiext.CustomResourceDefinition) (*loader.Package, ast.Node) {
+	versions := []string{version}
+	for _, ver := range crd.Spec.Versions {
+		if ver.Storage {
+			versions = append(versions, ver.Name)
+		}
+	}
+	for _, ver := range versions {
+		for pkg, gv := range parser.GroupVersions {
+			if gv.Group != groupKind.Group || gv.Version != ver {
+				continue
+			}
+			if info := parser.Types[crdgen.TypeIdent{Package: pkg, Name: groupKind.Kind}]; info != nil {
+				return pkg, info.RawSpec
+			}
+		}
+	}
+	return nil, nil
+}
+
+// crdsFromDirectory loads all the v1 CRDs from the YAML files in the given
+// directory (which may contain multiple documents each).
+func crdsFromDirectory(ctx *genall.GenerationContext, dir string) (map[schema.GroupKind]apiext.CustomResourceDefinition, error) {
+	res := map[schema.GroupKind]apiext.CustomResourceDefinition{}
+	dirEntries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fileInfo := range dirEntries {
+		// find all files that are YAML
+		if fileInfo.IsDir() || (filepath.Ext(fileInfo.Name()) != ".yaml" && filepath.Ext(fileInfo.Name()) != ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, fileInfo.Name())
+		rawContent, err := ctx.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(rawContent)))
+		for {
+			doc, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("load %q: %w", path, err)
+			}
+
+			// skip anything that's not a CRD
+			var typeMeta metav1.TypeMeta
+			if err := kyaml.Unmarshal(doc, &typeMeta); err != nil || typeMeta.Kind != "CustomResourceDefinition" {
+				continue
+			}
+			if typeMeta.APIVersion != apiext.SchemeGroupVersion.String() {
+				return nil, fmt.Errorf("load %q: apiVersion %q not supported", path, typeMeta.APIVersion)
+			}
+
+			var crd apiext.CustomResourceDefinition
+			if err := kyaml.Unmarshal(doc, &crd); err != nil {
+				return nil, fmt.Errorf("load %q: %w", path, err)
+			}
+			res[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd
+		}
+	}
+	return res, nil
+}
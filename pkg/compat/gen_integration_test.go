@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "sigs.k8s.io/controller-tools/pkg/compat"
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+// runAgainst runs the compat generator against the given baseline directory
+// in testdata, returning whether it failed along with the problems it found.
+func runAgainst(baseline string) (bool, []genall.Diagnostic) {
+	return run(Generator{Baseline: baseline})
+}
+
+// run is like runAgainst, but with the given generator.
+func run(gen Generator) (bool, []genall.Diagnostic) {
+	By("switching into testdata to appease go modules")
+	cwd, err := os.Getwd()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+	defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+	By("loading the generation runtime")
+	var compatGen genall.Generator = &gen
+	rt, err := genall.Generators{&compatGen}.ForRoots("./...")
+	Expect(err).NotTo(HaveOccurred())
+	errOut := &bytes.Buffer{}
+	rt.ErrorWriter = errOut
+	rt.DiagnosticsFormat = genall.DiagnosticsJSON
+
+	By("running the generator")
+	failed := rt.Run()
+
+	var diags []genall.Diagnostic
+	for _, line := range strings.Split(strings.TrimSpace(errOut.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var diag genall.Diagnostic
+		Expect(json.Unmarshal([]byte(line), &diag)).To(Succeed())
+		diags = append(diags, diag)
+	}
+	return failed, diags
+}
+
+// messagesWith returns the messages of the diagnostics with the given severity.
+func messagesWith(severity genall.Severity, diags []genall.Diagnostic) []string {
+	var res []string
+	for _, diag := range diags {
+		if diag.Severity == severity {
+			res = append(res, diag.Message)
+		}
+	}
+	return res
+}
+
+var _ = Describe("CRD Compatibility Checking", func() {
+	It("should warn about non-breaking changes without failing", func() {
+		failed, diags := runAgainst("./compatible")
+		Expect(failed).To(BeFalse(), "unexpectedly had errors")
+
+		Expect(messagesWith(genall.SeverityError, diags)).To(BeEmpty())
+		Expect(messagesWith(genall.SeverityWarning, diags)).To(ConsistOf(
+			"Widget.compat.testdata.kubebuilder.io: non-breaking change in version v1 at .spec.color: field was added",
+			"Widget.compat.testdata.kubebuilder.io: non-breaking change in version v1 at .spec.replicas: maximum was raised from 5 to 10",
+		))
+		for _, diag := range diags {
+			Expect(diag.File).To(HaveSuffix("types.go"))
+			Expect(diag.Line).To(Equal(26))
+		}
+	})
+
+	It("should fail on breaking changes", func() {
+		failed, diags := runAgainst("./breaking")
+		Expect(failed).To(BeTrue(), "unexpectedly succeeded")
+
+		Expect(messagesWith(genall.SeverityError, diags)).To(ConsistOf(
+			"Widget.compat.testdata.kubebuilder.io: breaking change in version v1 at .spec.legacyName: field was removed",
+			"Widget.compat.testdata.kubebuilder.io: breaking change in version v1 at .spec.replicas: field is newly required",
+			"Widget.compat.testdata.kubebuilder.io: breaking change in version v1 at .spec.replicas: maximum was lowered from 20 to 10",
+			`Widget.compat.testdata.kubebuilder.io: breaking change in version v1 at .spec.size: enum value "huge" was removed`,
+			"Widget.compat.testdata.kubebuilder.io: breaking change in version v1beta1: served version was removed",
+			"Gizmo.compat.testdata.kubebuilder.io: breaking change: CRD was removed",
+		))
+	})
+
+	It("should honor the schema options the baseline was generated with", func() {
+		By("checking against a baseline generated with embedded ObjectMeta")
+		embedObjectMeta := true
+		failed, diags := run(Generator{
+			Baseline:      "./embeddedobjectmeta",
+			ParserOptions: crdgen.ParserOptions{GenerateEmbeddedObjectMeta: &embedObjectMeta},
+		})
+		Expect(failed).To(BeFalse(), "unexpectedly had errors")
+		Expect(diags).To(BeEmpty())
+
+		By("checking against the same baseline without embedded ObjectMeta")
+		failed, diags = runAgainst("./embeddedobjectmeta")
+		Expect(failed).To(BeTrue(), "unexpectedly succeeded")
+		Expect(messagesWith(genall.SeverityError, diags)).To(ContainElement(
+			"WidgetSet.templates.compat.testdata.kubebuilder.io: breaking change in version v1 at .spec.template.metadata.labels: field was removed",
+		))
+	})
+
+	It("should fail if the baseline can't be loaded", func() {
+		failed, _ := runAgainst("./nonexistent")
+		Expect(failed).To(BeTrue(), "unexpectedly succeeded")
+	})
+})
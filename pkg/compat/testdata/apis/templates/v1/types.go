@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// WidgetSet is a kind whose schema embeds ObjectMeta, and so depends on
+// whether that's generated.
+type WidgetSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WidgetSetSpec `json:"spec,omitempty"`
+}
+
+// WidgetSetSpec is the spec for a WidgetSet.
+type WidgetSetSpec struct {
+	// Template is the template for the widgets in the set.
+	Template WidgetTemplate `json:"template"`
+}
+
+// WidgetTemplate is the template for a widget.
+type WidgetTemplate struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Size is the size of the widget.
+	Size string `json:"size"`
+}
+
+// +kubebuilder:object:root=true
+
+// WidgetSetList contains a list of WidgetSet.
+type WidgetSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WidgetSet `json:"items"`
+}
@@ -0,0 +1,49 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by helpgen. DO NOT EDIT.
+
+package compat
+
+import (
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+func (Generator) Help() *markers.DefinitionHelp {
+	help := &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "checks the CRDs for the roots for breaking changes against a baseline of previously generated CRDs. ",
+			Details: "It doesn't write anything: breaking changes fail the run, and non-breaking ones are reported as warnings.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Baseline": {
+				Summary: "is the directory containing the CRD manifests to compare against (e.g. the manifests from the last release, or the ones currently checked in).",
+				Details: "",
+			},
+		},
+	}
+
+	for field, fieldHelp := range (crdgen.ParserOptions{}).Help().FieldHelp {
+		help.FieldHelp[field] = fieldHelp
+	}
+
+	return help
+}
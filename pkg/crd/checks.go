@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// declarationCheck checks something declared on a type or field, given the
+// flattened schema for that type or field (flattened), and the unflattened
+// one (own), which only contains what was declared on it directly.
+type declarationCheck func(pkg *loader.Package, flattened, own apiext.JSONSchemaProps, isResourceRoot bool, node ast.Node)
+
+// checkDeclarations runs the given check on the given type and each of its
+// fields, and then on every type it references, skipping types that have
+// already been checked.
+func (p *Parser) checkDeclarations(typ TypeIdent, isResourceRoot bool, checked map[TypeIdent]struct{}, check declarationCheck) {
+	if _, isChecked := checked[typ]; isChecked {
+		return
+	}
+	checked[typ] = struct{}{}
+
+	info := p.Types[typ]
+	if info == nil {
+		// known or overridden types don't have any markers to check
+		return
+	}
+	p.NeedFlattenedSchemaFor(typ)
+	own, flattened := p.Schemata[typ], p.FlattenedSchemata[typ]
+
+	check(typ.Package, flattened, own, isResourceRoot, info.RawSpec)
+	for _, field := range info.Fields {
+		fieldName, inline := jsonFieldName(field.Tag.Get("json"))
+		if fieldName == "" || inline {
+			continue
+		}
+		ownProp, hasOwn := own.Properties[fieldName]
+		flatProp, hasFlat := flattened.Properties[fieldName]
+		if !hasOwn || !hasFlat {
+			continue
+		}
+		check(typ.Package, flatProp, ownProp, false, field.RawField)
+	}
+
+	// check anything we reference too (sorted, so that errors come out in
+	// the same order each time)
+	var refs []string
+	EditSchema(&own, refCollector{refs: &refs})
+	sort.Strings(refs)
+	for _, ref := range refs {
+		refIdent, err := identFromRef(ref, typ.Package)
+		if err != nil || refIdent.Package == nil {
+			// the flattener already reported this
+			continue
+		}
+		p.checkDeclarations(refIdent, false, checked, check)
+	}
+}
+
+// jsonFieldName returns the name of a field in its serialized form, given
+// its JSON tag, and whether or not it's inlined into its parent.  Fields
+// that aren't serialized have an empty name.
+func jsonFieldName(jsonTag string) (string, bool) {
+	jsonOpts := strings.Split(jsonTag, ",")
+	if jsonTag == "" || (len(jsonOpts) == 1 && jsonOpts[0] == "-") {
+		return "", false
+	}
+	for _, opt := range jsonOpts[1:] {
+		if opt == "inline" {
+			return jsonOpts[0], true
+		}
+	}
+	return jsonOpts[0], jsonOpts[0] == ""
+}
+
+// refCollector collects all the references in a schema.
+type refCollector struct {
+	refs *[]string
+}
+
+func (c refCollector) Visit(schema *apiext.JSONSchemaProps) SchemaVisitor {
+	if schema == nil {
+		return c
+	}
+	if schema.Ref != nil && *schema.Ref != "" {
+		*c.refs = append(*c.refs, *schema.Ref)
+	}
+	return c
+}
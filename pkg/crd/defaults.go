@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// NeedDefaultsCheckedFor validates the default values (+kubebuilder:default)
+// declared on the given type, and on every type it references, against the
+// final (flattened) schema of the node they're attached to, the same way the
+// apiserver will when the CRD is created.  Defaults with the wrong type, that
+// don't match an enum, pattern or bounds, that are missing required nested
+// fields, that contain fields that would be pruned, or that fail validation
+// rules are reported as errors on the type or field that declares them.
+//
+// isResourceRoot indicates that the type is the root of a CRD.
+func (p *Parser) NeedDefaultsCheckedFor(typ TypeIdent, isResourceRoot bool) {
+	p.init()
+	p.checkDeclarations(typ, isResourceRoot, p.checkedDefaults, checkDefault)
+}
+
+// checkDefault validates the default value declared on the given node (if
+// any) against the node's flattened schema.
+func checkDefault(pkg *loader.Package, flattened, own apiext.JSONSchemaProps, isResourceRoot bool, node ast.Node) {
+	if own.Default == nil {
+		return
+	}
+
+	// defaults nested further down were declared elsewhere, and are checked there
+	schema := flattened.DeepCopy()
+	EditSchema(schema, nestedDefaultRemover{root: schema})
+
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiext.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, internal, nil); err != nil {
+		pkg.AddError(loader.ErrFromNode(err, node))
+		return
+	}
+	structural, err := structuralschema.NewStructural(internal)
+	if err != nil {
+		// the apiserver won't accept this schema at all, so there's nothing to
+		// check the default against
+		return
+	}
+	errs, err := structuraldefaulting.ValidateDefaults(context.Background(), nil, structural, isResourceRoot, true)
+	if err != nil {
+		pkg.AddError(loader.ErrFromNode(fmt.Errorf("unable to validate default value: %w", err), node))
+		return
+	}
+	for _, validationErr := range errs {
+		pkg.AddError(loader.ErrFromNode(fmt.Errorf("invalid default value: %v", validationErr), node))
+	}
+}
+
+// nestedDefaultRemover removes default values from all schema nodes but the root.
+type nestedDefaultRemover struct {
+	root *apiext.JSONSchemaProps
+}
+
+func (v nestedDefaultRemover) Visit(schema *apiext.JSONSchemaProps) SchemaVisitor {
+	if schema != nil && schema != v.root {
+		schema.Default = nil
+	}
+	return v
+}
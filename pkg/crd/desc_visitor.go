@@ -27,7 +27,16 @@ import (
 // exceeds maxLen.
 // It tries to chop off the description at the closest sentence boundary.
 func TruncateDescription(schema *apiext.JSONSchemaProps, maxLen int) {
-	EditSchema(schema, descVisitor{maxLen: maxLen})
+	truncateDescriptions(schema, maxLen)
+}
+
+// truncateDescriptions is like TruncateDescription, but returns the number
+// of descriptions that were cut short (as opposed to dropped, when maxLen
+// is 0).
+func truncateDescriptions(schema *apiext.JSONSchemaProps, maxLen int) int {
+	truncated := 0
+	EditSchema(schema, descVisitor{maxLen: maxLen, truncated: &truncated})
+	return truncated
 }
 
 // descVisitor recursively visits all fields in the schema and truncates the
@@ -35,6 +44,8 @@ func TruncateDescription(schema *apiext.JSONSchemaProps, maxLen int) {
 type descVisitor struct {
 	// maxLen is the maximum allowed length for decription of a field
 	maxLen int
+	// truncated counts the descriptions that have been truncated
+	truncated *int
 }
 
 func (v descVisitor) Visit(schema *apiext.JSONSchemaProps) SchemaVisitor {
@@ -50,6 +61,7 @@ func (v descVisitor) Visit(schema *apiext.JSONSchemaProps) SchemaVisitor {
 	}
 	if len(schema.Description) > v.maxLen {
 		schema.Description = truncateString(schema.Description, v.maxLen)
+		*v.truncated++
 		return v
 	}
 	return v
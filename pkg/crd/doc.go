@@ -60,4 +60,19 @@ limitations under the License.
 // Flattened schemata may further be passed to FlattenEmbedded to remove the
 // use of AllOf (which is used to describe embedded struct fields when
 // references are in use).  This done automatically when fetching CRDs.
+//
+// Validation Rules and Defaults
+//
+// CEL validation rules are compiled against the flattened schema of the node
+// they're attached to, like the apiserver does, when requesting CRDs (or
+// directly with NeedRulesCheckedFor).  Rules that don't compile are reported
+// on the type or field that declares them.  With EstimateRuleCost set, the
+// cost of each rule is checked against the apiserver's limits as well.
+//
+// Default values are similarly validated against the flattened schema of the
+// node they're attached to (see NeedDefaultsCheckedFor).
+//
+// Finally, ValidateCRD runs the apiserver's own CRD validation over a
+// finished CRD, reporting anything it would reject on the Go field or type
+// that produced the offending part of the schema.
 package crd
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// Explain says why no CRDs are generated for any of the roots that don't
+// have any, as well as which types look like they're meant to be kinds, but
+// aren't.
+func (g Generator) Explain(ctx *genall.GenerationContext) []genall.Explanation {
+	parser := g.parserFor(ctx)
+	var explanations []genall.Explanation
+	for _, root := range ctx.Roots {
+		if reasons := explainPackage(parser, root); len(reasons) > 0 {
+			explanations = append(explanations, genall.Explanation{Package: root, Reasons: reasons})
+		}
+	}
+	return explanations
+}
+
+// explainPackage returns the reasons that no CRDs (or fewer than might be
+// expected) are generated for the given package.
+func explainPackage(parser *Parser, pkg *loader.Package) []error {
+	pkgMarkers, err := markers.PackageMarkers(parser.Collector, pkg)
+	if err != nil {
+		// already reported when generating
+		return nil
+	}
+	if pkgMarkers.Get("kubebuilder:skip") != nil {
+		return []error{errors.New("no CRDs generated: the package is marked +kubebuilder:skip")}
+	}
+	if pkgMarkers.Get("groupName") == nil {
+		return []error{errors.New("no CRDs generated: the package has no +groupName marker, so its types aren't part of any API group")}
+	}
+	metav1Pkg := pkg.Imports()[metav1Path]
+	if metav1Pkg == nil {
+		return []error{fmt.Errorf("no CRDs generated: the package doesn't import %s, so none of its types embed metav1.TypeMeta and metav1.ObjectMeta", metav1Path)}
+	}
+
+	parser.NeedPackage(pkg)
+	var reasons []error
+	hasKinds := false
+	if err := markers.EachType(parser.Collector, pkg, func(info *markers.TypeInfo) {
+		embedded := embeddedMetaTypes(pkg, info, metav1Pkg)
+		switch {
+		case embedded["TypeMeta"] && embedded["ObjectMeta"]:
+			hasKinds = true
+		case embedded["ListMeta"]:
+			// lists are served along with their items, not as kinds of their own
+		case embedded["TypeMeta"]:
+			reasons = append(reasons, loader.ErrFromNode(fmt.Errorf("no CRD generated for %s: it embeds metav1.TypeMeta, but not metav1.ObjectMeta", info.Name), info.RawSpec))
+		case embedded["ObjectMeta"]:
+			reasons = append(reasons, loader.ErrFromNode(fmt.Errorf("no CRD generated for %s: it embeds metav1.ObjectMeta, but not metav1.TypeMeta", info.Name), info.RawSpec))
+		}
+	}); err != nil {
+		// already reported when generating
+		return nil
+	}
+	if !hasKinds && len(reasons) == 0 {
+		reasons = append(reasons, errors.New("no CRDs generated: none of the package's types embed both metav1.TypeMeta and metav1.ObjectMeta"))
+	}
+	return reasons
+}
@@ -117,7 +117,7 @@ func flattenAllOfInto(dst *apiext.JSONSchemaProps, src apiext.JSONSchemaProps, e
 				flattenAllOfInto(&dstProp, v, errRec)
 				dstMap[k] = dstProp
 			}
-		case "Required":
+		case "Required", "XValidations":
 			// merge
 			dstField.Set(reflect.AppendSlice(dstField, srcField))
 		case "Type":
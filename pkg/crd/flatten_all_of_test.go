@@ -193,6 +193,26 @@ var _ = Describe("AllOf Flattening", func() {
 			})))
 		})
 
+		It("should merge XValidations, keeping the rules from every branch", func() {
+			By("flattening a schema with validation rules in multiple branches")
+			original := &apiext.JSONSchemaProps{
+				AllOf: []apiext.JSONSchemaProps{
+					{XValidations: apiext.ValidationRules{{Rule: "has(self.foo)"}}},
+					{XValidations: apiext.ValidationRules{{Rule: "self == oldSelf", Message: "Value is immutable"}}},
+				},
+			}
+			flattened := crd.FlattenEmbedded(original, errRec)
+			Expect(errRec.FirstError()).NotTo(HaveOccurred())
+
+			By("ensuring that the result has all the rules, with no branches")
+			Expect(flattened).To(Equal(&apiext.JSONSchemaProps{
+				XValidations: apiext.ValidationRules{
+					{Rule: "has(self.foo)"},
+					{Rule: "self == oldSelf", Message: "Value is immutable"},
+				},
+			}))
+		})
+
 		It("should merge Properties when possible, pushing AllOf inside Properties when not possible", func() {
 			By("flattening a schema with some conflicting and some non-conflicting Properties branches")
 			defSeven := float64(7)
@@ -17,6 +17,7 @@ limitations under the License.
 package crd
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/types"
@@ -38,10 +39,16 @@ const v1 = "v1"
 // The default CustomResourceDefinition version to generate.
 const defaultVersion = v1
 
+// The import path of the package containing TypeMeta and ObjectMeta.
+const metav1Path = "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 // +controllertools:marker:generateHelp
 
-// Generator generates CustomResourceDefinition objects.
-type Generator struct {
+// ParserOptions are the options that affect the schemata produced by a Parser.
+//
+// Generators that produce schemata embed them, so that they accept the same
+// options as the crd generator.
+type ParserOptions struct {
 	// IgnoreUnexportedFields indicates that we should skip unexported fields.
 	//
 	// Left unspecified, the default is false.
@@ -57,6 +64,63 @@ type Generator struct {
 	// Left unspecified, the default is false
 	AllowDangerousTypes *bool `marker:",optional"`
 
+	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta in the CRD should be generated
+	GenerateEmbeddedObjectMeta *bool `marker:",optional"`
+
+	// EstimateRuleCost estimates the cost of the CEL validation rules
+	// (+kubebuilder:validation:XValidation) in each CRD the same way the
+	// apiserver does, and fails generation if any rule, or all the rules in
+	// a version together, exceed the apiserver's limits.
+	//
+	// Left unspecified, the default is false.
+	EstimateRuleCost *bool `marker:",optional"`
+}
+
+// CheckFilter returns the filter for the types that a Parser needs checked,
+// for generators that embed ParserOptions.
+func (ParserOptions) CheckFilter() loader.NodeFilter {
+	return filterTypesForCRDs
+}
+
+// RegisterMarkers registers the markers that a Parser needs, for generators
+// that embed ParserOptions.
+func (ParserOptions) RegisterMarkers(into *markers.Registry) error {
+	return crdmarkers.Register(into)
+}
+
+// NewParser returns a Parser with these options for the given context, with
+// the known types (like metav1.ObjectMeta) already added.
+func (o ParserOptions) NewParser(ctx *genall.GenerationContext) *Parser {
+	parser := &Parser{
+		Collector: ctx.Collector,
+		Checker:   ctx.Checker,
+		// Perform defaulting here to avoid ambiguity later
+		IgnoreUnexportedFields: o.IgnoreUnexportedFields != nil && *o.IgnoreUnexportedFields == true,
+		AllowDangerousTypes:    o.AllowDangerousTypes != nil && *o.AllowDangerousTypes == true,
+		// Indicates the parser on whether to register the ObjectMeta type or not
+		GenerateEmbeddedObjectMeta: o.GenerateEmbeddedObjectMeta != nil && *o.GenerateEmbeddedObjectMeta == true,
+		EstimateRuleCost:           o.EstimateRuleCost != nil && *o.EstimateRuleCost == true,
+	}
+	AddKnownTypes(parser)
+	return parser
+}
+
+// ParserForRoots is like NewParser, except that the returned Parser has
+// already processed the roots of the given context.
+func (o ParserOptions) ParserForRoots(ctx *genall.GenerationContext) *Parser {
+	parser := o.NewParser(ctx)
+	for _, root := range ctx.Roots {
+		parser.NeedPackage(root)
+	}
+	return parser
+}
+
+// +controllertools:marker:generateHelp
+
+// Generator generates CustomResourceDefinition objects.
+type Generator struct {
+	ParserOptions
+
 	// MaxDescLen specifies the maximum description length for fields in CRD's OpenAPI schema.
 	//
 	// 0 indicates drop the description for all fields completely.
@@ -76,15 +140,16 @@ type Generator struct {
 	// along with an API server that supports it (Kubernetes 1.16+).
 	CRDVersions []string `marker:"crdVersions,optional"`
 
-	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta in the CRD should be generated
-	GenerateEmbeddedObjectMeta *bool `marker:",optional"`
-}
+	// Format is the format to write manifests in: yaml (the default), json,
+	// jsonl (compact JSON, one object per line), or list (a JSON v1 List
+	// holding all the objects in a file).
+	Format genall.ManifestFormat `marker:",optional"`
 
-func (Generator) CheckFilter() loader.NodeFilter {
-	return filterTypesForCRDs
-}
-func (Generator) RegisterMarkers(into *markers.Registry) error {
-	return crdmarkers.Register(into)
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files,
+	// as a YAML comment.
+	HeaderFile string `marker:",optional"`
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
 }
 
 // transformRemoveCRDStatus ensures we do not write the CRD status field.
@@ -93,19 +158,44 @@ func transformRemoveCRDStatus(obj map[string]interface{}) error {
 	return nil
 }
 
+// parserFor returns the Parser to use with the given context, reusing the
+// one from the previous run if the context has a cache.
+func (g Generator) parserFor(ctx *genall.GenerationContext) *Parser {
+	if ctx.Cache != nil {
+		if parser, cached := ctx.Cache.Value.(*Parser); cached {
+			return parser
+		}
+	}
+
+	parser := g.NewParser(ctx)
+
+	if ctx.Cache != nil {
+		ctx.Cache.Value = parser
+	}
+	return parser
+}
+
 func (g Generator) Generate(ctx *genall.GenerationContext) error {
-	parser := &Parser{
-		Collector: ctx.Collector,
-		Checker:   ctx.Checker,
-		// Perform defaulting here to avoid ambiguity later
-		IgnoreUnexportedFields: g.IgnoreUnexportedFields != nil && *g.IgnoreUnexportedFields == true,
-		AllowDangerousTypes:    g.AllowDangerousTypes != nil && *g.AllowDangerousTypes == true,
-		// Indicates the parser on whether to register the ObjectMeta type or not
-		GenerateEmbeddedObjectMeta: g.GenerateEmbeddedObjectMeta != nil && *g.GenerateEmbeddedObjectMeta == true,
+	return g.GenerateContext(context.Background(), ctx)
+}
+
+// GenerateContext is like Generate, but stops between packages and kinds
+// once the given context is done.
+func (g Generator) GenerateContext(goCtx context.Context, ctx *genall.GenerationContext) error {
+	if err := g.Format.Validate(); err != nil {
+		return err
+	}
+	header, err := ctx.ReadHeader(g.HeaderFile, g.Year)
+	if err != nil {
+		return err
 	}
 
-	AddKnownTypes(parser)
+	parser := g.parserFor(ctx)
 	for _, root := range ctx.Roots {
+		// check ahead of time, so that we can stop in the middle of it
+		if err := parser.Checker.CheckContext(goCtx, root); err != nil {
+			return err
+		}
 		parser.NeedPackage(root)
 	}
 
@@ -129,6 +219,9 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 	}
 
 	for _, groupKind := range kubeKinds {
+		if err := goCtx.Err(); err != nil {
+			return err
+		}
 		parser.NeedCRDFor(groupKind, g.MaxDescLen)
 		crdRaw := parser.CustomResourceDefinitions[groupKind]
 		addAttribution(&crdRaw)
@@ -136,6 +229,9 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 		// Prevent the top level metadata for the CRD to be generate regardless of the intention in the arguments
 		FixTopLevelMetadata(crdRaw)
 
+		// catch anything the apiserver would reject before we write it out
+		parser.ValidateCRD(groupKind, crdRaw)
+
 		versionedCRDs := make([]interface{}, len(crdVersions))
 		for i, ver := range crdVersions {
 			conv, err := AsVersion(crdRaw, schema.GroupVersion{Group: apiext.SchemeGroupVersion.Group, Version: ver})
@@ -153,7 +249,7 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 			} else {
 				fileName = fmt.Sprintf("%s_%s.%s.yaml", crdRaw.Spec.Group, crdRaw.Spec.Names.Plural, crdVersions[i])
 			}
-			if err := ctx.WriteYAML(fileName, []interface{}{crd}, genall.WithTransform(transformRemoveCRDStatus)); err != nil {
+			if err := ctx.WriteYAML(fileName, []interface{}{crd}, genall.WithTransform(transformRemoveCRDStatus), genall.WithFormat(g.Format), genall.WithHeader(header)); err != nil {
 				return err
 			}
 		}
@@ -199,14 +295,14 @@ func addAttribution(crd *apiext.CustomResourceDefinition) {
 	if crd.ObjectMeta.Annotations == nil {
 		crd.ObjectMeta.Annotations = map[string]string{}
 	}
-	crd.ObjectMeta.Annotations["controller-gen.kubebuilder.io/version"] = version.Version()
+	crd.ObjectMeta.Annotations[genall.GeneratedAnnotation] = version.Version()
 }
 
 // FindMetav1 locates the actual package representing metav1 amongst
 // the imports of the roots.
 func FindMetav1(roots []*loader.Package) *loader.Package {
 	for _, root := range roots {
-		pkg := root.Imports()["k8s.io/apimachinery/pkg/apis/meta/v1"]
+		pkg := root.Imports()[metav1Path]
 		if pkg != nil {
 			return pkg
 		}
@@ -214,6 +310,17 @@ func FindMetav1(roots []*loader.Package) *loader.Package {
 	return nil
 }
 
+// FindKubeKindsInRoots is like FindKubeKinds, except that it locates metav1
+// amongst the imports of the given roots first.  It returns nothing if none
+// of them import metav1, since they can't contain any objects then.
+func FindKubeKindsInRoots(parser *Parser, roots []*loader.Package) []schema.GroupKind {
+	metav1Pkg := FindMetav1(roots)
+	if metav1Pkg == nil {
+		return nil
+	}
+	return FindKubeKinds(parser, metav1Pkg)
+}
+
 // FindKubeKinds locates all types that contain TypeMeta and ObjectMeta
 // (and thus may be a Kubernetes object), and returns the corresponding
 // group-kinds.
@@ -221,50 +328,9 @@ func FindKubeKinds(parser *Parser, metav1Pkg *loader.Package) []schema.GroupKind
 	// TODO(directxman12): technically, we should be finding metav1 per-package
 	kubeKinds := map[schema.GroupKind]struct{}{}
 	for typeIdent, info := range parser.Types {
-		hasObjectMeta := false
-		hasTypeMeta := false
-
 		pkg := typeIdent.Package
-		pkg.NeedTypesInfo()
-		typesInfo := pkg.TypesInfo
-
-		for _, field := range info.Fields {
-			if field.Name != "" {
-				// type and object meta are embedded,
-				// so they can't be this
-				continue
-			}
-
-			fieldType := typesInfo.TypeOf(field.RawField.Type)
-			namedField, isNamed := fieldType.(*types.Named)
-			if !isNamed {
-				// ObjectMeta and TypeMeta are named types
-				continue
-			}
-			if namedField.Obj().Pkg() == nil {
-				// Embedded non-builtin universe type (specifically, it's probably `error`),
-				// so it can't be ObjectMeta or TypeMeta
-				continue
-			}
-			fieldPkgPath := loader.NonVendorPath(namedField.Obj().Pkg().Path())
-			fieldPkg := pkg.Imports()[fieldPkgPath]
-
-			// Compare the metav1 package by ID and not by the actual instance
-			// of the object. The objects in memory could be different due to
-			// loading from different root paths, even when they both refer to
-			// the same metav1 package.
-			if fieldPkg == nil || fieldPkg.ID != metav1Pkg.ID {
-				continue
-			}
-
-			switch namedField.Obj().Name() {
-			case "ObjectMeta":
-				hasObjectMeta = true
-			case "TypeMeta":
-				hasTypeMeta = true
-			}
-		}
-
+		embedded := embeddedMetaTypes(pkg, info, metav1Pkg)
+		hasObjectMeta, hasTypeMeta := embedded["ObjectMeta"], embedded["TypeMeta"]
 		if !hasObjectMeta || !hasTypeMeta {
 			continue
 		}
@@ -287,6 +353,47 @@ func FindKubeKinds(parser *Parser, metav1Pkg *loader.Package) []schema.GroupKind
 	return groupKindList
 }
 
+// embeddedMetaTypes returns the names of the types from metav1 (like
+// TypeMeta and ObjectMeta) that are embedded in the given type.
+func embeddedMetaTypes(pkg *loader.Package, info *markers.TypeInfo, metav1Pkg *loader.Package) map[string]bool {
+	pkg.NeedTypesInfo()
+	typesInfo := pkg.TypesInfo
+
+	embedded := make(map[string]bool)
+	for _, field := range info.Fields {
+		if field.Name != "" {
+			// type and object meta are embedded,
+			// so they can't be this
+			continue
+		}
+
+		fieldType := typesInfo.TypeOf(field.RawField.Type)
+		namedField, isNamed := fieldType.(*types.Named)
+		if !isNamed {
+			// ObjectMeta and TypeMeta are named types
+			continue
+		}
+		if namedField.Obj().Pkg() == nil {
+			// Embedded non-builtin universe type (specifically, it's probably `error`),
+			// so it can't be ObjectMeta or TypeMeta
+			continue
+		}
+		fieldPkgPath := loader.NonVendorPath(namedField.Obj().Pkg().Path())
+		fieldPkg := pkg.Imports()[fieldPkgPath]
+
+		// Compare the metav1 package by ID and not by the actual instance
+		// of the object. The objects in memory could be different due to
+		// loading from different root paths, even when they both refer to
+		// the same metav1 package.
+		if fieldPkg == nil || fieldPkg.ID != metav1Pkg.ID {
+			continue
+		}
+
+		embedded[namedField.Obj().Name()] = true
+	}
+	return embedded
+}
+
 // filterTypesForCRDs filters out all nodes that aren't used in CRD generation,
 // like interfaces and struct fields without JSON tag.
 func filterTypesForCRDs(node ast.Node) bool {
@@ -18,6 +18,7 @@ package crd_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -101,6 +102,33 @@ var _ = Describe("CRD Generation proper defaulting", func() {
 		Expect(out.buf.String()).To(Equal(string(expectedFile)), cmp.Diff(out.buf.String(), string(expectedFile)))
 	})
 
+	It("should warn about truncated descriptions", func() {
+		By("calling Generate with a short maximum description length")
+		maxDescLen := 10
+		gen := &crd.Generator{
+			MaxDescLen: &maxDescLen,
+		}
+		Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+
+		By("checking the warnings on the package")
+		warnings := ctx.Roots[0].WarningDetails()
+		Expect(warnings).To(HaveLen(1))
+		Expect(warnings[0].Msg).To(MatchRegexp(`^truncated \d+ description\(s\) in the schema for Foo to at most 10 characters \(see crd:maxDescLen\)$`))
+		Expect(filepath.Base(warnings[0].Position.Filename)).To(Equal("foo_types.go"))
+	})
+
+	It("should not warn about descriptions that didn't need truncating, or were dropped deliberately", func() {
+		for _, maxDescLen := range []int{0, 100000} {
+			By(fmt.Sprintf("calling Generate with a maximum description length of %d", maxDescLen))
+			maxDescLen := maxDescLen
+			gen := &crd.Generator{
+				MaxDescLen: &maxDescLen,
+			}
+			Expect(gen.Generate(ctx)).NotTo(HaveOccurred())
+			Expect(ctx.Roots[0].WarningDetails()).To(BeEmpty())
+		}
+	})
+
 	It("should have deterministic output", func() {
 		By("calling Generate on multple packages")
 		gen := &crd.Generator{
@@ -142,3 +170,68 @@ type nopCloser struct {
 func (n nopCloser) Close() error {
 	return nil
 }
+
+var _ = Describe("CRD Generation explanations", func() {
+	It("should say why no CRDs were generated for each package", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(filepath.Join("testdata", "explain"))).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(2))
+
+		By("asking the generator for explanations")
+		reg := &markers.Registry{}
+		Expect(crdmarkers.Register(reg)).To(Succeed())
+		ctx := &genall.GenerationContext{
+			Collector:  &markers.Collector{Registry: reg},
+			Roots:      pkgs,
+			Checker:    &loader.TypeChecker{},
+			OutputRule: genall.OutputToNothing,
+		}
+		reasons := make(map[string][]string)
+		for _, explanation := range (crd.Generator{}).Explain(ctx) {
+			for _, reason := range explanation.Reasons {
+				reasons[explanation.Package.Name] = append(reasons[explanation.Package.Name], reason.Error())
+			}
+		}
+
+		By("checking the reasons for each package")
+		Expect(reasons).To(HaveKeyWithValue("nogroup", []string{
+			"no CRDs generated: the package has no +groupName marker, so its types aren't part of any API group",
+		}))
+		Expect(reasons).To(HaveKeyWithValue("halfkinds", []string{
+			"no CRD generated for Gadget: it embeds metav1.TypeMeta, but not metav1.ObjectMeta",
+			"no CRD generated for Gizmo: it embeds metav1.ObjectMeta, but not metav1.TypeMeta",
+		}))
+	})
+})
+
+var _ = Describe("CRD Generation without a conversion webhook", func() {
+	It("should still generate CRDs whose conversion is patched in afterwards", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(filepath.Join("testdata", "conversion"))).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("running the generator, like a kubebuilder project whose kustomization adds the conversion")
+		var gen genall.Generator = crd.Generator{}
+		rt, err := genall.Generators{&gen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		out := &outputRule{buf: &bytes.Buffer{}}
+		rt.OutputRules.Default = out
+		errOut := &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors:\n%s", errOut.String())
+
+		By("checking that the CRD was generated without a conversion, and with a warning")
+		Expect(out.buf.String()).To(ContainSubstring("kind: CustomResourceDefinition"))
+		Expect(out.buf.String()).NotTo(ContainSubstring("conversion:"))
+		Expect(errOut.String()).To(ContainSubstring("warning: CRD for Widget.conversion.example.com has versions with different schemata"))
+	})
+})
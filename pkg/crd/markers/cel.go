@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// +controllertools:marker:generateHelp:category="CRD validation"
+
+// Immutable marks a field (or type) as not being changeable once it's set.
+//
+// It's a shorthand for an XValidation rule of `self == oldSelf`.  Like all
+// transition rules, it's only checked when there's an old value, so an
+// optional field may still be set (or unset) later on.
+type Immutable struct {
+	// Message is the message to report when the value is changed.
+	//
+	// Defaults to "Value is immutable".
+	Message string `marker:",optional"`
+}
+
+// +controllertools:marker:generateHelp:category="CRD validation"
+
+// ExactlyOneOf requires exactly one of the given fields of this type to be set.
+//
+// The fields are named by their JSON names, like `+kubebuilder:validation:ExactlyOneOf={foo,bar}`.
+// This marker may be repeated to specify several groups of fields.
+type ExactlyOneOf []string
+
+// +controllertools:marker:generateHelp:category="CRD validation"
+
+// AtMostOneOf allows at most one of the given fields of this type to be set.
+//
+// The fields are named by their JSON names, like `+kubebuilder:validation:AtMostOneOf={foo,bar}`.
+// This marker may be repeated to specify several groups of fields.
+type AtMostOneOf []string
+
+// +controllertools:marker:generateHelp:category="CRD validation"
+
+// RequiredWhen requires a field of this type to be set when another field has a particular value.
+//
+// For example, `+kubebuilder:validation:RequiredWhen:field=url,when=type,equals=Webhook`
+// requires `url` to be set whenever `type` is "Webhook".  Fields are named by their
+// JSON names.  This marker may be repeated.
+type RequiredWhen struct {
+	// Field is the field that's required.
+	Field string
+	// When is the field whose value is checked.
+	When string
+	// Equals is the value (a string, number, or boolean) that makes Field required.
+	Equals interface{}
+}
+
+func (m Immutable) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	message := m.Message
+	if message == "" {
+		message = "Value is immutable"
+	}
+	schema.XValidations = append(schema.XValidations, apiext.ValidationRule{
+		Rule:    "self == oldSelf",
+		Message: message,
+	})
+	return nil
+}
+
+func (m ExactlyOneOf) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	checks, err := presenceChecks(schema, m)
+	if err != nil {
+		return fmt.Errorf("invalid ExactlyOneOf: %w", err)
+	}
+	schema.XValidations = append(schema.XValidations, apiext.ValidationRule{
+		Rule:    fmt.Sprintf("[%s].exists_one(x, x)", strings.Join(checks, ", ")),
+		Message: fmt.Sprintf("exactly one of %s must be set", describeFields(m)),
+	})
+	return nil
+}
+
+func (m AtMostOneOf) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	checks, err := presenceChecks(schema, m)
+	if err != nil {
+		return fmt.Errorf("invalid AtMostOneOf: %w", err)
+	}
+	schema.XValidations = append(schema.XValidations, apiext.ValidationRule{
+		Rule:    fmt.Sprintf("[%s].filter(x, x).size() <= 1", strings.Join(checks, ", ")),
+		Message: fmt.Sprintf("at most one of %s may be set", describeFields(m)),
+	})
+	return nil
+}
+
+func (m RequiredWhen) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	if err := checkFields(schema, []string{m.Field, m.When}); err != nil {
+		return fmt.Errorf("invalid RequiredWhen: %w", err)
+	}
+	value, err := celLiteral(m.Equals)
+	if err != nil {
+		return fmt.Errorf("invalid RequiredWhen: %w", err)
+	}
+	field, when := celFieldName(m.Field), celFieldName(m.When)
+	schema.XValidations = append(schema.XValidations, apiext.ValidationRule{
+		Rule:    fmt.Sprintf("!has(self.%[2]s) || self.%[2]s != %[3]s || has(self.%[1]s)", field, when, value),
+		Message: fmt.Sprintf("%s must be set when %s is %s", m.Field, m.When, value),
+	})
+	return nil
+}
+
+// presenceChecks returns CEL expressions checking if each of the given
+// fields is set, after making sure that they're fields of the schema.
+func presenceChecks(schema *apiext.JSONSchemaProps, fields []string) ([]string, error) {
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("need at least two fields, not %d", len(fields))
+	}
+	if err := checkFields(schema, fields); err != nil {
+		return nil, err
+	}
+	checks := make([]string, len(fields))
+	for i, field := range fields {
+		checks[i] = fmt.Sprintf("has(self.%s)", celFieldName(field))
+	}
+	return checks, nil
+}
+
+// checkFields makes sure that the given schema is for an object with all
+// of the given fields.  Fields that might come from embedded structs (which
+// are still just references at this point) can't be checked.
+func checkFields(schema *apiext.JSONSchemaProps, fields []string) error {
+	if schema.Type != "object" {
+		return fmt.Errorf("must apply to an object (struct), not %s", schema.Type)
+	}
+	for _, field := range fields {
+		if !celIdentifier.MatchString(field) {
+			return fmt.Errorf("%q can't be referred to in a CEL rule", field)
+		}
+		if _, known := schema.Properties[field]; !known && len(schema.AllOf) == 0 {
+			return fmt.Errorf("no such field %q", field)
+		}
+	}
+	return nil
+}
+
+// describeFields lists the given fields for use in messages, like "a, b, or c".
+func describeFields(fields []string) string {
+	switch len(fields) {
+	case 0:
+		return ""
+	case 1:
+		return fields[0]
+	case 2:
+		return fields[0] + " or " + fields[1]
+	default:
+		return strings.Join(fields[:len(fields)-1], ", ") + ", or " + fields[len(fields)-1]
+	}
+}
+
+// celLiteral renders the given marker value as a CEL literal.
+func celLiteral(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	default:
+		return "", fmt.Errorf("can only compare against strings, numbers, and booleans, not %T", value)
+	}
+}
+
+var (
+	// celIdentifier matches the property names that can be used in CEL
+	// rules (possibly after escaping).
+	celIdentifier = regexp.MustCompile(`^[a-zA-Z_.\-/][a-zA-Z0-9_.\-/]*$`)
+
+	// celReservedWords are the property names that need to be escaped to
+	// avoid clashing with CEL keywords.
+	celReservedWords = map[string]struct{}{
+		"true": {}, "false": {}, "null": {}, "in": {}, "as": {}, "break": {}, "const": {},
+		"continue": {}, "else": {}, "for": {}, "function": {}, "if": {}, "import": {},
+		"let": {}, "loop": {}, "package": {}, "namespace": {}, "return": {}, "var": {},
+		"void": {}, "while": {},
+	}
+
+	// celEscapes escapes the characters that CEL doesn't allow in
+	// identifiers, like the API server does.
+	celEscapes = strings.NewReplacer("__", "__underscores__", ".", "__dot__", "-", "__dash__", "/", "__slash__")
+)
+
+// celFieldName returns the name that a property is referred to by in CEL
+// rules, escaping it if necessary.
+func celFieldName(name string) string {
+	if _, reserved := celReservedWords[name]; reserved {
+		return "__" + name + "__"
+	}
+	return celEscapes.Replace(name)
+}
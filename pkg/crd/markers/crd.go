@@ -18,6 +18,7 @@ package markers
 
 import (
 	"fmt"
+	"reflect"
 
 	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
@@ -51,6 +52,9 @@ var CRDMarkers = []*definitionWithHelp{
 
 	must(markers.MakeDefinition("kubebuilder:deprecatedversion", markers.DescribesType, DeprecatedVersion{})).
 		WithHelp(DeprecatedVersion{}.Help()),
+
+	must(markers.MakeDefinition("kubebuilder:conversion:webhook", markers.DescribesType, ConversionWebhook{})).
+		WithHelp(ConversionWebhook{}.Help()),
 }
 
 // TODO: categories and singular used to be annotations types
@@ -345,3 +349,66 @@ func (s DeprecatedVersion) ApplyToCRD(crd *apiext.CustomResourceDefinitionSpec,
 	}
 	return nil
 }
+
+// +controllertools:marker:generateHelp:category=CRD
+
+// ConversionWebhook configures a webhook to convert between the versions of a CRD.
+//
+// It's normally placed on the storage (or "hub") version's type, and only
+// needs to be specified once per CRD.  CRDs with several versions whose
+// schemata differ need a conversion webhook, since the API server can
+// otherwise only convert between them by changing their apiVersion.
+type ConversionWebhook struct {
+	// ServiceName is the name of the Service that the webhook is served by.
+	ServiceName string
+
+	// ServiceNamespace is the namespace of the Service that the webhook is served by.
+	ServiceNamespace string
+
+	// Path is the URL path that the webhook is served at.
+	//
+	// Defaults to "/convert".
+	Path string `marker:",optional"`
+
+	// Port is the port on the Service that the webhook is served on.
+	//
+	// Defaults to 443.
+	Port *int32 `marker:",optional"`
+
+	// ConversionReviewVersions are the versions of ConversionReview that
+	// the webhook accepts, in order of preference.
+	//
+	// Defaults to v1.
+	ConversionReviewVersions []string `marker:",optional"`
+}
+
+func (s ConversionWebhook) ApplyToCRD(crd *apiext.CustomResourceDefinitionSpec, version string) error {
+	path := s.Path
+	if path == "" {
+		path = "/convert"
+	}
+	reviewVersions := s.ConversionReviewVersions
+	if len(reviewVersions) == 0 {
+		reviewVersions = []string{"v1"}
+	}
+
+	conversion := &apiext.CustomResourceConversion{
+		Strategy: apiext.WebhookConverter,
+		Webhook: &apiext.WebhookConversion{
+			ClientConfig: &apiext.WebhookClientConfig{
+				Service: &apiext.ServiceReference{
+					Name:      s.ServiceName,
+					Namespace: s.ServiceNamespace,
+					Path:      &path,
+					Port:      s.Port,
+				},
+			},
+			ConversionReviewVersions: reviewVersions,
+		},
+	}
+	if crd.Conversion != nil && !reflect.DeepEqual(crd.Conversion, conversion) {
+		return fmt.Errorf("conversion webhook for version %q differs from the one specified for another version", version)
+	}
+	crd.Conversion = conversion
+	return nil
+}
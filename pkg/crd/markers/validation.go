@@ -68,6 +68,7 @@ var ValidationMarkers = mustMakeAllWithPrefix("kubebuilder:validation", markers.
 	XEmbeddedResource{},
 	XIntOrString{},
 	XValidation{},
+	Immutable{},
 )
 
 // FieldOnlyMarkers list field-specific validation markers (i.e. those markers that don't make
@@ -93,6 +94,14 @@ var FieldOnlyMarkers = []*definitionWithHelp{
 		WithHelp(Schemaless{}.Help()),
 }
 
+// TypeOnlyMarkers list type-specific validation markers (i.e. those markers that
+// refer to fields of the type they're on, and thus don't make sense on a field).
+var TypeOnlyMarkers = mustMakeAllWithPrefix("kubebuilder:validation", markers.DescribesType,
+	ExactlyOneOf(nil),
+	AtMostOneOf(nil),
+	RequiredWhen{},
+)
+
 // ValidationIshMarkers are field-and-type markers that don't fall under the
 // :validation: prefix, and/or don't have a name that directly matches their
 // type.
@@ -118,6 +127,7 @@ func init() {
 	}
 
 	AllDefinitions = append(AllDefinitions, FieldOnlyMarkers...)
+	AllDefinitions = append(AllDefinitions, TypeOnlyMarkers...)
 	AllDefinitions = append(AllDefinitions, ValidationIshMarkers...)
 }
 
@@ -215,9 +225,9 @@ type Nullable struct{}
 // A default value will be accepted as any value valid for the
 // field. Formatting for common types include: boolean: `true`, string:
 // `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy:
-// "delete"}`). Defaults should be defined in pruned form, and only best-effort
-// validation will be performed. Full validation of a default requires
-// submission of the containing CRD to an apiserver.
+// "delete"}`). Defaults should be defined in pruned form, and are validated
+// against the final schema of the field when generating CRDs, the same way
+// the apiserver validates them.
 type Default struct {
 	Value interface{}
 }
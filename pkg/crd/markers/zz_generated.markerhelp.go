@@ -25,12 +25,55 @@ import (
 	"sigs.k8s.io/controller-tools/pkg/markers"
 )
 
+func (AtMostOneOf) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD validation",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "allows at most one of the given fields of this type to be set. ",
+			Details: "The fields are named by their JSON names, like `+kubebuilder:validation:AtMostOneOf={foo,bar}`. This marker may be repeated to specify several groups of fields.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{},
+	}
+}
+
+func (ConversionWebhook) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "configures a webhook to convert between the versions of a CRD. ",
+			Details: "It's normally placed on the storage (or \"hub\") version's type, and only needs to be specified once per CRD.  CRDs with several versions whose schemata differ need a conversion webhook, since the API server can otherwise only convert between them by changing their apiVersion.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"ServiceName": {
+				Summary: "is the name of the Service that the webhook is served by.",
+				Details: "",
+			},
+			"ServiceNamespace": {
+				Summary: "is the namespace of the Service that the webhook is served by.",
+				Details: "",
+			},
+			"Path": {
+				Summary: "is the URL path that the webhook is served at. ",
+				Details: "Defaults to \"/convert\".",
+			},
+			"Port": {
+				Summary: "is the port on the Service that the webhook is served on. ",
+				Details: "Defaults to 443.",
+			},
+			"ConversionReviewVersions": {
+				Summary: "are the versions of ConversionReview that the webhook accepts, in order of preference. ",
+				Details: "Defaults to v1.",
+			},
+		},
+	}
+}
+
 func (Default) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD validation",
 		DetailedHelp: markers.DetailedHelp{
 			Summary: "sets the default value for this field. ",
-			Details: "A default value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Defaults should be defined in pruned form, and only best-effort validation will be performed. Full validation of a default requires submission of the containing CRD to an apiserver.",
+			Details: "A default value will be accepted as any value valid for the field. Formatting for common types include: boolean: `true`, string: `Cluster`, numerical: `1.24`, array: `{1,2}`, object: `{policy: \"delete\"}`). Defaults should be defined in pruned form, and are validated against the final schema of the field when generating CRDs, the same way the apiserver validates them.",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
 			"Value": {
@@ -68,6 +111,17 @@ func (Enum) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (ExactlyOneOf) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD validation",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "requires exactly one of the given fields of this type to be set. ",
+			Details: "The fields are named by their JSON names, like `+kubebuilder:validation:ExactlyOneOf={foo,bar}`. This marker may be repeated to specify several groups of fields.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{},
+	}
+}
+
 func (ExclusiveMaximum) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD validation",
@@ -101,6 +155,22 @@ func (Format) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (Immutable) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD validation",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "marks a field (or type) as not being changeable once it's set. ",
+			Details: "It's a shorthand for an XValidation rule of `self == oldSelf`.  Like all transition rules, it's only checked when there's an old value, so an optional field may still be set (or unset) later on.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Message": {
+				Summary: "is the message to report when the value is changed. ",
+				Details: "Defaults to \"Value is immutable\".",
+			},
+		},
+	}
+}
+
 func (ListMapKey) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD processing",
@@ -291,6 +361,30 @@ func (PrintColumn) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (RequiredWhen) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "CRD validation",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "requires a field of this type to be set when another field has a particular value. ",
+			Details: "For example, `+kubebuilder:validation:RequiredWhen:field=url,when=type,equals=Webhook` requires `url` to be set whenever `type` is \"Webhook\".  Fields are named by their JSON names.  This marker may be repeated.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Field": {
+				Summary: "is the field that's required.",
+				Details: "",
+			},
+			"When": {
+				Summary: "is the field whose value is checked.",
+				Details: "",
+			},
+			"Equals": {
+				Summary: "is the value (a string, number, or boolean) that makes Field required.",
+				Details: "",
+			},
+		},
+	}
+}
+
 func (Resource) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "CRD",
@@ -70,6 +70,12 @@ type Parser struct {
 	Checker *loader.TypeChecker
 	// packages marks packages as loaded, to avoid re-loading them.
 	packages map[*loader.Package]struct{}
+	// checkedRules marks types whose validation rules have been compiled,
+	// to avoid reporting errors in them more than once.
+	checkedRules map[TypeIdent]struct{}
+	// checkedDefaults marks types whose default values have been validated,
+	// to avoid reporting errors in them more than once.
+	checkedDefaults map[TypeIdent]struct{}
 
 	flattener *Flattener
 
@@ -92,6 +98,10 @@ type Parser struct {
 
 	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta should be generated
 	GenerateEmbeddedObjectMeta bool
+
+	// EstimateRuleCost specifies if the cost of CEL validation rules should be
+	// estimated and checked against the apiserver's limits when generating CRDs.
+	EstimateRuleCost bool
 }
 
 func (p *Parser) init() {
@@ -121,6 +131,45 @@ func (p *Parser) init() {
 	if p.FlattenedSchemata == nil {
 		p.FlattenedSchemata = make(map[TypeIdent]apiext.JSONSchemaProps)
 	}
+	if p.checkedRules == nil {
+		p.checkedRules = make(map[TypeIdent]struct{})
+	}
+	if p.checkedDefaults == nil {
+		p.checkedDefaults = make(map[TypeIdent]struct{})
+	}
+}
+
+// Invalidate discards everything known about the given packages (e.g.
+// after they've changed on disk and been passed to loader.Invalidate), so
+// that it's recomputed the next time it's needed.  CRDs and flattened schemata
+// may be assembled from types across several packages, so all of those are
+// discarded as well.
+func (p *Parser) Invalidate(pkgs ...*loader.Package) {
+	p.init()
+
+	invalid := make(map[*loader.Package]struct{}, len(pkgs))
+	for _, pkg := range pkgs {
+		invalid[pkg] = struct{}{}
+		delete(p.packages, pkg)
+		delete(p.GroupVersions, pkg)
+	}
+	for ident := range p.Types {
+		if _, isInvalid := invalid[ident.Package]; isInvalid {
+			delete(p.Types, ident)
+		}
+	}
+	for ident := range p.Schemata {
+		if _, isInvalid := invalid[ident.Package]; isInvalid {
+			delete(p.Schemata, ident)
+		}
+	}
+
+	p.FlattenedSchemata = make(map[TypeIdent]apiext.JSONSchemaProps)
+	p.checkedRules = make(map[TypeIdent]struct{})
+	p.checkedDefaults = make(map[TypeIdent]struct{})
+	p.CustomResourceDefinitions = make(map[schema.GroupKind]apiext.CustomResourceDefinition)
+	p.flattener = nil
+	p.init()
 }
 
 // indexTypes loads all types in the package into Types.
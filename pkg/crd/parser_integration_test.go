@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/ginkgo"
@@ -137,6 +139,18 @@ var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func
 			It("should successfully generate the CronJob CRD", func() {
 				assertCRD(pkgs[0], "CronJob", "testdata.kubebuilder.io_cronjobs.yaml")
 			})
+			It("should warn about floats, even though they're allowed", func() {
+				assertCRD(pkgs[0], "CronJob", "testdata.kubebuilder.io_cronjobs.yaml")
+				var floatWarnings []loader.PackageError
+				for _, warning := range pkgs[0].WarningDetails() {
+					if strings.HasPrefix(warning.Msg, "found float") {
+						floatWarnings = append(floatWarnings, warning)
+					}
+				}
+				Expect(floatWarnings).NotTo(BeEmpty())
+				Expect(filepath.Base(floatWarnings[0].Position.Filename)).To(Equal("cronjob_types.go"))
+				Expect(floatWarnings[0].Msg).To(ContainSubstring("allowed by crd:allowDangerousTypes=true"))
+			})
 		})
 
 		Context("Job API", func() {
@@ -207,6 +221,195 @@ var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func
 		Expect(parser.CustomResourceDefinitions[groupKind]).To(Equal(crd), "type not as expected, check pkg/crd/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(parser.CustomResourceDefinitions[groupKind], crd))
 	})
 
+	It("should warn about missing conversion webhooks for versions with different schemata", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/conversion")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(2))
+
+		By("setting up the parser")
+		reg := &markers.Registry{}
+		Expect(crdmarkers.Register(reg)).To(Succeed())
+		parser := &crd.Parser{
+			Collector: &markers.Collector{Registry: reg},
+			Checker:   &loader.TypeChecker{},
+		}
+		crd.AddKnownTypes(parser)
+
+		By("requesting that the packages be parsed")
+		for _, pkg := range pkgs {
+			parser.NeedPackage(pkg)
+		}
+
+		By("requesting the CRD")
+		groupKind := schema.GroupKind{Kind: "Widget", Group: "conversion.example.com"}
+		parser.NeedCRDFor(groupKind, nil)
+		Expect(parser.CustomResourceDefinitions[groupKind].Spec.Versions).To(HaveLen(2))
+
+		By("checking that the storage version's type has a warning, but no errors")
+		var storagePkg *loader.Package
+		for _, pkg := range pkgs {
+			if pkg.Name == "v2" {
+				storagePkg = pkg
+			}
+		}
+		Expect(storagePkg).NotTo(BeNil())
+		Expect(storagePkg.ErrorDetails()).To(BeEmpty())
+		warnings := storagePkg.WarningDetails()
+		Expect(warnings).To(HaveLen(1))
+		Expect(warnings[0].Msg).To(Equal("CRD for Widget.conversion.example.com has versions with different schemata, but no conversion webhook to convert between them (see +kubebuilder:conversion:webhook), so one must be added to it after generation"))
+		Expect(filepath.Base(warnings[0].Position.Filename)).To(Equal("types.go"))
+	})
+
+	It("should report validation rules that don't compile, or that cost too much", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/rules")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots(".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+
+		By("setting up the parser")
+		reg := &markers.Registry{}
+		Expect(crdmarkers.Register(reg)).To(Succeed())
+		parser := &crd.Parser{
+			Collector:        &markers.Collector{Registry: reg},
+			Checker:          &loader.TypeChecker{},
+			EstimateRuleCost: true,
+		}
+		crd.AddKnownTypes(parser)
+
+		By("requesting that the package be parsed")
+		parser.NeedPackage(pkgs[0])
+
+		By("requesting the CRD")
+		groupKind := schema.GroupKind{Kind: "Gadget", Group: "rules.example.com"}
+		parser.NeedCRDFor(groupKind, nil)
+		Expect(parser.CustomResourceDefinitions).To(HaveKey(groupKind))
+
+		By("checking that the broken and expensive rules have errors")
+		errs := pkgs[0].ErrorDetails()
+		Expect(errs).To(HaveLen(5))
+		Expect(errs[0].Msg).To(HavePrefix(`estimated cost of validation rule "self.all(x, self.all(y, x == y || x.name != y.name))" at .spec.parts in Gadget (version rules) is more than 100x over budget`))
+		Expect(errs[0].Position.Line).To(Equal(27))
+		Expect(errs[1].Msg).To(HavePrefix("estimated total cost of the validation rules in Gadget (version rules) is more than 100x over budget"))
+		Expect(errs[1].Position.Line).To(Equal(27))
+		Expect(errs[2].Msg).To(HavePrefix(`invalid validation rule "self.replicas <= self.maxReplicas": compilation failed: ERROR: <input>:1:5: undefined field 'replicas'`))
+		Expect(errs[2].Position.Line).To(Equal(37))
+		Expect(errs[3].Msg).To(Equal(`invalid validation rule "self.size()": cel expression must evaluate to a bool`))
+		Expect(errs[3].Position.Line).To(Equal(42))
+		Expect(errs[4].Msg).To(HavePrefix(`invalid validation rule "self.nmae.size() > 0": compilation failed: ERROR: <input>:1:5: undefined field 'nmae'`))
+		Expect(errs[4].Position.Line).To(Equal(50))
+	})
+
+	It("should report default values that don't match their schema", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/defaults")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots(".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+
+		By("setting up the parser")
+		reg := &markers.Registry{}
+		Expect(crdmarkers.Register(reg)).To(Succeed())
+		parser := &crd.Parser{
+			Collector: &markers.Collector{Registry: reg},
+			Checker:   &loader.TypeChecker{},
+		}
+		crd.AddKnownTypes(parser)
+
+		By("requesting that the package be parsed")
+		parser.NeedPackage(pkgs[0])
+
+		By("requesting the CRD")
+		groupKind := schema.GroupKind{Kind: "Doohickey", Group: "defaults.example.com"}
+		parser.NeedCRDFor(groupKind, nil)
+		Expect(parser.CustomResourceDefinitions).To(HaveKey(groupKind))
+
+		By("checking that the invalid defaults have errors")
+		errs := pkgs[0].ErrorDetails()
+		Expect(errs).To(HaveLen(6))
+		Expect(errs[0].Msg).To(Equal(`invalid default value: default: Unsupported value: "Orange": supported values: "Red", "Green", "Blue"`))
+		Expect(errs[0].Position.Line).To(Equal(36))
+		Expect(errs[1].Msg).To(HaveSuffix(`in body should match '^[a-z]+$'`))
+		Expect(errs[1].Position.Line).To(Equal(40))
+		Expect(errs[2].Msg).To(HaveSuffix("in body should be less than or equal to 10"))
+		Expect(errs[2].Position.Line).To(Equal(44))
+		Expect(errs[3].Msg).To(ContainSubstring("in body must be of type integer"))
+		Expect(errs[3].Position.Line).To(Equal(47))
+		Expect(errs[4].Msg).To(Equal("invalid default value: default.name: Required value"))
+		Expect(errs[4].Position.Line).To(Equal(50))
+		Expect(errs[5].Msg).To(HaveSuffix("must not have unknown fields"))
+		Expect(errs[5].Position.Line).To(Equal(53))
+	})
+
+	It("should report CRDs that the apiserver would reject on the fields that produced them", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/invalid")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots(".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+
+		By("setting up the parser")
+		reg := &markers.Registry{}
+		Expect(crdmarkers.Register(reg)).To(Succeed())
+		parser := &crd.Parser{
+			Collector: &markers.Collector{Registry: reg},
+			Checker:   &loader.TypeChecker{},
+		}
+		crd.AddKnownTypes(parser)
+
+		By("requesting that the package be parsed")
+		parser.NeedPackage(pkgs[0])
+
+		By("requesting the CRD")
+		groupKind := schema.GroupKind{Kind: "Thingamajig", Group: "invalid.example.com"}
+		parser.NeedCRDFor(groupKind, nil)
+		errs := pkgs[0].ErrorDetails()
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Msg).To(HavePrefix("found float, the usage of which is highly discouraged"))
+
+		By("validating the CRD")
+		crdRaw := parser.CustomResourceDefinitions[groupKind]
+		crd.FixTopLevelMetadata(crdRaw)
+		parser.ValidateCRD(groupKind, crdRaw)
+
+		By("checking that the errors are on the offending fields, without repeating the parser's")
+		errs = pkgs[0].ErrorDetails()
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Msg).To(HavePrefix("found float, the usage of which is highly discouraged"))
+		Expect(errs[1].Msg).To(Equal("invalid CRD for Thingamajig.invalid.example.com: spec.versions[0].schema.openAPIV3Schema.properties[spec].properties[parts].x-kubernetes-list-map-keys: Required value: must not be empty if x-kubernetes-list-type is map"))
+		Expect(errs[1].Position.Line).To(Equal(53))
+
+		By("checking that schemaless fields without a type, and recursive types, are warned about instead")
+		warnings := pkgs[0].WarningDetails()
+		Expect(warnings).To(HaveLen(2))
+		Expect(warnings[0].Msg).To(Equal("schemaless field has no type in the schema for Thingamajig.invalid.example.com, which the apiserver requires (Required value: must not be empty for specified object fields), so either specify one with +kubebuilder:validation:Type, or fill it in after generation"))
+		Expect(warnings[0].Position.Line).To(Equal(38))
+		Expect(warnings[1].Msg).To(Equal("recursive type ThingamajigSpec can't be represented in the schema for Thingamajig.invalid.example.com, so it was left empty here, and must be filled in after generation"))
+		Expect(warnings[1].Position.Line).To(Equal(47))
+	})
+
 	It("should skip api internal package", func() {
 		By("switching into testdata to appease go modules")
 		cwd, err := os.Getwd()
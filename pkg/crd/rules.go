@@ -0,0 +1,212 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"fmt"
+	"go/ast"
+	"math"
+	"sort"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+const (
+	// staticEstimatedCostLimit is the largest estimated cost the apiserver
+	// allows for a single validation rule.
+	staticEstimatedCostLimit = 10000000
+	// staticEstimatedCRDCostLimit is the largest estimated cost the apiserver
+	// allows for all the validation rules in a single version of a CRD.
+	staticEstimatedCRDCostLimit = 100000000
+)
+
+// NeedRulesCheckedFor compiles the CEL validation rules (x-kubernetes-validations)
+// declared on the given type, and on every type it references, the same way
+// the apiserver will when the CRD is created.  Rules that don't compile
+// against the schema of the node they're attached to (e.g. because they
+// reference an unknown field, or don't evaluate to a bool) are reported as
+// errors on the type or field that declares them.
+//
+// isResourceRoot indicates that the type is the root of a CRD, whose rules
+// may additionally refer to apiVersion, kind, and metadata.
+func (p *Parser) NeedRulesCheckedFor(typ TypeIdent, isResourceRoot bool) {
+	p.init()
+	p.checkDeclarations(typ, isResourceRoot, p.checkedRules, checkRules)
+}
+
+// checkRules compiles the rules on the given (flattened) schema node, reporting
+// errors for those that fail and that were declared by the given node in the
+// first place (rules that come from elsewhere, like the referenced type of a
+// field, are reported where they're declared).
+func checkRules(pkg *loader.Package, flattened, own apiext.JSONSchemaProps, isResourceRoot bool, node ast.Node) {
+	declared := own.XValidations
+	if len(declared) == 0 {
+		return
+	}
+	results, err := compileRules(&flattened, isResourceRoot)
+	if err != nil {
+		pkg.AddError(loader.ErrFromNode(err, node))
+		return
+	}
+	for i, rule := range flattened.XValidations {
+		if results[i].Error == nil || !containsRule(declared, rule) {
+			continue
+		}
+		pkg.AddError(loader.ErrFromNode(fmt.Errorf("invalid validation rule %q: %s", rule.Rule, results[i].Error.Detail), node))
+	}
+}
+
+// containsRule checks if the given rule is in the given list.
+func containsRule(rules []apiext.ValidationRule, rule apiext.ValidationRule) bool {
+	for _, candidate := range rules {
+		if candidate == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// compileRules compiles the rules on the given schema node against the
+// schema itself, returning one result for each rule.  Schemata that aren't
+// structural can't be compiled, and produce no results.
+func compileRules(schema *apiext.JSONSchemaProps, isResourceRoot bool) ([]cel.CompilationResult, error) {
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiext.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, internal, nil); err != nil {
+		return nil, err
+	}
+	structural, err := structuralschema.NewStructural(internal)
+	if err != nil {
+		// the apiserver won't accept this schema at all, so there's nothing to
+		// compile the rules against
+		return make([]cel.CompilationResult, len(schema.XValidations)), nil
+	}
+	results, err := cel.Compile(structural, isResourceRoot, cel.PerCallLimit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile validation rules: %w", err)
+	}
+	return results, nil
+}
+
+// checkRuleCost estimates the cost of each of the validation rules in the
+// given version of a CRD, like the apiserver does, reporting errors for
+// individual rules that exceed the per-rule limit, and for the CRD version as
+// a whole if the total exceeds the per-CRD limit.
+func checkRuleCost(pkg *loader.Package, kind string, schema *apiext.JSONSchemaProps, node ast.Node) {
+	var total uint64
+	one := uint64(1)
+	visitRuleCosts(schema, "", true, &one, func(path string, rule apiext.ValidationRule, cost uint64) {
+		total = addWithOverflowGuard(total, cost)
+		if cost > staticEstimatedCostLimit {
+			pkg.AddError(loader.ErrFromNode(fmt.Errorf("estimated cost of validation rule %q at %s in %s is %s", rule.Rule, path, kind, costOverBudget(cost, staticEstimatedCostLimit)), node))
+		}
+	})
+	if total > staticEstimatedCRDCostLimit {
+		pkg.AddError(loader.ErrFromNode(fmt.Errorf("estimated total cost of the validation rules in %s is %s", kind, costOverBudget(total, staticEstimatedCRDCostLimit)), node))
+	}
+}
+
+// times multiplies the given cardinality (the maximum number of times a
+// schema node may appear in an object, or nil if unbounded) by the given
+// maximum number of elements, returning nil if either is unbounded.
+func times(card *uint64, maxElems *int64) *uint64 {
+	if card == nil || maxElems == nil {
+		return nil
+	}
+	res := multiplyWithOverflowGuard(*card, uint64(*maxElems))
+	return &res
+}
+
+// visitRuleCosts calls the given function with the estimated cost of each rule
+// in the given schema (and all nodes below it), along with the path to the node
+// that holds it.  Nodes that don't compile are skipped (they're reported
+// separately by NeedRulesCheckedFor).
+func visitRuleCosts(schema *apiext.JSONSchemaProps, path string, isResourceRoot bool, card *uint64, visit func(path string, rule apiext.ValidationRule, cost uint64)) {
+	if schema == nil {
+		return
+	}
+	nodePath := path
+	if nodePath == "" {
+		nodePath = "<root>"
+	}
+	if len(schema.XValidations) > 0 {
+		results, err := compileRules(schema, isResourceRoot)
+		if err == nil {
+			for i, res := range results {
+				if res.Error != nil || res.Program == nil {
+					continue
+				}
+				var cost uint64
+				if card != nil {
+					cost = multiplyWithOverflowGuard(res.MaxCost, *card)
+				} else {
+					cost = multiplyWithOverflowGuard(res.MaxCost, res.MaxCardinality)
+				}
+				visit(nodePath, schema.XValidations[i], cost)
+			}
+		}
+	}
+
+	propNames := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		propNames = append(propNames, name)
+	}
+	sort.Strings(propNames)
+	for _, name := range propNames {
+		prop := schema.Properties[name]
+		visitRuleCosts(&prop, path+"."+name, false, card, visit)
+	}
+	if schema.Items != nil {
+		visitRuleCosts(schema.Items.Schema, path+"[*]", false, times(card, schema.MaxItems), visit)
+	}
+	if schema.AdditionalProperties != nil {
+		visitRuleCosts(schema.AdditionalProperties.Schema, path+"[*]", false, times(card, schema.MaxProperties), visit)
+	}
+}
+
+// multiplyWithOverflowGuard returns base * factor, or math.MaxUint64 if that
+// would overflow.
+func multiplyWithOverflowGuard(base, factor uint64) uint64 {
+	if base == 0 {
+		return 0
+	}
+	if math.MaxUint64/base < factor {
+		return math.MaxUint64
+	}
+	return base * factor
+}
+
+// addWithOverflowGuard returns a + b, or math.MaxUint64 if that would overflow.
+func addWithOverflowGuard(a, b uint64) uint64 {
+	if math.MaxUint64-a < b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// costOverBudget describes how far over the given limit the given cost is.
+func costOverBudget(cost, limit uint64) string {
+	factor := float64(cost) / float64(limit)
+	if factor > 100 {
+		return "more than 100x over budget (try adding maxItems, maxProperties, or maxLength to the arrays, maps, and strings it uses)"
+	}
+	return fmt.Sprintf("%.1fx over budget (try adding maxItems, maxProperties, or maxLength to the arrays, maps, and strings it uses)", factor)
+}
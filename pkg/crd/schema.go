@@ -22,6 +22,7 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"sort"
 	"strings"
 
 	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -125,10 +126,18 @@ func infoToSchema(ctx *schemaContext) *apiext.JSONSchemaProps {
 }
 
 // applyMarkers applies schema markers to the given schema, respecting "apply first" markers.
+// Markers are otherwise applied in order of their names, so that markers which add to
+// the same list (like validation rules) always add to it in the same order.
 func applyMarkers(ctx *schemaContext, markerSet markers.MarkerValues, props *apiext.JSONSchemaProps, node ast.Node) {
+	markerNames := make([]string, 0, len(markerSet))
+	for name := range markerSet {
+		markerNames = append(markerNames, name)
+	}
+	sort.Strings(markerNames)
+
 	// apply "apply first" markers first...
-	for _, markerValues := range markerSet {
-		for _, markerValue := range markerValues {
+	for _, name := range markerNames {
+		for _, markerValue := range markerSet[name] {
 			if _, isApplyFirst := markerValue.(applyFirstMarker); !isApplyFirst {
 				continue
 			}
@@ -145,8 +154,8 @@ func applyMarkers(ctx *schemaContext, markerSet markers.MarkerValues, props *api
 	}
 
 	// ...then the rest of the markers
-	for _, markerValues := range markerSet {
-		for _, markerValue := range markerValues {
+	for _, name := range markerNames {
+		for _, markerValue := range markerSet[name] {
 			if _, isApplyFirst := markerValue.(applyFirstMarker); isApplyFirst {
 				// skip apply-first markers, which were already applied
 				continue
@@ -220,6 +229,9 @@ func localNamedToSchema(ctx *schemaContext, ident *ast.Ident) *apiext.JSONSchema
 		typ, fmt, err := builtinToType(basicInfo, ctx.allowDangerousTypes)
 		if err != nil {
 			ctx.pkg.AddError(loader.ErrFromNode(err, ident))
+		} else if basicInfo.Info()&types.IsFloat != 0 {
+			// allowed, but still worth pointing out
+			ctx.pkg.AddWarning(loader.ErrFromNode(errors.New("found float, the usage of which is highly discouraged, as support for them varies across languages (allowed by crd:allowDangerousTypes=true)"), ident))
 		}
 		return &apiext.JSONSchemaProps{
 			Type:   typ,
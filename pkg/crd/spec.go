@@ -17,6 +17,7 @@ package crd
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -83,10 +84,19 @@ func (p *Parser) NeedCRDFor(groupKind schema.GroupKind, maxDescLen *int) {
 			continue
 		}
 		p.NeedFlattenedSchemaFor(typeIdent)
+		p.NeedRulesCheckedFor(typeIdent, true)
+		p.NeedDefaultsCheckedFor(typeIdent, true)
 		fullSchema := p.FlattenedSchemata[typeIdent]
 		fullSchema = *fullSchema.DeepCopy() // don't mutate the cache (we might be truncating description, etc)
 		if maxDescLen != nil {
-			TruncateDescription(&fullSchema, *maxDescLen)
+			// only warn about descriptions that were cut short -- dropping
+			// them all (with a maxDescLen of 0) is clearly deliberate
+			if truncated := truncateDescriptions(&fullSchema, *maxDescLen); truncated > 0 && *maxDescLen > 0 {
+				pkg.AddWarning(loader.ErrFromNode(fmt.Errorf("truncated %d description(s) in the schema for %s to at most %d characters (see crd:maxDescLen)", truncated, groupKind.Kind, *maxDescLen), typeInfo.RawSpec))
+			}
+		}
+		if p.EstimateRuleCost {
+			checkRuleCost(pkg, fmt.Sprintf("%s (version %s)", groupKind.Kind, p.GroupVersions[pkg].Version), &fullSchema, typeInfo.RawSpec)
 		}
 		ver := apiext.CustomResourceDefinitionVersion{
 			Name:   p.GroupVersions[pkg].Version,
@@ -164,5 +174,54 @@ func (p *Parser) NeedCRDFor(groupKind schema.GroupKind, maxDescLen *int) {
 		packages[0].AddError(fmt.Errorf("CRD for %s with version(s) %v does not serve any version", groupKind, crd.Spec.Versions))
 	}
 
+	if len(crd.Spec.Versions) > 1 && crd.Spec.Conversion == nil && schemataDiffer(crd.Spec.Versions) {
+		// this is only a warning, since the conversion is often patched in
+		// afterwards instead (e.g. with kustomize, as kubebuilder scaffolds)
+		err := fmt.Errorf("CRD for %s has versions with different schemata, but no conversion webhook to convert between them (see +kubebuilder:conversion:webhook), so one must be added to it after generation", groupKind)
+		p.addStorageVersionWarning(groupKind, packages, crd.Spec.Versions, err)
+	}
+
 	p.CustomResourceDefinitions[groupKind] = crd
 }
+
+// schemataDiffer checks if the schemata of the given versions differ in
+// anything but their descriptions.
+func schemataDiffer(versions []apiext.CustomResourceDefinitionVersion) bool {
+	var first *apiext.JSONSchemaProps
+	for i, ver := range versions {
+		var props *apiext.JSONSchemaProps
+		if ver.Schema != nil && ver.Schema.OpenAPIV3Schema != nil {
+			props = ver.Schema.OpenAPIV3Schema.DeepCopy()
+			truncateDescriptions(props, 0)
+		}
+		if i == 0 {
+			first = props
+			continue
+		}
+		if !reflect.DeepEqual(first, props) {
+			return true
+		}
+	}
+	return false
+}
+
+// addStorageVersionWarning adds the given warning to the type for the storage
+// version of the given group-kind, or to the first of the given packages if
+// that can't be found.
+func (p *Parser) addStorageVersionWarning(groupKind schema.GroupKind, packages []*loader.Package, versions []apiext.CustomResourceDefinitionVersion, err error) {
+	for _, ver := range versions {
+		if !ver.Storage {
+			continue
+		}
+		for _, pkg := range packages {
+			if p.GroupVersions[pkg].Version != ver.Name {
+				continue
+			}
+			if typeInfo := p.Types[TypeIdent{Package: pkg, Name: groupKind.Kind}]; typeInfo != nil {
+				pkg.AddWarning(loader.ErrFromNode(err, typeInfo.RawSpec))
+				return
+			}
+		}
+	}
+	packages[0].AddWarning(err)
+}
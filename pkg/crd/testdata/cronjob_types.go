@@ -114,7 +114,7 @@ type CronJobSpec struct {
 	DefaultedSlice []string `json:"defaultedSlice"`
 
 	// This tests that object defaulting can be performed.
-	// +kubebuilder:default={{nested: {foo: "baz", bar: true}},{nested: {bar: false}}}
+	// +kubebuilder:default={{nested: {foo: "baz", bar: true}},{nested: {foo: "qux", bar: false}}}
 	DefaultedObject []RootObject `json:"defaultedObject"`
 
 	// This tests that pattern validator is properly applied.
@@ -218,6 +218,12 @@ type CronJobSpec struct {
 	// +kubebuilder:validation:XValidation:rule="true"
 	StringWithEvenLength string `json:"stringWithEvenLength,omitempty"`
 
+	// Test of the immutability marker, with and without a message.
+	// +kubebuilder:validation:Immutable
+	ImmutableString string `json:"immutableString,omitempty"`
+	// +kubebuilder:validation:Immutable:message="backend can't be changed"
+	ImmutableBackend *Backend `json:"immutableBackend,omitempty"`
+
 	// Checks that fixed-length arrays work
 	Array [3]int `json:"array,omitempty"`
 
@@ -225,6 +231,18 @@ type CronJobSpec struct {
 	ArrayUsingCompositeLiteral [len(struct{ X [3]int }{}.X)]string `json:"arrayUsingCompositeLiteral,omitempty"`
 }
 
+// Backend tests the markers for relationships between fields.
+// +kubebuilder:validation:ExactlyOneOf={service,url}
+// +kubebuilder:validation:AtMostOneOf={caBundle,insecure}
+// +kubebuilder:validation:RequiredWhen:field=caBundle,when=scheme,equals=HTTPS
+type Backend struct {
+	Service  string `json:"service,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Scheme   string `json:"scheme,omitempty"`
+	CABundle []byte `json:"caBundle,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
 type ContainsNestedMap struct {
 	InnerMap map[string]string `json:"innerMap,omitempty"`
 }
@@ -478,6 +496,7 @@ type CronJobStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:singular=mycronjob
 // +kubebuilder:storageversion
+// +kubebuilder:conversion:webhook:serviceName=webhook-service,serviceNamespace=system,port=9443
 
 // CronJob is the Schema for the cronjobs API
 type CronJob struct {
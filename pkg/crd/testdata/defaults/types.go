@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=defaults.example.com
+package defaults
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// Doohickey has defaults, some of which are invalid.
+type Doohickey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DoohickeySpec `json:"spec"`
+}
+
+// DoohickeySpec is the spec of a Doohickey.
+type DoohickeySpec struct {
+	// +kubebuilder:default=Orange
+	Color Color `json:"color,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`^[a-z]+$`
+	// +kubebuilder:default="Not Lowercase"
+	Name string `json:"name,omitempty"`
+
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=11
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// +kubebuilder:default="three"
+	Count int32 `json:"count,omitempty"`
+
+	// +kubebuilder:default={size: 3}
+	Widget Widget `json:"widget,omitempty"`
+
+	// +kubebuilder:default={name: "gear", size: 3, colour: "red"}
+	Gear Widget `json:"gear,omitempty"`
+
+	// +kubebuilder:default={name: "valid", size: 3}
+	Valid Widget `json:"valid,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Red;Green;Blue
+type Color string
+
+// Widget is a part of a Doohickey.
+type Widget struct {
+	Name string `json:"name"`
+	// +kubebuilder:default=1
+	Size int32 `json:"size,omitempty"`
+}
@@ -0,0 +1,42 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package halfkinds has types that look like they're meant to be kinds,
+// but are each missing half of what they need.
+// +groupName=explain.example.com
+package halfkinds
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type Gadget struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Size int `json:"size"`
+}
+
+type Gizmo struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Size int `json:"size"`
+}
+
+type GadgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Gadget `json:"items"`
+}
@@ -0,0 +1,59 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=invalid.example.com
+package invalid
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// Thingamajig generates a CRD that the apiserver would reject.
+type Thingamajig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ThingamajigSpec `json:"spec"`
+}
+
+// ThingamajigSpec is the spec of a Thingamajig.
+type ThingamajigSpec struct {
+	Embedded `json:",inline"`
+
+	// +kubebuilder:validation:Schemaless
+	Blob []byte `json:"blob,omitempty"`
+
+	Valid string `json:"valid,omitempty"`
+
+	// Ratio is a float, which isn't allowed without allowDangerousTypes, and
+	// so has no type either.
+	Ratio float64 `json:"ratio,omitempty"`
+
+	// Children refers back to ThingamajigSpec, which can't be flattened.
+	Children []ThingamajigSpec `json:"children,omitempty"`
+}
+
+// Embedded is inlined into ThingamajigSpec.
+type Embedded struct {
+	// +listType=map
+	Parts []Part `json:"parts,omitempty"`
+}
+
+// Part is a part of a Thingamajig.
+type Part struct {
+	Name string `json:"name"`
+}
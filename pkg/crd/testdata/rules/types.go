@@ -0,0 +1,52 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=rules.example.com
+package rules
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// Gadget has validation rules, some of which don't compile.
+// +kubebuilder:validation:XValidation:rule="self.metadata.name.startsWith('gadget-')",message="name must start with gadget-"
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetSpec `json:"spec"`
+}
+
+// GadgetSpec is the spec of a Gadget.
+// +kubebuilder:validation:XValidation:rule="self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"
+// +kubebuilder:validation:XValidation:rule="self.replicas <= self.maxReplicas",message="replicas refers to a field that doesn't exist"
+type GadgetSpec struct {
+	MinReplicas int32 `json:"minReplicas"`
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// +kubebuilder:validation:XValidation:rule="self.size()",message="doesn't evaluate to a bool"
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:XValidation:rule="self.all(x, self.all(y, x == y || x.name != y.name))",message="names must be unique"
+	Parts []Part `json:"parts"`
+}
+
+// Part is a part of a Gadget.
+// +kubebuilder:validation:XValidation:rule="self.nmae.size() > 0",message="refers to a misspelled field"
+type Part struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextvalidation "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+var (
+	// versionSchemaPath matches the path to the schema of a particular version
+	// of a CRD, or to the schema shared by all versions.
+	versionSchemaPath = regexp.MustCompile(`^spec\.(?:versions\[(\d+)\]\.schema|validation)\.openAPIV3Schema`)
+	// schemaPathStep matches a single step into a child node of a schema.
+	schemaPathStep = regexp.MustCompile(`^\.(?:properties\[([^\]]+)\]|items|additionalProperties)`)
+)
+
+// ValidateCRD validates the given CustomResourceDefinition for the given
+// group-kind the same way the apiserver does when it's created, reporting
+// any problems (like non-structural schemata, or invalid list types) as
+// errors.  Problems in a version's schema are reported on the Go field (or
+// type) that the offending part of the schema was generated from.
+//
+// Some problems are reported as warnings instead, since the schema can
+// still be filled in after generation: fields whose type refers back to a
+// type containing them can't be flattened, and are left without a schema,
+// while schemaless fields are left without a type unless one is specified
+// with a marker.  Fields that already have errors (e.g. for using a type
+// that isn't allowed), and problems with validation rules and default
+// values (which NeedCRDFor reports in more detail), aren't reported again.
+//
+// The CRD should be in the form it'll be written in (e.g. after
+// FixTopLevelMetadata has been applied).
+func (p *Parser) ValidateCRD(groupKind schema.GroupKind, crd apiext.CustomResourceDefinition) {
+	p.init()
+
+	var packages []*loader.Package
+	for pkg, gv := range p.GroupVersions {
+		if gv.Group == groupKind.Group {
+			packages = append(packages, pkg)
+		}
+	}
+	if len(packages) == 0 {
+		return
+	}
+	// problems that can't be located are reported on the same package every time
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ID < packages[j].ID })
+
+	// the apiserver defaults and fills in the status of new CRDs before validating them
+	crd = *crd.DeepCopy()
+	apiext.SetObjectDefaults_CustomResourceDefinition(&crd)
+	for _, ver := range crd.Spec.Versions {
+		if ver.Storage {
+			crd.Status.StoredVersions = append(crd.Status.StoredVersions, ver.Name)
+		}
+	}
+	internal := &apiextensions.CustomResourceDefinition{}
+	if err := apiext.Convert_v1_CustomResourceDefinition_To_apiextensions_CustomResourceDefinition(&crd, internal, nil); err != nil {
+		packages[0].AddError(fmt.Errorf("unable to validate CRD for %s: %w", groupKind, err))
+		return
+	}
+
+	for _, validationErr := range apiextvalidation.ValidateCustomResourceDefinition(context.Background(), internal) {
+		if reportedElsewhere(validationErr) {
+			continue
+		}
+		verIndex, schemaPath := versionForPath(validationErr.Field)
+		if verIndex >= len(crd.Spec.Versions) {
+			packages[0].AddError(fmt.Errorf("invalid CRD for %s: %v", groupKind, validationErr))
+			continue
+		}
+		if schemaPath != "" {
+			// point at where the problem is in the CRD we're going to write,
+			// even if the apiserver saw a schema shared by all versions
+			validationErr.Field = field.NewPath("spec", "versions").Index(verIndex).Child("schema", "openAPIV3Schema").String() + schemaPath
+		}
+		err := fmt.Errorf("invalid CRD for %s: %v", groupKind, validationErr)
+
+		pkg, node, recursive := p.nodeForPath(groupKind, crd.Spec.Versions[verIndex].Name, schemaPath)
+		switch {
+		case pkg == nil:
+			packages[0].AddError(err)
+		case recursive != nil:
+			pkg.AddWarning(loader.ErrFromNode(fmt.Errorf("recursive type %s can't be represented in the schema for %s, so it was left empty here, and must be filled in after generation", recursive.Obj().Name(), groupKind), node))
+		case p.isSchemaless(pkg, node):
+			pkg.AddWarning(loader.ErrFromNode(fmt.Errorf("schemaless field has no type in the schema for %s, which the apiserver requires (%v), so either specify one with +kubebuilder:validation:Type, or fill it in after generation", groupKind, validationErr.ErrorBody()), node))
+		case hasErrorsWithin(pkg, node):
+			// the parser already reported what's wrong with this field
+		default:
+			pkg.AddError(loader.ErrFromNode(err, node))
+		}
+	}
+}
+
+// reportedElsewhere checks if the given error is about a validation rule or
+// default value, which are checked (and reported) as part of NeedCRDFor.
+func reportedElsewhere(err *field.Error) bool {
+	return strings.Contains(err.Field, ".x-kubernetes-validations") ||
+		strings.HasSuffix(err.Field, ".default") || strings.Contains(err.Field, ".default.") ||
+		strings.Contains(err.Detail, "x-kubernetes-validations estimated rule cost")
+}
+
+// versionForPath returns the index of the version whose schema the given
+// field path (in the internal form of a CRD) is within, along with the rest
+// of the path within that schema.  The apiserver sees a schema shared by all
+// versions as the first version's.  Paths outside of any schema are
+// considered to be about the first version, with an empty schema path.
+func versionForPath(path string) (int, string) {
+	loc := versionSchemaPath.FindStringSubmatchIndex(path)
+	if loc == nil {
+		return 0, ""
+	}
+	verIndex := 0
+	if loc[2] >= 0 {
+		verIndex, _ = strconv.Atoi(path[loc[2]:loc[3]])
+	}
+	return verIndex, path[loc[1]:]
+}
+
+// isSchemaless checks if the given node is a field with the schemaless
+// marker.
+func (p *Parser) isSchemaless(pkg *loader.Package, node ast.Node) bool {
+	if _, isField := node.(*ast.Field); !isField {
+		return false
+	}
+	nodeMarkers, err := p.Collector.MarkersInPackage(pkg)
+	if err != nil {
+		return false
+	}
+	return nodeMarkers[node].Get(crdmarkers.SchemalessName) != nil
+}
+
+// hasErrorsWithin checks if any errors have already been recorded against
+// the given package within the given node, if it's a field.
+func hasErrorsWithin(pkg *loader.Package, node ast.Node) bool {
+	fieldNode, isField := node.(*ast.Field)
+	if !isField {
+		return false
+	}
+	start, end := pkg.FileSet().Position(fieldNode.Pos()), pkg.FileSet().Position(fieldNode.End())
+	for _, err := range pkg.ErrorDetails() {
+		pos := err.Position
+		if pos.Filename != start.Filename {
+			continue
+		}
+		afterStart := pos.Line > start.Line || (pos.Line == start.Line && pos.Column >= start.Column)
+		beforeEnd := pos.Line < end.Line || (pos.Line == end.Line && pos.Column <= end.Column)
+		if afterStart && beforeEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeForPath finds the Go field or type that produced the part of the
+// schema for the given version of the given kind at the given path (relative
+// to the root of the schema), returning the package it's in along with it.
+// Paths resolve to the deepest field that can be found.  If the path leads
+// back into one of the types containing it, that type is returned as well.
+// If no type can be found at all, the returned package is nil.
+func (p *Parser) nodeForPath(groupKind schema.GroupKind, version, path string) (*loader.Package, ast.Node, *types.Named) {
+	var kindIdent TypeIdent
+	for pkg, gv := range p.GroupVersions {
+		if gv.Group == groupKind.Group && gv.Version == version {
+			kindIdent = TypeIdent{Package: pkg, Name: groupKind.Kind}
+		}
+	}
+	kindInfo := p.Types[kindIdent]
+	if kindInfo == nil {
+		return nil, nil, nil
+	}
+
+	pkg, node := kindIdent.Package, ast.Node(kindInfo.RawSpec)
+	typ := kindIdent.Package.Types.Scope().Lookup(kindIdent.Name).Type()
+	containing := make(map[*types.TypeName]struct{})
+	rest := path
+	for {
+		if named := namedType(typ); named != nil {
+			if _, isRecursive := containing[named.Obj()]; isRecursive {
+				return pkg, node, named
+			}
+			containing[named.Obj()] = struct{}{}
+		}
+
+		step := schemaPathStep.FindStringSubmatch(rest)
+		if step == nil {
+			break
+		}
+		rest = rest[len(step[0]):]
+
+		switch {
+		case step[1] != "":
+			fieldPkg, fieldNode, fieldType := p.findJSONField(pkg, typ, step[1])
+			if fieldNode == nil {
+				return pkg, node, nil
+			}
+			pkg, node, typ = fieldPkg, fieldNode, fieldType
+		case step[0] == ".items":
+			typ = elemType(typ, false)
+		default:
+			typ = elemType(typ, true)
+		}
+		if typ == nil {
+			break
+		}
+	}
+	return pkg, node, nil
+}
+
+// namedType returns the named type that the given type is (or points to),
+// or nil if it's not one.
+func namedType(typ types.Type) *types.Named {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	named, _ := typ.(*types.Named)
+	return named
+}
+
+// findJSONField finds the field with the given JSON name in the given type
+// (which must be a named struct type from the given package, or a pointer to
+// one), looking through embedded fields as well.  It returns the field and
+// its type, along with the package it was found in.
+func (p *Parser) findJSONField(pkg *loader.Package, typ types.Type, jsonName string) (*loader.Package, *ast.Field, types.Type) {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	named, isNamed := typ.(*types.Named)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return nil, nil, nil
+	}
+	if named.Obj().Pkg().Path() != pkg.PkgPath {
+		pkg = pkg.Imports()[named.Obj().Pkg().Path()]
+		if pkg == nil {
+			return nil, nil, nil
+		}
+	}
+	info := p.Types[TypeIdent{Package: pkg, Name: named.Obj().Name()}]
+	if info == nil {
+		return nil, nil, nil
+	}
+
+	for _, field := range info.Fields {
+		fieldName, inline := jsonFieldName(field.Tag.Get("json"))
+		fieldType := pkg.TypesInfo.TypeOf(field.RawField.Type)
+		if fieldType == nil {
+			continue
+		}
+		if inline {
+			if embeddedPkg, embeddedField, embeddedType := p.findJSONField(pkg, fieldType, jsonName); embeddedField != nil {
+				return embeddedPkg, embeddedField, embeddedType
+			}
+			continue
+		}
+		if fieldName == jsonName {
+			return pkg, field.RawField, fieldType
+		}
+	}
+	return nil, nil, nil
+}
+
+// elemType returns the element type of the given slice or array type (or
+// map type, if isMap is set), or nil if it's not one.
+func elemType(typ types.Type, isMap bool) types.Type {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	switch under := typ.Underlying().(type) {
+	case *types.Slice:
+		if !isMap {
+			return under.Elem()
+		}
+	case *types.Array:
+		if !isMap {
+			return under.Elem()
+		}
+	case *types.Map:
+		if isMap {
+			return under.Elem()
+		}
+	}
+	return nil
+}
@@ -26,21 +26,13 @@ import (
 )
 
 func (Generator) Help() *markers.DefinitionHelp {
-	return &markers.DefinitionHelp{
+	help := &markers.DefinitionHelp{
 		Category: "",
 		DetailedHelp: markers.DetailedHelp{
 			Summary: "generates CustomResourceDefinition objects.",
 			Details: "",
 		},
 		FieldHelp: map[string]markers.DetailedHelp{
-			"IgnoreUnexportedFields": {
-				Summary: "indicates that we should skip unexported fields. ",
-				Details: "Left unspecified, the default is false.",
-			},
-			"AllowDangerousTypes": {
-				Summary: "allows types which are usually omitted from CRD generation because they are not recommended. ",
-				Details: "Currently the following additional types are allowed when this is true: float32 float64 \n Left unspecified, the default is false",
-			},
 			"MaxDescLen": {
 				Summary: "specifies the maximum description length for fields in CRD's OpenAPI schema. ",
 				Details: "0 indicates drop the description for all fields completely. n indicates limit the description to at most n characters and truncate the description to closest sentence boundary if it exceeds n characters.",
@@ -49,10 +41,52 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "specifies the target API versions of the CRD type itself to generate. Defaults to v1. ",
 				Details: "Currently, the only supported value is v1. \n The first version listed will be assumed to be the \"default\" version and will not get a version suffix in the output filename. \n You'll need to use \"v1\" to get support for features like defaulting, along with an API server that supports it (Kubernetes 1.16+).",
 			},
+			"Format": {
+				Summary: "is the format to write manifests in: yaml (the default), json, jsonl (compact JSON, one object per line), or list (a JSON v1 List holding all the objects in a file).",
+				Details: "",
+			},
+			"HeaderFile": {
+				Summary: "specifies the header text (e.g. license) to prepend to generated files, as a YAML comment.",
+				Details: "",
+			},
+			"Year": {
+				Summary: "specifies the year to substitute for \" YEAR\" in the header file.",
+				Details: "",
+			},
+		},
+	}
+
+	for field, fieldHelp := range (ParserOptions{}).Help().FieldHelp {
+		help.FieldHelp[field] = fieldHelp
+	}
+
+	return help
+}
+
+func (ParserOptions) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "are the options that affect the schemata produced by a Parser. ",
+			Details: "Generators that produce schemata embed them, so that they accept the same options as the crd generator.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"IgnoreUnexportedFields": {
+				Summary: "indicates that we should skip unexported fields. ",
+				Details: "Left unspecified, the default is false.",
+			},
+			"AllowDangerousTypes": {
+				Summary: "allows types which are usually omitted from CRD generation because they are not recommended. ",
+				Details: "Currently the following additional types are allowed when this is true: float32 float64 \n Left unspecified, the default is false",
+			},
 			"GenerateEmbeddedObjectMeta": {
 				Summary: "specifies if any embedded ObjectMeta in the CRD should be generated",
 				Details: "",
 			},
+			"EstimateRuleCost": {
+				Summary: "estimates the cost of the CEL validation rules (+kubebuilder:validation:XValidation) in each CRD the same way the apiserver does, and fails generation if any rule, or all the rules in a version together, exceed the apiserver's limits. ",
+				Details: "Left unspecified, the default is false.",
+			},
 		},
 	}
 }
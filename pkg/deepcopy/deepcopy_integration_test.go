@@ -107,3 +107,37 @@ var _ = Describe("CRD Generation From Parsing to CustomResourceDefinition", func
 		Expect(hadErrs).To(BeFalse())
 	})
 })
+
+var _ = Describe("Explaining DeepCopy generation", func() {
+	It("should say why nothing (or less than expected) was generated for each package", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata/explain")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("initializing the runtime")
+		var gen genall.Generator = deepcopy.Generator{}
+		rt, err := genall.Generators{&gen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		rt.OutputRules = genall.OutputRules{Default: make(outputToMap)}
+		rt.GeneratorNames = map[*genall.Generator]string{&gen: "object"}
+		rt.Explain = true
+
+		By("running the generator")
+		res, err := rt.Generate()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the explanations")
+		Expect(res.Diagnostics).To(HaveLen(2))
+		for _, diag := range res.Diagnostics {
+			Expect(diag.Severity).To(Equal(genall.SeverityNote))
+			Expect(diag.Generator).To(Equal("object"))
+		}
+		Expect(res.Diagnostics[0].Package).To(HaveSuffix("/explain/disabled"))
+		Expect(res.Diagnostics[0].Message).To(Equal("no DeepCopy methods generated: the package is marked +kubebuilder:object:generate=false, and none of its types are marked +kubebuilder:object:generate=true or +kubebuilder:object:root=true"))
+		Expect(res.Diagnostics[1].Package).To(HaveSuffix("/explain/partial"))
+		Expect(res.Diagnostics[1].Line).To(BeNumerically(">", 0))
+		Expect(res.Diagnostics[1].Message).To(Equal("Widget won't implement runtime.Object: it embeds metav1.TypeMeta, but isn't marked +kubebuilder:object:root=true"))
+	})
+})
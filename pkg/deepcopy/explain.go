@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deepcopy
+
+import (
+	"fmt"
+	"go/types"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// Explain says why nothing is generated for any of the roots that don't
+// have deepcopy generation enabled for any of their types, as well as which
+// types look like they're meant to implement runtime.Object, but won't.
+func (d Generator) Explain(ctx *genall.GenerationContext) []genall.Explanation {
+	var explanations []genall.Explanation
+	for _, root := range ctx.Roots {
+		if reasons := explainPackage(ctx, root); len(reasons) > 0 {
+			explanations = append(explanations, genall.Explanation{Package: root, Reasons: reasons})
+		}
+	}
+	return explanations
+}
+
+// explainPackage returns the reasons that nothing (or less than might be
+// expected) is generated for the given package.
+func explainPackage(ctx *genall.GenerationContext, root *loader.Package) []error {
+	allTypes, err := enabledOnPackage(ctx.Collector, root)
+	if err != nil {
+		// already reported when generating
+		return nil
+	}
+	pkgMarkers, err := markers.PackageMarkers(ctx.Collector, root)
+	if err != nil {
+		return nil
+	}
+	disabled := pkgMarkers.Get(enablePkgMarker.Name) == false
+
+	ctx.Checker.Check(root)
+	root.NeedTypesInfo()
+
+	var reasons []error
+	anyEnabled := false
+	if err := markers.EachType(ctx.Collector, root, func(info *markers.TypeInfo) {
+		if !enabledOnType(allTypes, info) {
+			return
+		}
+		anyEnabled = true
+		if embedsTypeMeta(root, info) && !genObjectInterface(info) {
+			reasons = append(reasons, loader.ErrFromNode(fmt.Errorf("%s won't implement runtime.Object: it embeds metav1.TypeMeta, but isn't marked +%s=true", info.Name, isObjectMarker.Name), info.RawSpec))
+		}
+	}); err != nil {
+		return nil
+	}
+	if anyEnabled {
+		return reasons
+	}
+
+	switch {
+	case disabled:
+		return []error{fmt.Errorf("no DeepCopy methods generated: the package is marked +%[1]s=false, and none of its types are marked +%[1]s=true or +%[2]s=true", enablePkgMarker.Name, isObjectMarker.Name)}
+	case !allTypes:
+		return []error{fmt.Errorf("no DeepCopy methods generated: the package isn't marked +%[1]s=true, and none of its types are marked +%[1]s=true or +%[2]s=true", enablePkgMarker.Name, isObjectMarker.Name)}
+	default:
+		return []error{fmt.Errorf("no DeepCopy methods generated: the package has no types, or they're all marked +%s=false", enableTypeMarker.Name)}
+	}
+}
+
+// embedsTypeMeta checks if the given type embeds metav1.TypeMeta, and thus
+// is probably meant to be a runtime.Object.
+func embedsTypeMeta(pkg *loader.Package, info *markers.TypeInfo) bool {
+	for _, field := range info.Fields {
+		if field.Name != "" {
+			continue
+		}
+		named, isNamed := pkg.TypesInfo.TypeOf(field.RawField.Type).(*types.Named)
+		if !isNamed || named.Obj().Pkg() == nil {
+			continue
+		}
+		if named.Obj().Name() == "TypeMeta" && loader.NonVendorPath(named.Obj().Pkg().Path()) == "k8s.io/apimachinery/pkg/apis/meta/v1" {
+			return true
+		}
+	}
+	return false
+}
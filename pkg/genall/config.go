@@ -0,0 +1,480 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// ConfigError is a problem with a particular part of a configuration file.
+type ConfigError struct {
+	// Pos is the position in the file that the problem was found at.
+	Pos token.Position
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+// ConfigErrors lists all the problems found in a configuration file.
+type ConfigErrors []ConfigError
+
+func (l ConfigErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// OptionsFromConfigFile reads the configuration file at the given path, and
+// converts it to the equivalent set of options.  See OptionsFromConfig for
+// details.
+func OptionsFromConfigFile(optionsRegistry *markers.Registry, path string) ([]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OptionsFromConfig(optionsRegistry, path, contents)
+}
+
+// OptionsFromConfig converts the given YAML configuration file contents into
+// the equivalent set of options, as would be passed to FromOptions.  The
+// configuration looks like
+//
+//	paths:
+//	- ./apis/...
+//	plugins:
+//	  docs:
+//	    path: ./bin/docs-gen
+//	generators:
+//	  crd:
+//	    crdVersions: [v1]
+//	    maxDescLen: 0
+//	  rbac:
+//	    roleName: manager-role
+//	  object: {}
+//	  docs:
+//	    format: markdown
+//	output:
+//	  crd:
+//	    dir: config/crd/bases
+//	  rbac:
+//	    artifacts:
+//	      config: config/rbac
+//	  default: stdout
+//
+// Each key under generators is the name of a generator option, and the keys
+// beneath it are the option's arguments.  Each key under output is the name
+// of a generator (or "default" for the default rule), and its value is either
+// the name of an output rule, or a map from the name of a rule to its
+// arguments.  Each key under plugins declares a plugin (see Plugin) with that
+// name, whose generator can then be used under generators and output.
+// Relative paths are interpreted relative to the working
+// directory, exactly as they would be on the command line.
+//
+// The file is checked against the option definitions in the given registry,
+// and all problems found are returned together as ConfigErrors, with the
+// positions in the file (named by filename) that they occurred at.
+func OptionsFromConfig(optionsRegistry *markers.Registry, filename string, contents []byte) ([]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	cfg := &configParser{reg: optionsRegistry, filename: filename}
+	if len(doc.Content) > 0 {
+		cfg.parseRoot(doc.Content[0])
+	}
+	if len(cfg.errs) > 0 {
+		return nil, cfg.errs
+	}
+	return cfg.options, nil
+}
+
+// MergeOptions combines a base set of options (e.g. those loaded from a
+// configuration file) with a set that takes precedence (e.g. those passed on
+// the command line).  An overriding option replaces all base options for the
+// same generator, the same generator's output (or the default output), or the
+// input paths -- options are never combined argument-by-argument.
+func MergeOptions(optionsRegistry *markers.Registry, base, overrides []string) []string {
+	overridden := make(map[string]struct{}, len(overrides))
+	for _, opt := range overrides {
+		overridden[optionKey(optionsRegistry, opt)] = struct{}{}
+	}
+
+	res := make([]string, 0, len(base)+len(overrides))
+	for _, opt := range base {
+		if _, isOverridden := overridden[optionKey(optionsRegistry, opt)]; isOverridden {
+			continue
+		}
+		res = append(res, opt)
+	}
+	return append(res, overrides...)
+}
+
+// optionKey returns a key that identifies the thing that the given option
+// configures, such that two options with the same key override each other.
+func optionKey(optionsRegistry *markers.Registry, rawOpt string) string {
+	if len(rawOpt) == 0 || rawOpt[0] != '+' {
+		rawOpt = "+" + rawOpt
+	}
+	defn := optionsRegistry.Lookup(rawOpt, markers.DescribesPackage)
+	if defn == nil {
+		// we'll complain about this later
+		return rawOpt
+	}
+	if defn.Name == PluginMarker.Name {
+		// plugins only conflict with other declarations of the same plugin
+		if val, err := defn.Parse(rawOpt); err == nil {
+			return PluginMarker.Name + ":" + val.(Plugin).Name
+		}
+		return rawOpt
+	}
+	if strings.HasPrefix(defn.Name, "output:") {
+		// all rules for the same generator conflict with each other
+		_, genName := splitOutputRuleOption(defn.Name)
+		return "output:" + genName
+	}
+	return defn.Name
+}
+
+// configParser converts a parsed configuration file into options,
+// accumulating errors as it goes.
+type configParser struct {
+	reg      *markers.Registry
+	filename string
+
+	options []string
+	errs    ConfigErrors
+}
+
+// errorf records an error at the position of the given node.
+func (c *configParser) errorf(node *yaml.Node, format string, args ...interface{}) {
+	c.errs = append(c.errs, ConfigError{
+		Pos: token.Position{Filename: c.filename, Line: node.Line, Column: node.Column},
+		Msg: fmt.Sprintf(format, args...),
+	})
+}
+
+// mappingPairs returns the key-value pairs of the given mapping node, or
+// records an error if it's not a mapping.  Null nodes are treated as empty
+// mappings.
+func (c *configParser) mappingPairs(node *yaml.Node, what string) [][2]*yaml.Node {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		c.errorf(node, "%s must be a map", what)
+		return nil
+	}
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	return pairs
+}
+
+func (c *configParser) parseRoot(root *yaml.Node) {
+	pairs := c.mappingPairs(root, "configuration")
+
+	// plugins need to be registered before we can check their options
+	for _, pair := range pairs {
+		if pair[0].Value != "plugins" {
+			continue
+		}
+		for _, plugin := range c.mappingPairs(pair[1], "plugins") {
+			c.parsePlugin(plugin[0], plugin[1])
+		}
+	}
+
+	for _, pair := range pairs {
+		key, val := pair[0], pair[1]
+		switch key.Value {
+		case "plugins":
+			// already handled
+		case "paths":
+			c.parsePaths(val)
+		case "generators":
+			for _, gen := range c.mappingPairs(val, "generators") {
+				c.parseOption(gen[0], gen[0].Value, "generator", gen[1])
+			}
+		case "output":
+			for _, rule := range c.mappingPairs(val, "output") {
+				c.parseOutput(rule[0], rule[1])
+			}
+		default:
+			c.errorf(key, "unknown field %q (expected one of paths, plugins, generators, or output)", key.Value)
+		}
+	}
+}
+
+func (c *configParser) parsePaths(node *yaml.Node) {
+	defn := c.reg.Lookup("+paths", markers.DescribesPackage)
+	if defn == nil {
+		c.errorf(node, "input paths are not supported")
+		return
+	}
+	if node.Kind == yaml.ScalarNode {
+		// allow a single path for convenience
+		node = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{node}, Line: node.Line, Column: node.Column}
+	}
+	errsBefore := len(c.errs)
+	renderedPaths := c.renderArg(node, defn.Fields[""])
+	if len(c.errs) > errsBefore {
+		return
+	}
+	c.addOption(node, defn, "paths", renderedPaths)
+}
+
+// parsePlugin parses the declaration of the plugin named in key, registering
+// it so that its generator's options can be checked.
+func (c *configParser) parsePlugin(key, val *yaml.Node) {
+	defn := c.reg.Lookup("+"+PluginMarker.Name, markers.DescribesPackage)
+	if defn == nil {
+		c.errorf(key, "plugins are not supported")
+		return
+	}
+
+	errsBefore := len(c.errs)
+	renderedArgs := []string{"name=" + strconv.Quote(key.Value)}
+	for _, pair := range c.mappingPairs(val, fmt.Sprintf("plugin %q", key.Value)) {
+		argName := pair[0].Value
+		if argName == "name" {
+			c.errorf(pair[0], "unknown argument %q to plugin %q (the name is given by the key)", argName, key.Value)
+			continue
+		}
+		argType, known := defn.Fields[argName]
+		if !known {
+			c.errorf(pair[0], "unknown argument %q to plugin %q", argName, key.Value)
+			continue
+		}
+		renderedArgs = append(renderedArgs, argName+"="+c.renderArg(pair[1], argType))
+	}
+	if len(c.errs) > errsBefore {
+		return
+	}
+
+	c.addOption(key, defn, defn.Name, strings.Join(renderedArgs, ","))
+	if len(c.errs) > errsBefore {
+		return
+	}
+	if err := RegisterPlugins(c.reg, c.options[len(c.options)-1:]); err != nil {
+		c.errorf(key, "%v", err)
+	}
+}
+
+// parseOutput parses the output rule for the generator named in key.
+func (c *configParser) parseOutput(key, val *yaml.Node) {
+	prefix := "output:" + key.Value + ":"
+	what := fmt.Sprintf("output rule for generator %q", key.Value)
+	if key.Value == "default" {
+		prefix = "output:"
+		what = "default output rule"
+	}
+
+	if val.Kind == yaml.ScalarNode {
+		// just the rule name, with no arguments
+		c.parseOption(val, prefix+val.Value, "output rule", nil)
+		return
+	}
+	pairs := c.mappingPairs(val, what)
+	if len(pairs) != 1 {
+		c.errorf(val, "%s must specify exactly one rule", what)
+		return
+	}
+	c.parseOption(pairs[0][0], prefix+pairs[0][0].Value, "output rule", pairs[0][1])
+}
+
+// parseOption parses an option with the given name, with arguments taken
+// from the given node (which may be nil or null for no arguments).  Errors
+// about the option itself are reported at the position of nameNode.
+func (c *configParser) parseOption(nameNode *yaml.Node, name string, kind string, args *yaml.Node) {
+	defn := c.reg.Lookup("+"+name, markers.DescribesPackage)
+	if defn == nil || defn.Name != name {
+		c.errorf(nameNode, "unknown %s %q", kind, nameNode.Value)
+		return
+	}
+
+	errsBefore := len(c.errs)
+	var renderedArgs string
+	if defn.AnonymousField() {
+		if args != nil && args.Tag != "!!null" {
+			renderedArgs = c.renderArg(args, defn.Fields[""])
+		} else if !defn.Fields[""].Optional {
+			c.errorf(nameNode, "%s %q requires a value", kind, name)
+		}
+	} else {
+		renderedArgs = c.renderFields(nameNode, defn, kind, args)
+	}
+
+	if len(c.errs) > errsBefore {
+		// don't pile on with parse errors for a known-bad option
+		return
+	}
+	c.addOption(nameNode, defn, name, renderedArgs)
+}
+
+// renderFields renders the arguments for a struct-based option from the
+// given map node, checking for unknown and missing arguments.
+func (c *configParser) renderFields(nameNode *yaml.Node, defn *markers.Definition, kind string, args *yaml.Node) string {
+	seen := make(map[string]struct{})
+	var renderedArgs []string
+	if args != nil {
+		for _, pair := range c.mappingPairs(args, fmt.Sprintf("arguments to %s %q", kind, defn.Name)) {
+			argName := pair[0].Value
+			argType, known := defn.Fields[argName]
+			if !known {
+				c.errorf(pair[0], "unknown argument %q to %s %q", argName, kind, defn.Name)
+				continue
+			}
+			seen[argName] = struct{}{}
+			renderedArgs = append(renderedArgs, argName+"="+c.renderArg(pair[1], argType))
+		}
+	}
+
+	if defn.Strict {
+		var missing []string
+		for argName, arg := range defn.Fields {
+			if _, wasSeen := seen[argName]; !wasSeen && !arg.Optional {
+				missing = append(missing, argName)
+			}
+		}
+		sort.Strings(missing)
+		for _, argName := range missing {
+			c.errorf(nameNode, "missing argument %q to %s %q", argName, kind, defn.Name)
+		}
+	}
+
+	return strings.Join(renderedArgs, ",")
+}
+
+// addOption records the given option, double-checking that it actually
+// parses, so that any remaining problems are reported against the file.
+func (c *configParser) addOption(node *yaml.Node, defn *markers.Definition, name, args string) {
+	opt := name
+	switch {
+	case args == "":
+	case defn.AnonymousField():
+		opt += "=" + args
+	default:
+		// like `name:arg=val,arg=val`
+		opt += ":" + args
+	}
+	if _, err := defn.Parse("+" + opt); err != nil {
+		c.errorf(node, "invalid option %q: %v", opt, err)
+		return
+	}
+	c.options = append(c.options, opt)
+}
+
+// renderArg renders the given node as a marker argument of the given type,
+// recording errors for values of the wrong type.
+func (c *configParser) renderArg(node *yaml.Node, arg markers.Argument) string {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch arg.Type {
+	case markers.StringType:
+		if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+			c.errorf(node, "expected a string")
+			return `""`
+		}
+		return strconv.Quote(node.Value)
+	case markers.IntType:
+		var val int
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" || node.Decode(&val) != nil {
+			c.errorf(node, "expected an integer")
+			return "0"
+		}
+		return strconv.Itoa(val)
+	case markers.NumberType:
+		var val float64
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") || node.Decode(&val) != nil {
+			c.errorf(node, "expected a number")
+			return "0"
+		}
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case markers.BoolType:
+		var val bool
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" || node.Decode(&val) != nil {
+			c.errorf(node, "expected a boolean")
+			return "false"
+		}
+		return strconv.FormatBool(val)
+	case markers.SliceType:
+		if node.Kind != yaml.SequenceNode {
+			c.errorf(node, "expected a list")
+			return "{}"
+		}
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			items[i] = c.renderArg(item, *arg.ItemType)
+		}
+		return "{" + strings.Join(items, ",") + "}"
+	case markers.MapType:
+		if node.Kind != yaml.MappingNode {
+			c.errorf(node, "expected a map")
+			return "{}"
+		}
+		var items []string
+		for _, pair := range c.mappingPairs(node, "value") {
+			items = append(items, strconv.Quote(pair[0].Value)+":"+c.renderArg(pair[1], *arg.ItemType))
+		}
+		return "{" + strings.Join(items, ",") + "}"
+	case markers.AnyType:
+		return c.renderAny(node)
+	default:
+		c.errorf(node, "arguments of type %s cannot be specified in a configuration file", arg.TypeString())
+		return ""
+	}
+}
+
+// renderAny renders an argument of unknown type based on the type of the
+// YAML value itself.
+func (c *configParser) renderAny(node *yaml.Node) string {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return c.renderArg(node, markers.Argument{Type: markers.SliceType, ItemType: &markers.Argument{Type: markers.AnyType}})
+	case yaml.MappingNode:
+		return c.renderArg(node, markers.Argument{Type: markers.MapType, ItemType: &markers.Argument{Type: markers.AnyType}})
+	}
+	switch node.Tag {
+	case "!!int":
+		return c.renderArg(node, markers.Argument{Type: markers.IntType})
+	case "!!float":
+		return c.renderArg(node, markers.Argument{Type: markers.NumberType})
+	case "!!bool":
+		return c.renderArg(node, markers.Argument{Type: markers.BoolType})
+	default:
+		return c.renderArg(node, markers.Argument{Type: markers.StringType})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// optionsGenerator is a generator with a few options, for testing option parsing.
+type optionsGenerator struct {
+	fakeGenerator
+
+	RoleName   string
+	MaxDescLen *int     `marker:",optional"`
+	Versions   []string `marker:",optional"`
+}
+
+var _ = Describe("Configuration files", func() {
+	var reg *markers.Registry
+
+	BeforeEach(func() {
+		reg = &markers.Registry{}
+		Expect(reg.Define("fake", markers.DescribesPackage, optionsGenerator{})).To(Succeed())
+		Expect(reg.Define("other", markers.DescribesPackage, fakeGenerator{})).To(Succeed())
+		for _, prefix := range []string{"output:", "output:fake:", "output:other:"} {
+			Expect(reg.Define(prefix+"dir", markers.DescribesPackage, genall.OutputToDirectory(""))).To(Succeed())
+			Expect(reg.Define(prefix+"stdout", markers.DescribesPackage, genall.OutputToStdout)).To(Succeed())
+			Expect(reg.Define(prefix+"artifacts", markers.DescribesPackage, genall.OutputArtifacts{})).To(Succeed())
+		}
+		Expect(genall.RegisterOptionsMarkers(reg)).To(Succeed())
+	})
+
+	It("should convert a valid file into the equivalent options", func() {
+		opts, err := genall.OptionsFromConfig(reg, "cfg.yaml", []byte(`
+paths:
+- ./apis/...
+- ./other
+generators:
+  fake:
+    roleName: manager-role
+    maxDescLen: 0
+    versions: [v1, v2]
+  other:
+output:
+  fake:
+    artifacts:
+      config: config/fake
+  other: stdout
+  default:
+    dir: "some dir"
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal([]string{
+			`paths={"./apis/...","./other"}`,
+			`fake:roleName="manager-role",maxDescLen=0,versions={"v1","v2"}`,
+			`other`,
+			`output:fake:artifacts:config="config/fake"`,
+			`output:other:stdout`,
+			`output:dir="some dir"`,
+		}))
+
+		By("checking that the options actually produce the expected runtime pieces")
+		_, err = genall.RegistryFromOptions(reg, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report all problems, with their positions", func() {
+		_, err := genall.OptionsFromConfig(reg, "cfg.yaml", []byte(`paths: ./apis/...
+generators:
+  fake:
+    roleNam: manager-role
+    maxDescLen: lots
+  fakee: {}
+output:
+  fake:
+    directory: config
+outputs: {}
+`))
+		Expect(err).To(MatchError(
+			"cfg.yaml:4:5: unknown argument \"roleNam\" to generator \"fake\"\n" +
+				"cfg.yaml:5:17: expected an integer\n" +
+				"cfg.yaml:3:3: missing argument \"roleName\" to generator \"fake\"\n" +
+				"cfg.yaml:6:3: unknown generator \"fakee\"\n" +
+				"cfg.yaml:9:5: unknown output rule \"directory\"\n" +
+				"cfg.yaml:10:1: unknown field \"outputs\" (expected one of paths, plugins, generators, or output)"))
+	})
+
+	It("should let overriding options replace whole options from the base set", func() {
+		merged := genall.MergeOptions(reg,
+			[]string{`paths={"./apis/..."}`, `fake:roleName="a",maxDescLen=0`, `other`, `output:fake:artifacts:config="config/fake"`, `output:dir="out"`},
+			[]string{`fake:roleName=b`, `output:fake:stdout`, `paths=./other`})
+		Expect(merged).To(Equal([]string{
+			`other`,
+			`output:dir="out"`,
+			`fake:roleName=b`,
+			`output:fake:stdout`,
+			`paths=./other`,
+		}))
+	})
+})
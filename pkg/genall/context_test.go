@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// blockingGenerator writes an artifact, and then waits for its context to
+// be done.
+type blockingGenerator struct{}
+
+func (blockingGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (g blockingGenerator) Generate(ctx *genall.GenerationContext) error {
+	return g.GenerateContext(context.Background(), ctx)
+}
+
+func (blockingGenerator) GenerateContext(ctx context.Context, genCtx *genall.GenerationContext) error {
+	out, err := genCtx.Open(nil, "blocked.yaml")
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+var _ = Describe("Canceling generation", func() {
+	var (
+		rt                  *genall.Runtime
+		configGen, blockGen genall.Generator
+		errOut              *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		configGen = fakeGenerator{artifacts: map[string]string{"a.yaml": "kind: A\n"}}
+		blockGen = blockingGenerator{}
+		errOut = &bytes.Buffer{}
+		rt = &genall.Runtime{
+			Generators:     genall.Generators{&configGen, &blockGen},
+			GeneratorNames: map[*genall.Generator]string{&configGen: "config", &blockGen: "block"},
+			ErrorWriter:    errOut,
+		}
+	})
+
+	It("should adapt plain generators to check the context before running", func() {
+		Expect(genall.WithContext(blockGen)).To(Equal(blockGen))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(genall.WithContext(configGen).GenerateContext(ctx, &genall.GenerationContext{})).To(MatchError(context.Canceled))
+	})
+
+	It("should stop running generators, keeping the artifacts of those that finished", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		res, err := rt.GenerateContext(ctx)
+		Expect(err).To(MatchError("generation canceled: context deadline exceeded"))
+		Expect(errors.As(err, &genall.CanceledError{})).To(BeTrue())
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(res.Artifacts).To(HaveKey("config"))
+		Expect(res.Artifacts).NotTo(HaveKey("block"))
+	})
+
+	It("should discard everything when running in parallel", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		rt.Parallel = true
+		res, err := rt.GenerateContext(ctx)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(res.Artifacts).To(BeEmpty())
+	})
+
+	It("should print the cancellation when running normally", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(rt.RunContext(ctx)).To(BeTrue())
+		Expect(errOut.String()).To(Equal("generation canceled: context canceled\n"))
+	})
+
+	It("should stop loading packages once canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := genall.Generators{&configGen}.ForRootsContext(ctx, "./testdata/diagnostics")
+		Expect(err).To(Equal(genall.CanceledError{Err: context.Canceled}))
+	})
+})
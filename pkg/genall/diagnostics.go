@@ -0,0 +1,498 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/controller-tools/pkg/version"
+)
+
+// DiagnosticsFormat is a format that a Runtime can write errors in.
+type DiagnosticsFormat string
+
+const (
+	// DiagnosticsText writes errors as plain text, one per line (or
+	// several, for diffs of out-of-date artifacts).
+	DiagnosticsText DiagnosticsFormat = ""
+	// DiagnosticsJSON writes each Diagnostic as a JSON object on its own
+	// line.
+	DiagnosticsJSON DiagnosticsFormat = "json"
+	// DiagnosticsSARIF writes a single SARIF 2.1.0 log containing all
+	// diagnostics, for consumption by code scanning tools.
+	DiagnosticsSARIF DiagnosticsFormat = "sarif"
+)
+
+// Severity is how serious a Diagnostic is.
+type Severity string
+
+const (
+	// SeverityError indicates a problem that causes generation to fail.
+	SeverityError Severity = "error"
+	// SeverityWarning indicates a problem that doesn't cause generation to
+	// fail.
+	SeverityWarning Severity = "warning"
+	// SeverityNote indicates information that isn't a problem at all, like
+	// an explanation of why a generator produced nothing.
+	SeverityNote Severity = "note"
+)
+
+// Diagnostic is a single problem found while running generators, in a form
+// suitable for consumption by other tools.
+type Diagnostic struct {
+	// File is the file that the problem is in, if known.
+	File string `json:"file,omitempty"`
+	// Line is the (1-based) line that the problem is on, if known.
+	Line int `json:"line,omitempty"`
+	// Column is the (1-based) column that the problem is at, if known.
+	Column int `json:"column,omitempty"`
+	// Severity is how serious the problem is.
+	Severity Severity `json:"severity"`
+	// Generator is the name of the generator that found the problem,
+	// if known.
+	Generator string `json:"generator,omitempty"`
+	// Marker is the name of the marker that the problem is with, if any.
+	Marker string `json:"marker,omitempty"`
+	// Package is the ID of the package that the problem is in, if any.
+	Package string `json:"package,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// String formats this Diagnostic like a plain-text error, with its position
+// (if known) before its message, and "warning: " (or "note: ") before that
+// if it's only a warning (or a note).
+func (d Diagnostic) String() string {
+	switch {
+	case d.File == "":
+		return d.message()
+	case d.Line == 0:
+		return d.File + ": " + d.message()
+	case d.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.message())
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.message())
+	}
+}
+
+// message returns the message, marked as a warning or note if it is one.
+func (d Diagnostic) message() string {
+	switch d.Severity {
+	case SeverityWarning, SeverityNote:
+		return string(d.Severity) + ": " + d.Message
+	default:
+		return d.Message
+	}
+}
+
+// Diagnostics is a list of Diagnostics, which may be used as an error.
+type Diagnostics []Diagnostic
+
+// Error formats each Diagnostic on its own line.
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors checks if any of the Diagnostics are errors (as opposed to
+// warnings or notes).
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// runWithDiagnostics runs the Generators like Run does, but collects all
+// problems as Diagnostics, writing them out in r.DiagnosticsFormat once
+// everything has finished.
+func (r *Runtime) runWithDiagnostics(ctx context.Context) bool {
+	diags, err := r.collectDiagnostics(ctx)
+	if err != nil {
+		diags = append(diags, Diagnostic{Severity: SeverityError, Message: err.Error()})
+	}
+	return r.writeDiagnostics(diags)
+}
+
+// collectDiagnostics runs the Generators like Run does, but returns all
+// problems as Diagnostics instead of printing them.  If the given context
+// is done before everything's finished, it returns a CanceledError along
+// with the problems found so far.
+func (r *Runtime) collectDiagnostics(ctx context.Context) (Diagnostics, error) {
+	var diags Diagnostics
+	if len(r.Generators) == 0 {
+		return append(diags, Diagnostic{Severity: SeverityError, Message: "no generators to run"}), nil
+	}
+
+	var verifier *artifactVerifier
+	if r.Verify {
+		verifier = &artifactVerifier{}
+	}
+
+	// figure out which generator ran into each package error by looking for
+	// new errors after each one's run.  This isn't possible when they run in
+	// parallel, so errors found then aren't attributed to any generator.
+	owners := make(errorOwners)
+	owners.claim(r.Roots, "")
+	written := &writtenArtifacts{}
+	if err := r.runGenerators(ctx, verifier, written, func(gen *Generator, errs, warnings []error) {
+		name := r.generatorName(gen)
+		for _, err := range errs {
+			diags = append(diags, r.diagnosticsFor(err, name)...)
+		}
+		diags = append(diags, r.warningDiagnostics(warnings, name)...)
+		if !r.Parallel {
+			owners.claim(r.Roots, name)
+		}
+	}); err != nil {
+		return diags, err
+	}
+
+	if r.Explain {
+		diags = append(diags, r.explanations()...)
+	}
+
+	if verifier != nil {
+		for _, artifact := range verifier.staleArtifacts() {
+			msg := artifact.problem
+			if artifact.diff != "" {
+				msg += "\n" + artifact.diff
+			}
+			diags = append(diags, Diagnostic{File: artifact.path, Severity: SeverityError, Message: msg})
+		}
+	}
+
+	diags = append(diags, r.packageWarnings(owners)...)
+
+	visitPackages(r.Roots, func(pkg *loader.Package) {
+		for _, err := range pkg.ErrorDetails() {
+			// skip TypeErrors -- they're probably just from partial typechecking in crd-gen
+			if err.Kind == packages.TypeError {
+				continue
+			}
+			diags = append(diags, packageDiagnostic(pkg, err, SeverityError, owners[err.Error]))
+		}
+	})
+
+	if !diags.HasErrors() {
+		// only go looking for stale files if everything was generated
+		diags = append(diags, r.handleStaleFiles(written)...)
+	}
+	return diags, nil
+}
+
+// diagnosticsFor converts an error returned by a generator to Diagnostics,
+// one for each error in any ErrList.
+func (r *Runtime) diagnosticsFor(err error, generator string) []Diagnostic {
+	if errList, isList := err.(loader.ErrList); isList {
+		var diags []Diagnostic
+		for _, subErr := range errList {
+			diags = append(diags, r.diagnosticsFor(subErr, generator)...)
+		}
+		return diags
+	}
+
+	diag := Diagnostic{
+		Severity:  SeverityError,
+		Generator: generator,
+		Marker:    markerFor(err),
+		Message:   err.Error(),
+	}
+	var posErr loader.PositionedError
+	if errors.As(err, &posErr) && len(r.Roots) > 0 {
+		pos := r.Roots[0].FileSet().Position(posErr.Pos)
+		diag.File, diag.Line, diag.Column = pos.Filename, pos.Line, pos.Column
+	}
+	return []Diagnostic{diag}
+}
+
+// packageDiagnostic converts an error (or warning) recorded against the
+// given package into a Diagnostic.
+func packageDiagnostic(pkg *loader.Package, err loader.PackageError, severity Severity, generator string) Diagnostic {
+	return Diagnostic{
+		File:      err.Position.Filename,
+		Line:      err.Position.Line,
+		Column:    err.Position.Column,
+		Severity:  severity,
+		Generator: generator,
+		Marker:    markerFor(err.Cause),
+		Package:   pkg.ID,
+		Message:   err.Msg,
+	}
+}
+
+// markerFor returns the name of the marker that the given error is about,
+// if it's (or contains) an error from parsing a marker, or is about an
+// unknown marker.
+func markerFor(err error) string {
+	if err == nil {
+		return ""
+	}
+	var scanErr *markers.ScannerError
+	if errors.As(err, &scanErr) {
+		return scanErr.Marker
+	}
+	var unknownErr *markers.UnknownMarkerError
+	if errors.As(err, &unknownErr) {
+		return unknownErr.Marker
+	}
+	var errList loader.ErrList
+	if errors.As(err, &errList) {
+		for _, subErr := range errList {
+			if marker := markerFor(subErr); marker != "" {
+				return marker
+			}
+		}
+	}
+	return ""
+}
+
+// errorOwners maps package errors (and warnings) to the name of the
+// generator that was running when they were first recorded.
+type errorOwners map[packages.Error]string
+
+// claim records the given generator as the owner of all package errors
+// and warnings that don't yet have an owner.
+func (o errorOwners) claim(roots []*loader.Package, generator string) {
+	visitPackages(roots, func(pkg *loader.Package) {
+		for _, err := range append(pkg.ErrorDetails(), pkg.WarningDetails()...) {
+			if _, known := o[err.Error]; !known {
+				o[err.Error] = generator
+			}
+		}
+	})
+}
+
+// visitPackages calls visit for each of the given packages and everything
+// that they (transitively) import, in dependency order.
+func visitPackages(roots []*loader.Package, visit func(pkg *loader.Package)) {
+	seen := make(map[*loader.Package]struct{})
+	var visitOne func(pkg *loader.Package)
+	visitOne = func(pkg *loader.Package) {
+		if _, done := seen[pkg]; done {
+			return
+		}
+		seen[pkg] = struct{}{}
+
+		imports := pkg.Imports()
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			visitOne(imports[path])
+		}
+		visit(pkg)
+	}
+	for _, root := range roots {
+		visitOne(root)
+	}
+}
+
+// writeDiagnostics writes the given diagnostics to r.ErrorWriter in
+// r.DiagnosticsFormat, returning true if any of them are errors.
+func (r *Runtime) writeDiagnostics(diags Diagnostics) bool {
+	var err error
+	switch r.DiagnosticsFormat {
+	case DiagnosticsJSON:
+		err = writeDiagnosticsJSON(r.ErrorWriter, diags)
+	case DiagnosticsSARIF:
+		err = writeDiagnosticsSARIF(r.ErrorWriter, diags)
+	default:
+		err = fmt.Errorf("unknown diagnostics format %q", r.DiagnosticsFormat)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write diagnostics: %v\n", err)
+		return true
+	}
+	return diags.HasErrors()
+}
+
+// writeDiagnosticsJSON writes each diagnostic as a JSON object on its own line.
+func writeDiagnosticsJSON(out io.Writer, diags []Diagnostic) error {
+	enc := json.NewEncoder(out)
+	for _, diag := range diags {
+		if err := enc.Encode(diag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	// sarifToolName is the name of the tool reported in SARIF logs, and
+	// the rule used for problems not found by a particular generator.
+	sarifToolName = "controller-gen"
+)
+
+// The following types are the subset of the SARIF 2.1.0 log format
+// (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html)
+// that we produce.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeDiagnosticsSARIF writes all the diagnostics as a single SARIF log,
+// with a rule per generator.
+func writeDiagnosticsSARIF(out io.Writer, diags []Diagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           sarifToolName,
+			Version:        version.Version(),
+			InformationURI: "https://sigs.k8s.io/controller-tools",
+		}},
+		Results: []sarifResult{},
+	}
+
+	seenRules := make(map[string]struct{})
+	for _, diag := range diags {
+		ruleID := diag.Generator
+		if ruleID == "" {
+			ruleID = sarifToolName
+		}
+		if _, seen := seenRules[ruleID]; !seen {
+			seenRules[ruleID] = struct{}{}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: ruleID})
+		}
+
+		result := sarifResult{
+			RuleID:  ruleID,
+			Level:   string(diag.Severity),
+			Message: sarifMessage{Text: diag.Message},
+		}
+		if diag.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(diag.File)},
+			}}
+			if diag.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: diag.Line, StartColumn: diag.Column}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		for key, val := range map[string]string{
+			"generator": diag.Generator,
+			"marker":    diag.Marker,
+			"package":   diag.Package,
+		} {
+			if val == "" {
+				continue
+			}
+			if result.Properties == nil {
+				result.Properties = make(map[string]string)
+			}
+			result.Properties[key] = val
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI converts the given path to a URI for use in a SARIF log.  Paths
+// in the current directory are made relative to it (which is what code
+// scanning tools generally expect), while others become file URIs.
+func sarifURI(path string) string {
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	if !filepath.IsAbs(path) {
+		return (&url.URL{Path: filepath.ToSlash(path)}).String()
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
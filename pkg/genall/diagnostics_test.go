@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var countMarker = markers.Must(markers.MakeDefinition("diag:count", markers.DescribesType, 0))
+
+// markerErrorsGenerator records errors from collecting markers against
+// each root.
+type markerErrorsGenerator struct{}
+
+func (markerErrorsGenerator) RegisterMarkers(reg *markers.Registry) error {
+	return reg.Register(countMarker)
+}
+
+func (markerErrorsGenerator) Generate(ctx *genall.GenerationContext) error {
+	for _, root := range ctx.Roots {
+		if _, err := ctx.Collector.MarkersInPackage(root); err != nil {
+			root.AddError(err)
+		}
+	}
+	return nil
+}
+
+// failingGenerator always fails.
+type failingGenerator struct{}
+
+func (failingGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (failingGenerator) Generate(_ *genall.GenerationContext) error {
+	return errors.New("something went wrong")
+}
+
+var _ = Describe("Machine-readable diagnostics", func() {
+	var (
+		rt     *genall.Runtime
+		errOut *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var markerGen, failingGen genall.Generator = markerErrorsGenerator{}, failingGenerator{}
+		var err error
+		rt, err = genall.Generators{&markerGen, &failingGen}.ForRoots("./testdata/diagnostics")
+		Expect(err).NotTo(HaveOccurred())
+		rt.GeneratorNames = map[*genall.Generator]string{&markerGen: "markers", &failingGen: "failing"}
+		errOut = &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+	})
+
+	It("should write each problem as a JSON object on its own line", func() {
+		rt.DiagnosticsFormat = genall.DiagnosticsJSON
+		Expect(rt.Run()).To(BeTrue())
+
+		lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		var diags []genall.Diagnostic
+		for _, line := range lines {
+			var diag genall.Diagnostic
+			Expect(json.Unmarshal([]byte(line), &diag)).To(Succeed())
+			diags = append(diags, diag)
+		}
+
+		By("reporting errors returned by generators first")
+		Expect(diags[0]).To(Equal(genall.Diagnostic{
+			Severity:  genall.SeverityError,
+			Generator: "failing",
+			Message:   "something went wrong",
+		}))
+
+		By("reporting errors recorded against packages, with their position and marker")
+		markerDiag := diags[1]
+		Expect(filepath.ToSlash(markerDiag.File)).To(HaveSuffix("testdata/diagnostics/types.go"))
+		Expect(markerDiag.Line).To(Equal(21))
+		Expect(markerDiag.Column).To(BeNumerically(">", 0))
+		Expect(markerDiag.Severity).To(Equal(genall.SeverityError))
+		Expect(markerDiag.Generator).To(Equal("markers"))
+		Expect(markerDiag.Marker).To(Equal("diag:count"))
+		Expect(markerDiag.Package).To(HaveSuffix("pkg/genall/testdata/diagnostics"))
+		Expect(markerDiag.Message).To(ContainSubstring("expected integer"))
+	})
+
+	It("should write a SARIF log with a rule per generator", func() {
+		rt.DiagnosticsFormat = genall.DiagnosticsSARIF
+		Expect(rt.Run()).To(BeTrue())
+
+		var log struct {
+			Version string `json:"version"`
+			Runs    []struct {
+				Tool struct {
+					Driver struct {
+						Name  string `json:"name"`
+						Rules []struct {
+							ID string `json:"id"`
+						} `json:"rules"`
+					} `json:"driver"`
+				} `json:"tool"`
+				Results []struct {
+					RuleID    string `json:"ruleId"`
+					Level     string `json:"level"`
+					Locations []struct {
+						PhysicalLocation struct {
+							ArtifactLocation struct {
+								URI string `json:"uri"`
+							} `json:"artifactLocation"`
+							Region struct {
+								StartLine int `json:"startLine"`
+							} `json:"region"`
+						} `json:"physicalLocation"`
+					} `json:"locations"`
+					Properties map[string]string `json:"properties"`
+				} `json:"results"`
+			} `json:"runs"`
+		}
+		Expect(json.Unmarshal(errOut.Bytes(), &log)).To(Succeed())
+		Expect(log.Version).To(Equal("2.1.0"))
+		Expect(log.Runs).To(HaveLen(1))
+		run := log.Runs[0]
+		Expect(run.Tool.Driver.Name).To(Equal("controller-gen"))
+		Expect(run.Tool.Driver.Rules).To(HaveLen(2))
+		Expect(run.Tool.Driver.Rules[0].ID).To(Equal("failing"))
+		Expect(run.Tool.Driver.Rules[1].ID).To(Equal("markers"))
+
+		Expect(run.Results).To(HaveLen(2))
+		Expect(run.Results[0].RuleID).To(Equal("failing"))
+		Expect(run.Results[0].Locations).To(BeEmpty())
+		Expect(run.Results[1].RuleID).To(Equal("markers"))
+		Expect(run.Results[1].Level).To(Equal("error"))
+		Expect(run.Results[1].Locations).To(HaveLen(1))
+		Expect(run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("testdata/diagnostics/types.go"))
+		Expect(run.Results[1].Locations[0].PhysicalLocation.Region.StartLine).To(Equal(21))
+		Expect(run.Results[1].Properties).To(HaveKeyWithValue("marker", "diag:count"))
+	})
+
+	It("should still write a valid (empty) SARIF log when there are no problems", func() {
+		var markerGen genall.Generator = markerErrorsGenerator{}
+		rt.Generators = genall.Generators{&markerGen}
+		rt.Roots = nil
+		rt.DiagnosticsFormat = genall.DiagnosticsSARIF
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(ContainSubstring(`"results": []`))
+	})
+})
+
+var _ = Describe("Unknown markers", func() {
+	var (
+		rt     *genall.Runtime
+		errOut *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var markerGen genall.Generator = markerErrorsGenerator{}
+		var err error
+		rt, err = genall.Generators{&markerGen}.ForRoots("./testdata/unknownmarkers")
+		Expect(err).NotTo(HaveOccurred())
+		rt.GeneratorNames = map[*genall.Generator]string{&markerGen: "markers"}
+		errOut = &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+	})
+
+	It("should be reported as warnings, with suggestions", func() {
+		Expect(rt.Run()).To(BeFalse())
+		Expect(filepath.ToSlash(errOut.String())).To(MatchRegexp(
+			`^\S*testdata/unknownmarkers/types.go:21:1: warning: unknown marker "\+diag:cuont" \(did you mean "\+diag:count"\?\)\n$`))
+	})
+
+	It("should be reported as warning diagnostics, naming the marker", func() {
+		res, err := rt.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Diagnostics).To(HaveLen(1))
+		Expect(res.Diagnostics[0].Severity).To(Equal(genall.SeverityWarning))
+		Expect(res.Diagnostics[0].Line).To(Equal(21))
+		Expect(res.Diagnostics[0].Marker).To(Equal("diag:cuont"))
+		Expect(res.Diagnostics[0].Package).To(HaveSuffix("pkg/genall/testdata/unknownmarkers"))
+	})
+
+	It("should be errors when the collector is asked to fail on them", func() {
+		rt.Collector.FailOnUnknownMarkers = true
+		res, err := rt.Generate()
+		Expect(err).To(HaveOccurred())
+		Expect(res.Diagnostics).To(HaveLen(1))
+		Expect(res.Diagnostics[0].Severity).To(Equal(genall.SeverityError))
+		Expect(res.Diagnostics[0].Generator).To(Equal("markers"))
+		Expect(res.Diagnostics[0].Marker).To(Equal("diag:cuont"))
+		Expect(res.Diagnostics[0].Message).To(Equal(`unknown marker "+diag:cuont" (did you mean "+diag:count"?)`))
+	})
+})
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// maxDiffEdits bounds the work done trying to find a minimal diff.  Past
+	// this, we just show the whole of both files, which is more useful than
+	// a "minimal" diff between two mostly unrelated files anyway.
+	maxDiffEdits = 1000
+)
+
+// diffOp is a single line in an edit script.
+type diffOp struct {
+	// kind is ' ' for unchanged lines, '-' for removed lines,
+	// and '+' for added lines.
+	kind byte
+	// line is the content of the line, including the trailing newline
+	// (if any).
+	line string
+}
+
+// unifiedDiff produces a unified diff (like `diff -u`) transforming from into
+// to, labelling each side with the given names.  It returns the empty string
+// if the two are identical.
+func unifiedDiff(fromName, toName string, from, to []byte) string {
+	if string(from) == string(to) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(from)), splitLines(string(to)))
+
+	// figure out where in each file each op lives, for hunk headers
+	fromPos := make([]int, len(ops)+1)
+	toPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if op.kind != '+' {
+			fromPos[i+1]++
+		}
+		if op.kind != '-' {
+			toPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// grow the hunk until we hit a long-enough run of unchanged lines
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		lastChange := i
+		for j := i; j < len(ops) && j-lastChange <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				lastChange = j
+			}
+		}
+		end := lastChange + diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&out, ops[start:end], fromPos[start], toPos[start], fromPos[end]-fromPos[start], toPos[end]-toPos[start])
+		i = end
+	}
+	return out.String()
+}
+
+// writeHunk writes out a single hunk of a unified diff, with the given
+// (zero-indexed) starting lines and lengths.
+func writeHunk(out *strings.Builder, ops []diffOp, fromStart, toStart, fromLen, toLen int) {
+	// unified diff lines are one-indexed, except for empty ranges, which
+	// point at the line *before* the range.
+	if fromLen > 0 {
+		fromStart++
+	}
+	if toLen > 0 {
+		toStart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", fromStart, fromLen, toStart, toLen)
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits the given text into lines, keeping line terminators.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes an edit script transforming from into to, using
+// Myers' O(ND) algorithm.
+func diffLines(from, to []string) []diffOp {
+	n, m := len(from), len(to)
+	maxEdits := n + m
+	if maxEdits > maxDiffEdits {
+		maxEdits = maxDiffEdits
+	}
+
+	// v[k] holds the furthest x reached on diagonal k (offset by maxEdits+1,
+	// so that we can index k-1 and k+1 for all reachable diagonals).
+	offset := maxEdits + 1
+	v := make([]int, 2*maxEdits+3)
+	// trace[d] holds the relevant part of v (diagonals -d...d)
+	// as it was after d edits.
+	var trace [][]int
+	found := false
+	for d := 0; d <= maxEdits && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // move down (insertion)
+			} else {
+				x = v[offset+k-1] + 1 // move right (deletion)
+			}
+			y := x - k
+			for x < n && y < m && from[x] == to[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	if !found {
+		// too different to bother -- just replace everything
+		ops := make([]diffOp, 0, n+m)
+		for _, line := range from {
+			ops = append(ops, diffOp{kind: '-', line: line})
+		}
+		for _, line := range to {
+			ops = append(ops, diffOp{kind: '+', line: line})
+		}
+		return ops
+	}
+
+	// walk backwards through the trace to recover the actual edits
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // diagonals -(d-1)...(d-1), indexed by k+d-1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', line: from[x-1]})
+			x, y = x-1, y-1
+		}
+		if prevK == k+1 {
+			ops = append(ops, diffOp{kind: '+', line: to[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{kind: '-', line: from[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{kind: ' ', line: from[x-1]})
+		x, y = x-1, y-1
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
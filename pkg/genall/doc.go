@@ -36,6 +36,10 @@ limitations under the License.
 //
 // OutputRules are defined for stdout, file writing, and sending to /dev/null
 // (useful for doing "type-checking" without actually saving the results).
+// Files are only rewritten if their contents have changed (leaving their
+// modification times alone otherwise), and are replaced atomically.  The
+// Runtime also holds on to the files a generator writes until it's finished,
+// discarding them if it fails.
 //
 // InputRule defines custom input loading, but its shared across all
 // Generators.  There's currently only a filesystem implementation.
@@ -50,9 +54,73 @@ limitations under the License.
 // skipping type-checking errors (since those are commonly caused by the
 // partial type-checking of loader.TypeChecker).
 //
+// A Runtime can also be asked to verify output instead of writing it, in
+// which case artifacts are rendered in memory and compared against the files
+// they would have been written to, with any differences reported as errors.
+//
+// A Runtime can also be set to Prune generated files (as indicated by
+// GeneratedAnnotation) that it generated previously, but didn't generate
+// this time around, like the CRD for a type that's since been removed.  The
+// files each set of Generators and roots generated are recorded in a
+// manifest in the directories they write config to, so that files from
+// other invocations sharing those directories are never removed.
+//
+// Generators normally run one after another, but may also be run in
+// parallel, since the loader, type-checker, and marker collector are all
+// safe for concurrent use.  Errors, and output that isn't written to its own
+// file, are still reported in the order the generators are listed in.
+//
+// Loading packages and running generators can be stopped early with a
+// context.Context, using ForRootsContext, RunContext, and GenerateContext,
+// which report a CanceledError when that happens.  Generators that can stop
+// part-way through implement ContextGenerator; others (adapted with
+// WithContext) just aren't started once the context is done.
+//
+// Tools that embed generators, and want their output as Go values instead of
+// files, can use Generate, which captures each generator's artifacts with
+// OutputToMemory and returns them along with any problems as Diagnostics.
+//
+// Finally, a Runtime can watch the source files of its roots, re-running
+// generators as they change.  Only the changed packages are re-loaded, and
+// generators can keep information between runs in their GenerationCache,
+// discarding just the parts about changed packages by implementing
+// Invalidator.
+//
+// Errors can also be reported in machine-readable form, for editors and CI
+// systems, by setting the Runtime's DiagnosticsFormat.  Each problem is then
+// described by a Diagnostic, saying where it is, which generator found it,
+// and which marker (if any) it's about, and written out either as JSON lines
+// or as a SARIF log.
+//
+// Problems that don't stop generation, like uses of deprecated markers, are
+// reported as warnings: generators add them to their GenerationContext, or
+// to the package they're about with loader.Package.AddWarning.  Warnings are
+// printed (or described by Diagnostics) alongside errors, but only fail the
+// run if the Runtime is set to treat WarningsAsErrors.  Only warnings about
+// the roots are reported, not ones about the packages they import.
+//
+// When a generator produces nothing, a Runtime set to Explain asks it why,
+// if it's an Explainer, reporting each reason (like a missing marker) as a
+// note against the root package it's about.
+//
 // Options
 //
 // The FromOptions (and associated helpers) function makes it easy to use generators
 // and output rules as markers that can be parsed from the command line, producing
 // a registry from command line args.
+//
+// The same options can also be written down in a YAML configuration file,
+// which OptionsFromConfig checks against the options registry and converts
+// into options.  MergeOptions then lets options from the command line
+// override those from the file.
+//
+// Plugins
+//
+// Generators can also live in separate executables, declared with the
+// "plugin" option.  RegisterPlugins asks each plugin to describe its options
+// and markers, registering a generator option for it that works just like
+// the built-in ones.  When run, the plugin is passed its options and the
+// root packages (along with their marker values) as JSON on standard input,
+// and replies with the artifacts to write out through its OutputRule.  See
+// PluginRequest for the details of the protocol.
 package genall
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"errors"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// Explainer is a Generator that can explain why it generates nothing (or
+// less than might be expected) for some root packages, to help track down
+// missing or misplaced markers and the like.
+type Explainer interface {
+	Generator
+	// Explain returns the reasons that the Generator generates nothing (or
+	// less than might be expected) for the context's roots, leaving out
+	// roots that it has nothing to say about.  It's called with the same
+	// sort of context as Generate, after Generate has been called.
+	Explain(ctx *GenerationContext) []Explanation
+}
+
+// Explanation describes why a Generator generates nothing (or less than
+// might be expected) for a root package.
+type Explanation struct {
+	// Package is the root package being explained.
+	Package *loader.Package
+	// Reasons say what's missing from the package.  Reasons about
+	// particular parts of the package should be created with
+	// loader.ErrFromNode, so that they can be reported along with their
+	// position.  Others are reported against the package's first file.
+	Reasons []error
+}
+
+// explanations asks each Generator that's an Explainer why it generated
+// nothing for the roots, returning the answers as notes.
+func (r *Runtime) explanations() Diagnostics {
+	var diags Diagnostics
+	for _, gen := range r.Generators {
+		explainer, canExplain := (*gen).(Explainer)
+		if !canExplain {
+			continue
+		}
+		// explaining shouldn't write anything, but the context should
+		// otherwise look like the one passed to Generate
+		genCtx := r.contextFor(gen, nil, &writtenArtifacts{})
+		genCtx.OutputRule = OutputToNothing
+		name := r.generatorName(gen)
+		for _, explanation := range explainer.Explain(&genCtx) {
+			for _, reason := range explanation.Reasons {
+				diags = append(diags, explanationDiagnostic(explanation.Package, reason, name))
+			}
+		}
+	}
+	return diags
+}
+
+// explanationDiagnostic converts one reason for generating nothing for the
+// given package into a note.
+func explanationDiagnostic(pkg *loader.Package, reason error, generator string) Diagnostic {
+	diag := Diagnostic{
+		Severity:  SeverityNote,
+		Generator: generator,
+		Marker:    markerFor(reason),
+		Package:   pkg.ID,
+		Message:   reason.Error(),
+	}
+	var posErr loader.PositionedError
+	switch {
+	case errors.As(reason, &posErr):
+		pos := pkg.FileSet().Position(posErr.Pos)
+		diag.File, diag.Line, diag.Column = pos.Filename, pos.Line, pos.Column
+	case len(pkg.GoFiles) > 0:
+		diag.File = pkg.GoFiles[0]
+	}
+	return diag
+}
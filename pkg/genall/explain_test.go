@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// explainingGenerator generates nothing, and says so.
+type explainingGenerator struct{}
+
+func (explainingGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (explainingGenerator) Generate(_ *genall.GenerationContext) error { return nil }
+
+func (explainingGenerator) Explain(ctx *genall.GenerationContext) []genall.Explanation {
+	var explanations []genall.Explanation
+	for _, root := range ctx.Roots {
+		explanations = append(explanations, genall.Explanation{
+			Package: root,
+			Reasons: []error{errors.New("nothing generated: nothing to generate")},
+		})
+	}
+	return explanations
+}
+
+var _ = Describe("Explanations", func() {
+	var (
+		rt     *genall.Runtime
+		errOut *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var explainingGen genall.Generator = explainingGenerator{}
+		var err error
+		rt, err = genall.Generators{&explainingGen}.ForRoots("./testdata/diagnostics")
+		Expect(err).NotTo(HaveOccurred())
+		rt.GeneratorNames = map[*genall.Generator]string{&explainingGen: "explaining"}
+		errOut = &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+	})
+
+	It("should only be given when asked for", func() {
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("should be printed as notes against the package, without failing the run", func() {
+		rt.Explain = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(filepath.ToSlash(errOut.String())).To(MatchRegexp(
+			`^\S*testdata/diagnostics/types.go: note: nothing generated: nothing to generate\n$`))
+	})
+
+	It("should be reported as note diagnostics, attributed to their generator", func() {
+		rt.Explain = true
+		res, err := rt.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Diagnostics).To(HaveLen(1))
+		Expect(res.Diagnostics[0].Severity).To(Equal(genall.SeverityNote))
+		Expect(res.Diagnostics[0].Generator).To(Equal("explaining"))
+		Expect(res.Diagnostics[0].Package).To(HaveSuffix("pkg/genall/testdata/diagnostics"))
+	})
+})
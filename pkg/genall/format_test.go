@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+var _ = Describe("Writing manifests in different formats", func() {
+	var (
+		out  *genall.OutputToMemory
+		ctx  genall.GenerationContext
+		objs []interface{}
+	)
+
+	BeforeEach(func() {
+		out = &genall.OutputToMemory{}
+		ctx = genall.GenerationContext{OutputRule: out}
+		objs = []interface{}{
+			map[string]interface{}{"kind": "A", "spec": map[string]interface{}{"size": 1.5, "note": "<b>"}},
+			map[string]interface{}{"kind": "B", "status": map[string]interface{}{}},
+		}
+	})
+
+	written := func() map[string]string {
+		contents := make(map[string]string)
+		for key, artifact := range out.Artifacts() {
+			contents[key] = string(artifact.Contents)
+		}
+		return contents
+	}
+
+	It("should write YAML documents by default", func() {
+		Expect(ctx.WriteYAML("things.yaml", objs)).To(Succeed())
+		Expect(written()).To(Equal(map[string]string{
+			"things.yaml": "---\nkind: A\nspec:\n  note: <b>\n  size: 1.5\n---\nkind: B\nstatus: {}\n",
+		}))
+	})
+
+	It("should write indented JSON, with a matching extension", func() {
+		Expect(ctx.WriteYAML("things.yaml", objs, genall.WithFormat(genall.FormatJSON))).To(Succeed())
+		Expect(written()).To(Equal(map[string]string{
+			"things.json": "{\n  \"kind\": \"A\",\n  \"spec\": {\n    \"note\": \"<b>\",\n    \"size\": 1.5\n  }\n}\n" +
+				"{\n  \"kind\": \"B\",\n  \"status\": {}\n}\n",
+		}))
+	})
+
+	It("should write one compact JSON object per line", func() {
+		Expect(ctx.WriteYAML("things.yml", objs, genall.WithFormat(genall.FormatJSONLines))).To(Succeed())
+		Expect(written()).To(Equal(map[string]string{
+			"things.jsonl": "{\"kind\":\"A\",\"spec\":{\"note\":\"<b>\",\"size\":1.5}}\n{\"kind\":\"B\",\"status\":{}}\n",
+		}))
+	})
+
+	It("should write a List holding all the objects", func() {
+		Expect(ctx.WriteYAML("things.yaml", objs, genall.WithFormat(genall.FormatList))).To(Succeed())
+		Expect(written()).To(Equal(map[string]string{
+			"things.json": "{\n  \"apiVersion\": \"v1\",\n  \"items\": [\n" +
+				"    {\n      \"kind\": \"A\",\n      \"spec\": {\n        \"note\": \"<b>\",\n        \"size\": 1.5\n      }\n    },\n" +
+				"    {\n      \"kind\": \"B\",\n      \"status\": {}\n    }\n" +
+				"  ],\n  \"kind\": \"List\"\n}\n",
+		}))
+	})
+
+	It("should apply transformations in any format", func() {
+		removeStatus := genall.WithTransform(func(obj map[string]interface{}) error {
+			delete(obj, "status")
+			return nil
+		})
+		Expect(ctx.WriteYAML("things.yaml", objs, removeStatus, genall.WithFormat(genall.FormatJSONLines))).To(Succeed())
+		Expect(written()).To(HaveKeyWithValue("things.jsonl", "{\"kind\":\"A\",\"spec\":{\"note\":\"<b>\",\"size\":1.5}}\n{\"kind\":\"B\"}\n"))
+	})
+
+	It("should write a header before the first YAML document", func() {
+		Expect(ctx.WriteYAML("things.yaml", objs[1:], genall.WithHeader("Copyright 2022 The Authors.\n\nDO NOT EDIT."))).To(Succeed())
+		Expect(written()).To(Equal(map[string]string{
+			"things.yaml": "# Copyright 2022 The Authors.\n#\n# DO NOT EDIT.\n---\nkind: B\nstatus: {}\n",
+		}))
+	})
+
+	It("should refuse to write headers in JSON formats", func() {
+		err := ctx.WriteYAML("things.yaml", objs, genall.WithHeader("DO NOT EDIT."), genall.WithFormat(genall.FormatJSON))
+		Expect(err).To(MatchError("headers can only be written in the yaml format, not json"))
+	})
+
+	It("should refuse unknown formats", func() {
+		err := ctx.WriteYAML("things.yaml", objs, genall.WithFormat("toml"))
+		Expect(err).To(MatchError(`unknown format "toml" (expected yaml, json, jsonl, or list)`))
+		Expect(written()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Turning headers into YAML comments", func() {
+	It("should comment out each line", func() {
+		Expect(genall.YAMLComment("Copyright 2022.\n\n    Indented.\n")).To(Equal("# Copyright 2022.\n#\n#     Indented.\n"))
+	})
+
+	It("should swap Go comments for YAML ones", func() {
+		Expect(genall.YAMLComment("/*\nCopyright 2022.\n*/\n")).To(Equal("# Copyright 2022.\n"))
+		Expect(genall.YAMLComment("// Copyright 2022.\n//\n// License.")).To(Equal("# Copyright 2022.\n#\n# License.\n"))
+	})
+
+	It("should leave existing YAML comments alone", func() {
+		Expect(genall.YAMLComment("# Copyright 2022.\n#  License.\n")).To(Equal("# Copyright 2022.\n#  License.\n"))
+	})
+
+	It("should produce nothing for empty headers", func() {
+		Expect(genall.YAMLComment(" \n")).To(BeEmpty())
+	})
+})
@@ -17,11 +17,15 @@ limitations under the License.
 package genall
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 	rawyaml "gopkg.in/yaml.v2"
@@ -85,6 +89,75 @@ type Generator interface {
 	Generate(*GenerationContext) error
 }
 
+// ContextGenerator is a Generator that can stop early when asked to, via a
+// context.Context.  Runtimes use GenerateContext instead of Generate for
+// Generators that implement it.
+type ContextGenerator interface {
+	Generator
+	// GenerateContext is like Generate, but should stop early, returning
+	// the context's error, once the given context is done.
+	GenerateContext(ctx context.Context, genCtx *GenerationContext) error
+}
+
+// WithContext adapts the given Generator to a ContextGenerator.  If it
+// doesn't implement ContextGenerator itself, the result only checks whether
+// the context is done before running it, since there's no way to stop it
+// once it's started.
+func WithContext(gen Generator) ContextGenerator {
+	if ctxGen, isCtxGen := gen.(ContextGenerator); isCtxGen {
+		return ctxGen
+	}
+	return contextAdapter{Generator: gen}
+}
+
+// contextAdapter adapts a Generator to a ContextGenerator.
+type contextAdapter struct {
+	Generator
+}
+
+func (g contextAdapter) GenerateContext(ctx context.Context, genCtx *GenerationContext) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return g.Generate(genCtx)
+}
+
+// CanceledError is returned when loading packages or running generators is
+// stopped early because its context was canceled, or its deadline passed.
+type CanceledError struct {
+	// Err is the context's error (context.Canceled or
+	// context.DeadlineExceeded).
+	Err error
+}
+
+func (e CanceledError) Error() string {
+	return "generation canceled: " + e.Err.Error()
+}
+
+// Unwrap returns the context's error.
+func (e CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// canceledError returns a CanceledError for the given context if it's done,
+// or nil otherwise.
+func canceledError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return CanceledError{Err: err}
+	}
+	return nil
+}
+
+// DependsOnlyOnMarkers may be implemented by Generators whose output depends
+// only on the values of markers in the root packages (and not on what they're
+// attached to, or on any Go types).  When watching for changes, such
+// Generators are only re-run if marker values have changed.
+type DependsOnlyOnMarkers interface {
+	// DependsOnlyOnMarkers doesn't do anything -- it just indicates that
+	// the Generator implements this interface.
+	DependsOnlyOnMarkers()
+}
+
 // HasHelp is some Generator, OutputRule, etc with a help method.
 type HasHelp interface {
 	// Help returns help for this generator.
@@ -103,6 +176,43 @@ type Runtime struct {
 	OutputRules OutputRules
 	// ErrorWriter defines where to write error messages.
 	ErrorWriter io.Writer
+	// Verify indicates that, instead of writing artifacts, Run should
+	// compare them against the existing files that they'd be written to,
+	// printing a diff for (and failing on) any that are out of date or
+	// missing.  Artifacts that aren't written to disk (e.g. those sent to
+	// stdout) are ignored.
+	Verify bool
+	// Parallel indicates that Generators should be run concurrently
+	// instead of one after another.  Errors are still reported in the
+	// order of Generators, and output that isn't written to a per-artifact
+	// file (e.g. stdout) is buffered and written in that order as well.
+	Parallel bool
+	// DiagnosticsFormat is the format to write errors to ErrorWriter in.
+	// By default, they're written as plain text.
+	DiagnosticsFormat DiagnosticsFormat
+	// Prune indicates that, after a successful run, generated files (those
+	// containing GeneratedAnnotation) that the Generators wrote to their
+	// config directories for the same roots on an earlier (pruning) run, but
+	// didn't write this time, should be removed.  When verifying, they're
+	// reported as errors instead.  Files generated by anything else, like
+	// another invocation sharing the directories, are left alone.
+	Prune bool
+	// WarningsAsErrors makes warnings (from Generators and packages)
+	// errors, failing the run.
+	WarningsAsErrors bool
+	// Explain indicates that, after running, Generators that are
+	// Explainers should be asked why they generated nothing for any of the
+	// roots, with their answers reported as notes.
+	Explain bool
+	// GeneratorNames are the names of the Generators (as used in options),
+	// used to say which Generator ran into a problem in diagnostics.
+	// Generators not listed here are identified by their Go type names.
+	GeneratorNames map[*Generator]string
+
+	// rootPaths are the paths that Roots were loaded from, if known.
+	rootPaths []string
+	// caches hold each Generator's GenerationCache, when watching.
+	caches map[*Generator]*GenerationCache
 }
 
 // GenerationContext defines the common information needed for each Generator
@@ -119,11 +229,36 @@ type GenerationContext struct {
 	// InputRule describes how to load associated boilerplate artifacts.
 	// It should *not* be used to load source files.
 	InputRule
+	// Cache is where the Generator may keep information between runs
+	// against the same packages (as happens when watching for changes).
+	// It's nil if the Generator won't be run again.
+	Cache *GenerationCache
+
+	// warnings collects warnings passed to AddWarning.
+	warnings *warningList
+}
+
+// GenerationCache holds information that a Generator wants to keep between
+// runs.  Between runs, packages that have changed on disk are passed to
+// loader.Invalidate, and the cached value is told about them as well if it's
+// an Invalidator.
+type GenerationCache struct {
+	// Value is whatever the Generator wants to keep.
+	Value interface{}
+}
+
+// Invalidator knows how to discard cached information about packages that
+// have changed.
+type Invalidator interface {
+	// Invalidate discards any information about the given packages.
+	Invalidate(pkgs ...*loader.Package)
 }
 
 // WriteYAMLOptions implements the Options Pattern for WriteYAML.
 type WriteYAMLOptions struct {
 	transform func(obj map[string]interface{}) error
+	format    ManifestFormat
+	header    string
 }
 
 // WithTransform applies a transformation to objects just before writing them.
@@ -133,33 +268,187 @@ func WithTransform(transform func(obj map[string]interface{}) error) *WriteYAMLO
 	}
 }
 
+// WithFormat writes objects in the given format, instead of as YAML.
+func WithFormat(format ManifestFormat) *WriteYAMLOptions {
+	return &WriteYAMLOptions{
+		format: format,
+	}
+}
+
+// WithHeader writes the given text (e.g. a license, or a "DO NOT EDIT"
+// banner) before the objects, as a YAML comment (see YAMLComment).  Headers
+// can only be written in the YAML format, since JSON has no comments.
+func WithHeader(text string) *WriteYAMLOptions {
+	return &WriteYAMLOptions{
+		header: text,
+	}
+}
+
+// ManifestFormat is a format that WriteYAML can write objects in.
+type ManifestFormat string
+
+const (
+	// FormatYAML writes objects as YAML documents.  It's the default.
+	FormatYAML ManifestFormat = "yaml"
+	// FormatJSON writes objects as indented JSON, one after another.
+	FormatJSON ManifestFormat = "json"
+	// FormatJSONLines writes each object as compact JSON on its own line.
+	FormatJSONLines ManifestFormat = "jsonl"
+	// FormatList writes objects as the items of a single v1 List object,
+	// as indented JSON.
+	FormatList ManifestFormat = "list"
+)
+
+// Validate checks that this is a known format (or empty, meaning YAML).
+func (f ManifestFormat) Validate() error {
+	switch f {
+	case "", FormatYAML, FormatJSON, FormatJSONLines, FormatList:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (expected %s, %s, %s, or %s)", f, FormatYAML, FormatJSON, FormatJSONLines, FormatList)
+	}
+}
+
+// pathFor swaps the YAML extension of the given path (if any) for the one
+// that goes with this format.
+func (f ManifestFormat) pathFor(itemPath string) string {
+	ext := filepath.Ext(itemPath)
+	if ext != ".yaml" && ext != ".yml" {
+		return itemPath
+	}
+	switch f {
+	case FormatJSON, FormatList:
+		return strings.TrimSuffix(itemPath, ext) + ".json"
+	case FormatJSONLines:
+		return strings.TrimSuffix(itemPath, ext) + ".jsonl"
+	default:
+		return itemPath
+	}
+}
+
 // WriteYAML writes the given objects out, serialized as YAML, using the
 // context's OutputRule.  Objects are written as separate documents, separated
 // from each other by `---` (as per the YAML spec).
+//
+// If a different format is requested using WithFormat, objects are written
+// in that format instead, and a ".yaml" (or ".yml") extension on the given
+// path is replaced with one that matches the format.
+//
+// A header given using WithHeader is written as a comment before the first
+// document.
 func (g GenerationContext) WriteYAML(itemPath string, objs []interface{}, options ...*WriteYAMLOptions) error {
-	out, err := g.Open(nil, itemPath)
+	format := FormatYAML
+	var header string
+	for _, option := range options {
+		if option.format != "" {
+			format = option.format
+		}
+		if option.header != "" {
+			header = option.header
+		}
+	}
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if header != "" && format != FormatYAML {
+		return fmt.Errorf("headers can only be written in the %s format, not %s", FormatYAML, format)
+	}
+
+	out, err := g.Open(nil, format.pathFor(itemPath))
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 
-	for _, obj := range objs {
-		yamlContent, err := yamlMarshal(obj, options...)
-		if err != nil {
-			return err
+	content := []byte(YAMLComment(header))
+	switch format {
+	case FormatYAML:
+		for _, obj := range objs {
+			yamlContent, err := yamlMarshal(obj, options...)
+			if err != nil {
+				return err
+			}
+			content = append(content, "---\n"...)
+			content = append(content, yamlContent...)
+		}
+	case FormatJSON, FormatJSONLines:
+		for _, obj := range objs {
+			jsonContent, err := jsonMarshal(obj, format == FormatJSON, options...)
+			if err != nil {
+				return err
+			}
+			content = append(content, jsonContent...)
+		}
+	case FormatList:
+		items := make([]json.RawMessage, len(objs))
+		for i, obj := range objs {
+			items[i], err = jsonMarshal(obj, false, options...)
+			if err != nil {
+				return err
+			}
 		}
-		n, err := out.Write(append([]byte("---\n"), yamlContent...))
+		content, err = encodeJSON(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}, true)
 		if err != nil {
 			return err
 		}
-		if n < len(yamlContent) {
-			return io.ErrShortWrite
-		}
 	}
 
+	n, err := out.Write(content)
+	if err != nil {
+		return err
+	}
+	if n < len(content) {
+		return io.ErrShortWrite
+	}
 	return nil
 }
 
+// jsonMarshal serializes the given object as JSON (followed by a newline),
+// applying any transformations from the given options first.
+func jsonMarshal(o interface{}, indent bool, options ...*WriteYAMLOptions) ([]byte, error) {
+	j, err := json.Marshal(o)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling into JSON: %v", err)
+	}
+
+	// decode numbers as json.Number, so that they're written back out as-is
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+	var jsonObj map[string]interface{}
+	if err := dec.Decode(&jsonObj); err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		if option.transform != nil {
+			if err := option.transform(jsonObj); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return encodeJSON(jsonObj, indent)
+}
+
+// encodeJSON serializes the given object as JSON, followed by a newline,
+// without escaping HTML characters (which are common in descriptions).
+func encodeJSON(o interface{}, indent bool) ([]byte, error) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(o); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
 // yamlMarshal is based on sigs.k8s.io/yaml.Marshal, but allows for transforming the final data before writing.
 func yamlMarshal(o interface{}, options ...*WriteYAMLOptions) ([]byte, error) {
 	j, err := json.Marshal(o)
@@ -205,15 +494,71 @@ func (g GenerationContext) ReadFile(path string) ([]byte, error) {
 	return ioutil.ReadAll(file)
 }
 
+// ReadHeader reads header text (e.g. a license) for generated files from the
+// given boilerplate file, replacing " YEAR" in it with the given year.  An
+// empty path means there's no header.
+func (g GenerationContext) ReadHeader(headerFile, year string) (string, error) {
+	if headerFile == "" {
+		return "", nil
+	}
+	headerBytes, err := g.ReadFile(headerFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(string(headerBytes), " YEAR", " "+year), nil
+}
+
+// YAMLComment turns the given text into a YAML comment, ending in a newline,
+// so that it can be written at the top of a YAML file.  Lines that are
+// already comments are left alone, and Go comment markers are swapped for
+// YAML ones, so that the same boilerplate file can be used for Go code and
+// manifests.  Empty text gives an empty comment.
+func YAMLComment(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "/*") && strings.HasSuffix(text, "*/") {
+		text = strings.TrimSpace(text[2 : len(text)-2])
+	}
+	if text == "" {
+		return ""
+	}
+
+	var out strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case strings.HasPrefix(line, "#"):
+			out.WriteString(line)
+		case strings.HasPrefix(line, "//"):
+			out.WriteString("#" + strings.TrimPrefix(line, "//"))
+		case line == "":
+			out.WriteString("#")
+		default:
+			out.WriteString("# " + line)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
 // ForRoots produces a Runtime to run the given generators against the
 // given packages.  It outputs to /dev/null by default.
 func (g Generators) ForRoots(rootPaths ...string) (*Runtime, error) {
-	roots, err := loader.LoadRoots(rootPaths...)
+	return g.ForRootsContext(context.Background(), rootPaths...)
+}
+
+// ForRootsContext is like ForRoots, but stops loading packages early (with
+// a CanceledError) once the given context is done.
+func (g Generators) ForRootsContext(ctx context.Context, rootPaths ...string) (*Runtime, error) {
+	roots, err := loader.LoadRootsWithConfig(&packages.Config{Context: ctx}, rootPaths...)
+	if cancelErr := canceledError(ctx); cancelErr != nil {
+		return nil, cancelErr
+	}
 	if err != nil {
 		return nil, err
 	}
 	rt := &Runtime{
 		Generators: g,
+		rootPaths:  rootPaths,
 		GenerationContext: GenerationContext{
 			Collector: &markers.Collector{
 				Registry: &markers.Registry{},
@@ -234,36 +579,152 @@ func (g Generators) ForRoots(rootPaths ...string) (*Runtime, error) {
 
 // Run runs the Generators in this Runtime against its packages, printing
 // errors (except type errors, which common result from using TypeChecker with
-// filters), returning true if errors were found.
+// filters), returning true if errors were found (or, when verifying, if any
+// artifacts were out of date).
 func (r *Runtime) Run() bool {
-	// TODO(directxman12): we could make this parallel,
-	// but we'd need to ensure all underlying machinery is threadsafe
+	return r.RunContext(context.Background())
+}
 
+// RunContext is like Run, but stops running Generators once the given
+// context is done, printing a CanceledError.  Artifacts from Generators
+// that had already finished are still written.
+func (r *Runtime) RunContext(ctx context.Context) bool {
 	if r.ErrorWriter == nil {
 		r.ErrorWriter = os.Stderr
 	}
+	if r.DiagnosticsFormat != DiagnosticsText {
+		return r.runWithDiagnostics(ctx)
+	}
 	if len(r.Generators) == 0 {
 		fmt.Fprintln(r.ErrorWriter, "no generators to run")
 		return true
 	}
 
-	hadErrs := false
-	for _, gen := range r.Generators {
-		ctx := r.GenerationContext // make a shallow copy
-		ctx.OutputRule = r.OutputRules.ForGenerator(gen)
+	var verifier *artifactVerifier
+	if r.Verify {
+		verifier = &artifactVerifier{}
+	}
+	written := &writtenArtifacts{}
 
-		// don't pass a typechecker to generators that don't provide a filter
-		// to avoid accidents
-		if _, needsChecking := (*gen).(NeedsTypeChecking); !needsChecking {
-			ctx.Checker = nil
+	hadErrs := false
+	printDiagnostics := func(diags Diagnostics) {
+		for _, diag := range diags {
+			fmt.Fprintln(r.ErrorWriter, diag)
 		}
-
-		if err := (*gen).Generate(&ctx); err != nil {
+		hadErrs = diags.HasErrors() || hadErrs
+	}
+	if err := r.runGenerators(ctx, verifier, written, func(gen *Generator, errs, warnings []error) {
+		for _, err := range errs {
 			fmt.Fprintln(r.ErrorWriter, err)
 			hadErrs = true
 		}
+		printDiagnostics(r.warningDiagnostics(warnings, r.generatorName(gen)))
+	}); err != nil {
+		// don't bother with anything else, since it'll be incomplete anyway
+		fmt.Fprintln(r.ErrorWriter, err)
+		return true
+	}
+
+	if r.Explain {
+		printDiagnostics(r.explanations())
+	}
+	printDiagnostics(r.packageWarnings(nil))
+
+	if verifier != nil && verifier.report(r.ErrorWriter) {
+		hadErrs = true
 	}
 
 	// skip TypeErrors -- they're probably just from partial typechecking in crd-gen
-	return loader.PrintErrors(r.Roots, packages.TypeError) || hadErrs
+	hadErrs = loader.PrintErrors(r.Roots, packages.TypeError) || hadErrs
+	if hadErrs {
+		// don't go looking for stale files if some might not have been generated
+		return true
+	}
+
+	staleDiags := r.handleStaleFiles(written)
+	for _, diag := range staleDiags {
+		fmt.Fprintln(r.ErrorWriter, diag)
+	}
+	return staleDiags.HasErrors()
+}
+
+// runGenerators runs each Generator, either one after another or in
+// parallel, calling done (in the order that the generators are listed in)
+// with any errors that each one returned, and any warnings it reported.  If
+// the given context is done before all of them have finished, it returns a
+// CanceledError (without calling done for the Generators that didn't
+// finish).
+//
+// Artifacts written to disk (or kept in memory) are held on to until their
+// Generator finishes, and discarded if it fails, so that a failure doesn't
+// leave only some of them updated.
+func (r *Runtime) runGenerators(ctx context.Context, verifier *artifactVerifier, written *writtenArtifacts, done func(gen *Generator, errs, warnings []error)) error {
+	if r.Parallel {
+		return r.runParallel(ctx, verifier, written, done)
+	}
+	for _, gen := range r.Generators {
+		genCtx := r.contextFor(gen, verifier, written)
+		var pending *bufferedOutputRule
+		if r.keepsArtifacts(gen, verifier) {
+			pending = &bufferedOutputRule{rule: genCtx.OutputRule}
+			genCtx.OutputRule = pending
+		}
+
+		err := WithContext(*gen).GenerateContext(ctx, &genCtx)
+		if cancelErr := canceledError(ctx); cancelErr != nil {
+			return cancelErr
+		}
+		var errs []error
+		if err != nil {
+			errs = append(errs, err)
+		} else if pending != nil {
+			if err := pending.flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		done(gen, errs, genCtx.warnings.list())
+	}
+	return nil
+}
+
+// keepsArtifacts checks if the given generator's artifacts will be kept,
+// either in files on disk or in memory (as opposed to sent to stdout, or
+// verified).
+func (r *Runtime) keepsArtifacts(gen *Generator, verifier *artifactVerifier) bool {
+	rule := r.OutputRules.ForGenerator(gen)
+	if _, inMemory := rule.(*OutputToMemory); inMemory {
+		return true
+	}
+	_, onDisk := rule.(artifactPather)
+	return onDisk && verifier == nil
+}
+
+// contextFor produces the context to pass to the given generator, writing
+// to a verifier instead of its normal output if one is given, and recording
+// the paths of artifacts written to disk in written.
+func (r *Runtime) contextFor(gen *Generator, verifier *artifactVerifier, written *writtenArtifacts) GenerationContext {
+	ctx := r.GenerationContext // make a shallow copy
+	ctx.OutputRule = r.OutputRules.ForGenerator(gen)
+	ctx.warnings = &warningList{}
+	pather, onDisk := ctx.OutputRule.(artifactPather)
+	if verifier != nil {
+		ctx.OutputRule = verifyingOutputRule{rule: ctx.OutputRule, verifier: verifier}
+	}
+	if onDisk {
+		ctx.OutputRule = trackingOutputRule{rule: ctx.OutputRule, pather: pather, written: written}
+	}
+
+	// don't pass a typechecker to generators that don't provide a filter
+	// to avoid accidents
+	if _, needsChecking := (*gen).(NeedsTypeChecking); !needsChecking {
+		ctx.Checker = nil
+	}
+
+	if r.caches != nil {
+		if r.caches[gen] == nil {
+			r.caches[gen] = &GenerationCache{}
+		}
+		ctx.Cache = r.caches[gen]
+	}
+	return ctx
 }
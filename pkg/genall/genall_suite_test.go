@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+func TestGenAll(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GenAll Suite")
+}
+
+// fakeGenerator writes out a fixed set of config artifacts.
+type fakeGenerator struct {
+	// artifacts maps artifact paths to their contents.
+	artifacts map[string]string
+}
+
+func (fakeGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (g fakeGenerator) Generate(ctx *genall.GenerationContext) error {
+	for path, contents := range g.artifacts {
+		if err := func() error {
+			out, err := ctx.Open(nil, path)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			_, err = out.Write([]byte(contents))
+			return err
+		}(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
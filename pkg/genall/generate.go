@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"context"
+	"fmt"
+)
+
+// GenerationResult is the result of running Generators with Generate.
+type GenerationResult struct {
+	// Artifacts maps the name of each Generator to the artifacts that it
+	// produced, keyed by Artifact.Key.
+	Artifacts map[string]map[string]Artifact
+	// Diagnostics are all the problems that were found, including
+	// warnings.
+	Diagnostics Diagnostics
+}
+
+// Generate runs the Generators in this Runtime against its packages, like
+// Run, but captures their artifacts in memory instead of using OutputRules,
+// and returns all problems found as Diagnostics instead of writing them to
+// ErrorWriter.  Verify and Prune are ignored, since nothing is written to
+// disk.
+//
+// Generators are identified by their names in GeneratorNames, falling back
+// to their Go type names (e.g. "crd.Generator") for those not listed there.
+//
+// The returned error is the Diagnostics, if any of them are errors.  Even
+// then, the result holds the artifacts of the Generators that succeeded.
+func (r *Runtime) Generate() (*GenerationResult, error) {
+	return r.GenerateContext(context.Background())
+}
+
+// GenerateContext is like Generate, but stops running Generators once the
+// given context is done, returning a CanceledError (along with the results
+// of the Generators that had already finished).
+func (r *Runtime) GenerateContext(ctx context.Context) (*GenerationResult, error) {
+	outputs := make(map[*Generator]*OutputToMemory, len(r.Generators))
+	rt := *r // make a shallow copy, so we can swap out the output
+	rt.OutputRules = OutputRules{Default: OutputToNothing, ByGenerator: make(map[*Generator]OutputRule, len(r.Generators))}
+	rt.Verify = false
+	rt.Prune = false
+	for _, gen := range r.Generators {
+		outputs[gen] = &OutputToMemory{}
+		rt.OutputRules.ByGenerator[gen] = outputs[gen]
+	}
+
+	diags, runErr := rt.collectDiagnostics(ctx)
+	res := &GenerationResult{
+		Artifacts:   make(map[string]map[string]Artifact, len(r.Generators)),
+		Diagnostics: diags,
+	}
+	for _, gen := range r.Generators {
+		name := r.generatorName(gen)
+		artifacts := outputs[gen].Artifacts()
+		if len(artifacts) == 0 {
+			continue
+		}
+		if res.Artifacts[name] == nil {
+			res.Artifacts[name] = artifacts
+			continue
+		}
+		for key, artifact := range artifacts {
+			res.Artifacts[name][key] = artifact
+		}
+	}
+
+	if runErr != nil {
+		return res, runErr
+	}
+	if res.Diagnostics.HasErrors() {
+		return res, res.Diagnostics
+	}
+	return res, nil
+}
+
+// generatorName returns the name of the given Generator from GeneratorNames,
+// or its type name if it isn't listed there.
+func (r *Runtime) generatorName(gen *Generator) string {
+	if name, known := r.GeneratorNames[gen]; known {
+		return name
+	}
+	return fmt.Sprintf("%T", *gen)
+}
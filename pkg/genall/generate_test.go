@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// codeGenerator writes an artifact into each root package.
+type codeGenerator struct{}
+
+func (codeGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (codeGenerator) Generate(ctx *genall.GenerationContext) error {
+	for _, root := range ctx.Roots {
+		out, err := ctx.Open(root, "zz_generated.txt")
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write([]byte("package " + root.Name)); err != nil {
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ = Describe("Generating artifacts in memory", func() {
+	var (
+		rt                 *genall.Runtime
+		configGen, codeGen genall.Generator
+		errOut             *bytes.Buffer
+		outputRule         genall.OutputRule
+	)
+
+	BeforeEach(func() {
+		configGen = fakeGenerator{artifacts: map[string]string{
+			"a.yaml": "kind: A\n",
+			"b.yaml": "kind: B\n",
+		}}
+		codeGen = codeGenerator{}
+		var err error
+		rt, err = genall.Generators{&configGen, &codeGen}.ForRoots("./testdata/diagnostics")
+		Expect(err).NotTo(HaveOccurred())
+		rt.GeneratorNames = map[*genall.Generator]string{&configGen: "config"}
+		errOut = &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+		outputRule = genall.OutputToStdout
+		rt.OutputRules = genall.OutputRules{Default: outputRule}
+	})
+
+	It("should return the artifacts of each generator, keyed by name and path", func() {
+		res, err := rt.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Diagnostics).To(BeEmpty())
+		Expect(errOut.String()).To(BeEmpty())
+
+		Expect(res.Artifacts).To(HaveLen(2))
+		Expect(res.Artifacts["config"]).To(HaveLen(2))
+		Expect(res.Artifacts["config"]["a.yaml"].Contents).To(Equal([]byte("kind: A\n")))
+		Expect(res.Artifacts["config"]["a.yaml"].Package).To(BeNil())
+		Expect(res.Artifacts["config"]["b.yaml"].Contents).To(Equal([]byte("kind: B\n")))
+
+		By("identifying unnamed generators by type, and keying package artifacts by import path")
+		codeArtifacts := res.Artifacts["genall_test.codeGenerator"]
+		key := "sigs.k8s.io/controller-tools/pkg/genall/testdata/diagnostics/zz_generated.txt"
+		Expect(codeArtifacts).To(HaveKey(key))
+		Expect(codeArtifacts[key].Package).To(Equal(rt.Roots[0]))
+		Expect(codeArtifacts[key].Path).To(Equal("zz_generated.txt"))
+		Expect(codeArtifacts[key].Contents).To(Equal([]byte("package diagnostics")))
+
+		By("leaving the runtime's own output rules alone")
+		Expect(rt.OutputRules.Default).To(Equal(outputRule))
+	})
+
+	It("should return structured errors, along with the artifacts of generators that succeeded", func() {
+		var failingGen genall.Generator = partialGenerator{fakeGenerator{artifacts: map[string]string{"c.yaml": "kind: C\n"}}}
+		rt.Generators = append(rt.Generators, &failingGen)
+		rt.GeneratorNames[&failingGen] = "failing"
+
+		res, err := rt.Generate()
+		var diags genall.Diagnostics
+		Expect(errors.As(err, &diags)).To(BeTrue())
+		Expect(diags).To(Equal(genall.Diagnostics{{
+			Severity:  genall.SeverityError,
+			Generator: "failing",
+			Message:   "something went wrong",
+		}}))
+		Expect(err).To(MatchError("something went wrong"))
+		Expect(res.Diagnostics).To(Equal(diags))
+
+		Expect(res.Artifacts).To(HaveKey("config"))
+		Expect(res.Artifacts).NotTo(HaveKey("failing"))
+		Expect(errOut.String()).To(BeEmpty())
+	})
+})
@@ -93,7 +93,7 @@ func (optionsSort) Group(def *markers.Definition, _ *markers.DefinitionHelp) str
 
 	switch len(parts) {
 	case 1:
-		if parts[0] == "paths" {
+		if parts[0] == "paths" || parts[0] == "plugin" {
 			return "generic"
 		}
 		return "generators"
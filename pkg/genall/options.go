@@ -17,6 +17,7 @@ limitations under the License.
 package genall
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -33,7 +34,7 @@ var (
 type InputPaths []string
 
 // RegisterOptionsMarkers registers "mandatory" options markers for FromOptions into the given registry.
-// At this point, that's just InputPaths.
+// At this point, that's InputPaths and Plugin.
 func RegisterOptionsMarkers(into *markers.Registry) error {
 	if err := into.Register(InputPathsMarker); err != nil {
 		return err
@@ -42,6 +43,12 @@ func RegisterOptionsMarkers(into *markers.Registry) error {
 	if helpGiver, hasHelp := ((interface{})(InputPaths(nil))).(HasHelp); hasHelp {
 		into.AddHelp(InputPathsMarker, helpGiver.Help())
 	}
+	if err := into.Register(PluginMarker); err != nil {
+		return err
+	}
+	if helpGiver, hasHelp := ((interface{})(Plugin{})).(HasHelp); hasHelp {
+		into.AddHelp(PluginMarker, helpGiver.Help())
+	}
 	return nil
 }
 
@@ -49,6 +56,9 @@ func RegisterOptionsMarkers(into *markers.Registry) error {
 // attempting to produce a full Runtime.  This can be useful if you want to display help without
 // trying to load roots.
 func RegistryFromOptions(optionsRegistry *markers.Registry, options []string) (*markers.Registry, error) {
+	if err := RegisterPlugins(optionsRegistry, options); err != nil {
+		return nil, err
+	}
 	protoRt, err := protoFromOptions(optionsRegistry, options)
 	if err != nil {
 		return nil, err
@@ -66,12 +76,22 @@ func RegistryFromOptions(optionsRegistry *markers.Registry, options []string) (*
 // a) Generators
 // b) OutputRules
 // c) InputPaths
+// d) Plugins (whose generators are registered using RegisterPlugins)
 //
 // The paths specified in InputPaths are loaded as package roots, and the combined with
 // the generators and the specified output rules to produce a runtime that can be run or
 // further modified.  Not default generators are used if none are specified -- you can check
 // the output and rerun for that.
 func FromOptions(optionsRegistry *markers.Registry, options []string) (*Runtime, error) {
+	return FromOptionsContext(context.Background(), optionsRegistry, options)
+}
+
+// FromOptionsContext is like FromOptions, but stops loading packages early
+// (with a CanceledError) once the given context is done.
+func FromOptionsContext(ctx context.Context, optionsRegistry *markers.Registry, options []string) (*Runtime, error) {
+	if err := RegisterPlugins(optionsRegistry, options); err != nil {
+		return nil, err
+	}
 
 	protoRt, err := protoFromOptions(optionsRegistry, options)
 	if err != nil {
@@ -79,10 +99,14 @@ func FromOptions(optionsRegistry *markers.Registry, options []string) (*Runtime,
 	}
 
 	// make the runtime
-	genRuntime, err := protoRt.Generators.ForRoots(protoRt.Paths...)
+	genRuntime, err := protoRt.Generators.ForRootsContext(ctx, protoRt.Paths...)
 	if err != nil {
 		return nil, err
 	}
+	genRuntime.GeneratorNames = make(map[*Generator]string, len(protoRt.GeneratorsByName))
+	for name, gen := range protoRt.GeneratorsByName {
+		genRuntime.GeneratorNames[gen] = name
+	}
 
 	// attempt to figure out what the user wants without a lot of verbose specificity:
 	// if the user specifies a default rule, assume that they probably want to fall back
@@ -147,7 +171,14 @@ func protoFromOptions(optionsRegistry *markers.Registry, options []string) (prot
 			continue
 		case InputPaths:
 			paths = append(paths, val...)
+		case Plugin:
+			// already registered by RegisterPlugins, nothing else to do
 		default:
+			if gen, isPlugin := newPluginGenerator(defn, val); isPlugin {
+				gens = append(gens, &gen)
+				gensByName[defn.Name] = &gen
+				continue
+			}
 			return protoRuntime{}, fmt.Errorf("unknown option marker %q", defn.Name)
 		}
 	}
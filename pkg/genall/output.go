@@ -17,11 +17,14 @@ limitations under the License.
 package genall
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 
 	"sigs.k8s.io/controller-tools/pkg/loader"
 )
@@ -83,6 +86,15 @@ type OutputRule interface {
 	Open(pkg *loader.Package, path string) (io.WriteCloser, error)
 }
 
+// artifactPather is implemented by OutputRules that write each artifact to a
+// well-known location on disk, allowing other machinery (like verification)
+// to find the file that a given artifact would end up in.
+type artifactPather interface {
+	// artifactPath returns the path on disk that the given artifact
+	// would be written to by Open.
+	artifactPath(pkg *loader.Package, itemPath string) (string, error)
+}
+
 // OutputToNothing skips outputting anything.
 var OutputToNothing = outputToNothing{}
 
@@ -107,7 +119,75 @@ func (o OutputToDirectory) Open(_ *loader.Package, itemPath string) (io.WriteClo
 		return nil, err
 	}
 	path := filepath.Join(string(o), itemPath)
-	return os.Create(path)
+	return &fileArtifact{path: path}, nil
+}
+
+func (o OutputToDirectory) artifactPath(_ *loader.Package, itemPath string) (string, error) {
+	return filepath.Join(string(o), itemPath), nil
+}
+
+// OutputToMemory captures artifacts in memory, instead of writing them
+// anywhere.  It must be used as a pointer, and is safe for concurrent use.
+type OutputToMemory struct {
+	artifacts map[string]Artifact
+	mu        sync.Mutex
+}
+
+// Artifact is a single artifact, captured by OutputToMemory.
+type Artifact struct {
+	// Package is the package that the artifact is associated with, or nil
+	// for config (and other artifacts not involved in Go compilation).
+	Package *loader.Package
+	// Path is the path of the artifact, as passed to OutputRule.Open.
+	Path string
+	// Contents are the contents of the artifact.
+	Contents []byte
+}
+
+// Key returns the key of this artifact in the map returned by
+// OutputToMemory.Artifacts: its path, prefixed by its package's import path
+// (and a slash) if it's associated with a package.
+func (a Artifact) Key() string {
+	if a.Package == nil {
+		return a.Path
+	}
+	return a.Package.PkgPath + "/" + a.Path
+}
+
+func (o *OutputToMemory) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	return &memoryArtifact{artifact: Artifact{Package: pkg, Path: itemPath}, output: o}, nil
+}
+
+// Artifacts returns a copy of the artifacts captured so far, keyed by
+// Artifact.Key.  Like with files, the last artifact written with a given
+// path wins.
+func (o *OutputToMemory) Artifacts() map[string]Artifact {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	res := make(map[string]Artifact, len(o.artifacts))
+	for key, artifact := range o.artifacts {
+		res[key] = artifact
+	}
+	return res
+}
+
+// memoryArtifact buffers an artifact, handing it to an OutputToMemory once
+// closed.
+type memoryArtifact struct {
+	bytes.Buffer
+	artifact Artifact
+	output   *OutputToMemory
+}
+
+func (a *memoryArtifact) Close() error {
+	a.artifact.Contents = append([]byte(nil), a.Bytes()...)
+	a.output.mu.Lock()
+	defer a.output.mu.Unlock()
+	if a.output.artifacts == nil {
+		a.output.artifacts = make(map[string]Artifact)
+	}
+	a.output.artifacts[a.artifact.Key()] = a.artifact
+	return nil
 }
 
 // OutputToStdout outputs everything to standard-out, with no separation.
@@ -151,10 +231,105 @@ func (o OutputArtifacts) Open(pkg *loader.Package, itemPath string) (io.WriteClo
 		return o.Code.Open(pkg, itemPath)
 	}
 
+	outPath, err := o.artifactPath(pkg, itemPath)
+	if err != nil {
+		return nil, err
+	}
+	return &fileArtifact{path: outPath}, nil
+}
+
+func (o OutputArtifacts) artifactPath(pkg *loader.Package, itemPath string) (string, error) {
+	if pkg == nil {
+		return o.Config.artifactPath(pkg, itemPath)
+	}
+
+	if o.Code != "" {
+		return o.Code.artifactPath(pkg, itemPath)
+	}
+
 	if len(pkg.CompiledGoFiles) == 0 {
-		return nil, fmt.Errorf("cannot output to a package with no path on disk")
+		return "", fmt.Errorf("cannot output to a package with no path on disk")
 	}
 	outDir := filepath.Dir(pkg.CompiledGoFiles[0])
-	outPath := filepath.Join(outDir, itemPath)
-	return os.Create(outPath)
+	return filepath.Join(outDir, itemPath), nil
+}
+
+// fileArtifact buffers an artifact in memory, writing it to its file on
+// disk once closed.
+type fileArtifact struct {
+	bytes.Buffer
+	path string
+}
+
+func (a *fileArtifact) Close() error {
+	return writeFileIfChanged(a.path, a.Bytes())
+}
+
+// tempFileCounter is used to come up with unique names for temporary files.
+var tempFileCounter uint32
+
+// writeFileIfChanged writes the given contents to the file at the given path,
+// unless it already has exactly those contents, in which case it's left
+// alone (keeping its modification time, so that build tools don't think
+// it's changed).  Contents are written to a temporary file in the same
+// directory first, and then renamed into place, so that the file is never
+// left partially written.
+func writeFileIfChanged(path string, contents []byte) error {
+	// write through symlinks, instead of replacing them
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	existing, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(existing, contents) {
+		return nil
+	}
+
+	// like os.Create, new files get 0666 (before umask), while existing
+	// files keep their permissions
+	perm := os.FileMode(0666)
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(path)
+	var tmp *os.File
+	for {
+		tmpPath := filepath.Join(dir, fmt.Sprintf(".%s.%d-%d.tmp", base, os.Getpid(), atomic.AddUint32(&tempFileCounter, 1)))
+		tmp, err = os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writeAndClose(tmp, contents); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if statErr == nil {
+		// make sure the umask didn't change anything
+		if err := os.Chmod(tmp.Name(), perm); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// writeAndClose writes the given contents to the given file, closing it
+// afterwards.
+func writeAndClose(file *os.File, contents []byte) error {
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
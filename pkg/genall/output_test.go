@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+var _ = Describe("Writing artifacts to disk", func() {
+	var (
+		outDir  string
+		path    string
+		longAgo time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		outDir, err = ioutil.TempDir("", "controller-gen-output")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(outDir, "a.yaml")
+		longAgo = time.Now().Add(-time.Hour).Truncate(time.Second)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(outDir)).To(Succeed())
+	})
+
+	writeExisting := func(contents string, perm os.FileMode) {
+		ExpectWithOffset(1, ioutil.WriteFile(path, []byte(contents), perm)).To(Succeed())
+		ExpectWithOffset(1, os.Chmod(path, perm)).To(Succeed())
+		ExpectWithOffset(1, os.Chtimes(path, longAgo, longAgo)).To(Succeed())
+	}
+	runWith := func(gens ...genall.Generator) bool {
+		rt := &genall.Runtime{
+			OutputRules: genall.OutputRules{Default: genall.OutputToDirectory(outDir)},
+			ErrorWriter: &bytes.Buffer{},
+		}
+		for i := range gens {
+			rt.Generators = append(rt.Generators, &gens[i])
+		}
+		return rt.Run()
+	}
+	dirContents := func() []string {
+		entries, err := os.ReadDir(outDir)
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	It("should leave files with unchanged contents untouched", func() {
+		writeExisting("kind: A\n", 0640)
+		Expect(runWith(fakeGenerator{artifacts: map[string]string{"a.yaml": "kind: A\n"}})).To(BeFalse())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ModTime()).To(Equal(longAgo))
+	})
+
+	It("should replace files with changed contents, keeping their permissions, without leaving temporary files", func() {
+		writeExisting("kind: A\nold: true\n", 0640)
+		Expect(runWith(fakeGenerator{artifacts: map[string]string{"a.yaml": "kind: A\n"}})).To(BeFalse())
+
+		Expect(ioutil.ReadFile(path)).To(Equal([]byte("kind: A\n")))
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ModTime()).NotTo(Equal(longAgo))
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+		Expect(dirContents()).To(ConsistOf("a.yaml"))
+	})
+
+	It("should write through symlinks", func() {
+		target := filepath.Join(outDir, "target.yaml")
+		Expect(ioutil.WriteFile(target, []byte("kind: Old\n"), 0644)).To(Succeed())
+		Expect(os.Symlink(target, path)).To(Succeed())
+
+		Expect(runWith(fakeGenerator{artifacts: map[string]string{"a.yaml": "kind: A\n"}})).To(BeFalse())
+		Expect(ioutil.ReadFile(target)).To(Equal([]byte("kind: A\n")))
+		info, err := os.Lstat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+	})
+
+	It("should not write anything for generators that fail", func() {
+		writeExisting("kind: A\nold: true\n", 0644)
+		Expect(runWith(
+			partialGenerator{fakeGenerator{artifacts: map[string]string{"a.yaml": "kind: A\n", "b.yaml": "kind: B\n"}}},
+		)).To(BeTrue())
+
+		Expect(ioutil.ReadFile(path)).To(Equal([]byte("kind: A\nold: true\n")))
+		Expect(dirContents()).To(ConsistOf("a.yaml"))
+	})
+})
+
+// partialGenerator writes out all its artifacts, but then fails anyway.
+type partialGenerator struct {
+	fakeGenerator
+}
+
+func (g partialGenerator) Generate(ctx *genall.GenerationContext) error {
+	if err := g.fakeGenerator.Generate(ctx); err != nil {
+		return err
+	}
+	return failingGenerator{}.Generate(ctx)
+}
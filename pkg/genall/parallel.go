@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// runParallel runs all generators concurrently, calling done for each
+// generator (in the order that the generators are listed in) once they've
+// all finished.  If the given context is done before then, nothing is
+// written out, and a CanceledError is returned instead.
+func (r *Runtime) runParallel(ctx context.Context, verifier *artifactVerifier, written *writtenArtifacts, done func(gen *Generator, errs, warnings []error)) error {
+	errs := make([]error, len(r.Generators))
+	warnings := make([]*warningList, len(r.Generators))
+	buffers := make([]*bufferedOutputRule, len(r.Generators))
+	keeps := make([]bool, len(r.Generators))
+
+	var wg sync.WaitGroup
+	for i, gen := range r.Generators {
+		genCtx := r.contextFor(gen, verifier, written)
+		warnings[i] = genCtx.warnings
+		keeps[i] = r.keepsArtifacts(gen, verifier)
+		_, perFile := r.OutputRules.ForGenerator(gen).(artifactPather)
+		if !perFile || keeps[i] {
+			// buffer output that might be shared with other generators,
+			// so that it doesn't get interleaved, as well as files, so
+			// that they're only written if the generator succeeds
+			buffers[i] = &bufferedOutputRule{rule: genCtx.OutputRule}
+			genCtx.OutputRule = buffers[i]
+		}
+
+		wg.Add(1)
+		go func(i int, gen *Generator, genCtx GenerationContext) {
+			defer wg.Done()
+			errs[i] = WithContext(*gen).GenerateContext(ctx, &genCtx)
+		}(i, gen, genCtx)
+	}
+	wg.Wait()
+	if cancelErr := canceledError(ctx); cancelErr != nil {
+		return cancelErr
+	}
+
+	for i, gen := range r.Generators {
+		var genErrs []error
+		if buffers[i] != nil && (errs[i] == nil || !keeps[i]) {
+			if err := buffers[i].flush(); err != nil {
+				genErrs = append(genErrs, err)
+			}
+		}
+		if errs[i] != nil {
+			genErrs = append(genErrs, errs[i])
+		}
+		done(gen, genErrs, warnings[i].list())
+	}
+	return nil
+}
+
+// bufferedOutputRule is an OutputRule that holds artifacts in memory until
+// flushed, at which point they're written to the wrapped rule in the order
+// they were opened.
+type bufferedOutputRule struct {
+	rule OutputRule
+
+	artifacts []*bufferedArtifact
+	mu        sync.Mutex
+}
+
+func (o *bufferedOutputRule) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	artifact := &bufferedArtifact{pkg: pkg, itemPath: itemPath}
+	o.artifacts = append(o.artifacts, artifact)
+	return artifact, nil
+}
+
+// flush writes all buffered artifacts to the wrapped rule.
+func (o *bufferedOutputRule) flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, artifact := range o.artifacts {
+		if err := artifact.writeTo(o.rule); err != nil {
+			return err
+		}
+	}
+	o.artifacts = nil
+	return nil
+}
+
+// bufferedArtifact is a single artifact held by a bufferedOutputRule.
+type bufferedArtifact struct {
+	bytes.Buffer
+	pkg      *loader.Package
+	itemPath string
+}
+
+func (a *bufferedArtifact) Close() error {
+	return nil
+}
+
+// writeTo writes this artifact out using the given rule.
+func (a *bufferedArtifact) writeTo(rule OutputRule) error {
+	out, err := rule.Open(a.pkg, a.itemPath)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(a.Bytes()); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
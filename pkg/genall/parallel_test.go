@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// slowGenerator writes some output and then fails, after waiting a bit.
+type slowGenerator struct {
+	delay  time.Duration
+	output string
+}
+
+func (slowGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (g slowGenerator) Generate(ctx *genall.GenerationContext) error {
+	time.Sleep(g.delay)
+	out, err := ctx.Open(nil, "out.txt")
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := out.Write([]byte(g.output + "\n")); err != nil {
+		return err
+	}
+	return errors.New(g.output + " failed")
+}
+
+// sharedOutputRule writes all artifacts to the same buffer.
+type sharedOutputRule struct {
+	buf *bytes.Buffer
+}
+
+func (o sharedOutputRule) Open(_ *loader.Package, _ string) (io.WriteCloser, error) {
+	return nopCloser{o.buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+var _ = Describe("Running generators in parallel", func() {
+	It("should report output and errors in the order the generators were given", func() {
+		// the first generator finishes last
+		var first, second genall.Generator = slowGenerator{delay: 200 * time.Millisecond, output: "first"}, slowGenerator{delay: 100 * time.Millisecond, output: "second"}
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		rt := &genall.Runtime{
+			Generators:  genall.Generators{&first, &second},
+			OutputRules: genall.OutputRules{Default: sharedOutputRule{buf: out}},
+			ErrorWriter: errOut,
+			Parallel:    true,
+		}
+
+		start := time.Now()
+		Expect(rt.Run()).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", 300*time.Millisecond), "generators should have run concurrently")
+
+		Expect(out.String()).To(Equal("first\nsecond\n"))
+		Expect(errOut.String()).To(Equal("first failed\nsecond failed\n"))
+	})
+})
@@ -0,0 +1,679 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var (
+	PluginMarker = markers.Must(markers.MakeDefinition("plugin", markers.DescribesPackage, Plugin{}))
+)
+
+// +controllertools:marker:generateHelp:category=""
+
+// Plugin declares an out-of-process generator plugin.
+//
+// Once declared, a plugin's generator is used like any other, as
+// `<name>[:<options>]`, with its output configured by `output:<name>:...`.
+type Plugin struct {
+	// Name is the name of the plugin's generator.
+	Name string
+	// Path is the plugin executable to run.
+	//
+	// If not specified, `controller-gen-<name>` is looked up on the PATH.
+	Path string `marker:",optional"`
+}
+
+// PluginProtocolVersion is the version of the plugin protocol described by
+// the Plugin* types, which is sent to plugins with each request.
+const PluginProtocolVersion = 1
+
+const (
+	// PluginDescribe asks a plugin to describe itself, responding with a
+	// PluginDescription.
+	PluginDescribe = "describe"
+	// PluginGenerate asks a plugin to generate artifacts for some packages,
+	// responding with a PluginResponse.
+	PluginGenerate = "generate"
+)
+
+// PluginRequest is written (as JSON) to a plugin's standard input.  The
+// plugin writes its response (as JSON) to standard output.  Anything written
+// to standard error is passed along, and exiting with a non-zero status is
+// treated as a failure.
+type PluginRequest struct {
+	// ProtocolVersion is the version of the protocol in use.
+	ProtocolVersion int `json:"protocolVersion"`
+	// Command is what the plugin is being asked to do, either PluginDescribe
+	// or PluginGenerate.
+	Command string `json:"command"`
+
+	// Options are the options passed to the plugin's generator, by argument
+	// name.  Optional arguments that weren't specified are omitted, but
+	// those that were are always included, even if they're zero values.
+	Options map[string]interface{} `json:"options"`
+	// Packages are the root packages to generate artifacts for.
+	Packages []PluginPackage `json:"packages,omitempty"`
+}
+
+// PluginMarkerValues holds the values of all markers on a particular node, by
+// marker name.  Values for markers with multiple arguments are maps from
+// argument name to value (omitting unspecified optional arguments), while
+// values for markers with a single unnamed argument are just that value.
+type PluginMarkerValues map[string][]interface{}
+
+// PluginPackage describes a loaded package, and the markers in it.
+type PluginPackage struct {
+	// ID is the ID of the package, which should be used to refer to it in
+	// artifacts.
+	ID string `json:"id"`
+	// Name is the package's name.
+	Name string `json:"name"`
+	// PkgPath is the package's import path.
+	PkgPath string `json:"pkgPath"`
+	// GoFiles are the absolute paths to the package's Go source files.
+	GoFiles []string `json:"goFiles"`
+	// Markers are the package-level markers.
+	Markers PluginMarkerValues `json:"markers,omitempty"`
+	// Types are the type declarations in the package.
+	Types []PluginType `json:"types,omitempty"`
+}
+
+// PluginType describes a type declaration, and the markers on it.
+type PluginType struct {
+	// Name is the name of the type.
+	Name string `json:"name"`
+	// Doc is the type's Godoc, with markers removed.
+	Doc string `json:"doc,omitempty"`
+	// Markers are the markers on the type.
+	Markers PluginMarkerValues `json:"markers,omitempty"`
+	// Fields are the fields of the type, if it's a struct.
+	Fields []PluginField `json:"fields,omitempty"`
+}
+
+// PluginField describes a struct field, and the markers on it.
+type PluginField struct {
+	// Name is the name of the field, or empty for embedded fields.
+	Name string `json:"name,omitempty"`
+	// Doc is the field's Godoc, with markers removed.
+	Doc string `json:"doc,omitempty"`
+	// Type is the field's type, as written in the source.
+	Type string `json:"type"`
+	// Tag is the field's struct tag.
+	Tag string `json:"tag,omitempty"`
+	// Markers are the markers on the field.
+	Markers PluginMarkerValues `json:"markers,omitempty"`
+}
+
+// PluginDescription is a plugin's response to PluginDescribe.
+type PluginDescription struct {
+	// Help summarizes what the plugin's generator does.
+	Help string `json:"help,omitempty"`
+	// Options are the arguments to the plugin's generator.
+	Options []PluginArgument `json:"options,omitempty"`
+	// Markers are the markers that the plugin uses.  Markers that are
+	// already known (e.g. because they belong to a built-in generator)
+	// keep their existing definitions.
+	Markers []PluginMarkerDefinition `json:"markers,omitempty"`
+}
+
+// PluginMarkerDefinition describes a marker that a plugin uses.
+type PluginMarkerDefinition struct {
+	// Name is the name of the marker, without the leading `+`.
+	Name string `json:"name"`
+	// Target is what the marker is placed on: "package", "type", or "field".
+	Target string `json:"target"`
+	// Category is the category that the marker is listed under in help
+	// (defaulting to the plugin's name).
+	Category string `json:"category,omitempty"`
+	// Help summarizes what the marker does.
+	Help string `json:"help,omitempty"`
+	// Arguments are the marker's arguments.  A single argument with an empty
+	// name is specified directly, as in `+name=value`.
+	Arguments []PluginArgument `json:"arguments,omitempty"`
+}
+
+// PluginArgument describes an argument to a plugin's generator or markers.
+type PluginArgument struct {
+	// Name is the argument's name.
+	Name string `json:"name"`
+	// Type is the argument's type: one of string, int, float64, bool, or
+	// any, or a slice (`[]T`) or map (`map[string]T`) of those.
+	Type string `json:"type"`
+	// Optional indicates that the argument may be omitted.
+	Optional bool `json:"optional,omitempty"`
+	// Help summarizes what the argument does.
+	Help string `json:"help,omitempty"`
+}
+
+// PluginResponse is a plugin's response to PluginGenerate.
+type PluginResponse struct {
+	// Artifacts are written out using the plugin's output rule.
+	Artifacts []PluginArtifact `json:"artifacts,omitempty"`
+	// Errors are reported as generator errors.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PluginArtifact is an artifact produced by a plugin.
+type PluginArtifact struct {
+	// Package is the ID of the package that this artifact belongs to, or
+	// empty if it doesn't belong to a particular package.
+	Package string `json:"package,omitempty"`
+	// Path is the path of the artifact, relative to wherever the output
+	// rule puts things.
+	Path string `json:"path"`
+	// Contents are the contents of the artifact.
+	Contents string `json:"contents"`
+}
+
+// pluginPathTag is the struct tag that records the plugin executable that the
+// options for a plugin's generator belong to.  Plugin options are parsed into
+// structs constructed at runtime, and the first field of each such struct is
+// tagged with this.
+const pluginPathTag = "controllergenplugin"
+
+// pluginNameRE matches valid plugin names.
+var pluginNameRE = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// pluginDescriptions holds the descriptions of the plugins that have been
+// registered, by executable path, so that plugins' generators can use them
+// without asking the plugins to describe themselves again.
+var pluginDescriptions = struct {
+	byPath map[string]PluginDescription
+	mu     sync.Mutex
+}{byPath: make(map[string]PluginDescription)}
+
+// RegisterPlugins runs the `describe` command for each plugin declared in the
+// given options, registering the options for the plugin's generator (and its
+// per-generator output rules) into the given registry, along with their help.
+// Plugins that are already registered are skipped.
+//
+// FromOptions and RegistryFromOptions call this automatically, but it can
+// also be called directly, e.g. to display help for plugins' options.
+func RegisterPlugins(optionsRegistry *markers.Registry, options []string) error {
+	for _, rawOpt := range options {
+		if len(rawOpt) == 0 || rawOpt[0] != '+' {
+			rawOpt = "+" + rawOpt
+		}
+		defn := optionsRegistry.Lookup(rawOpt, markers.DescribesPackage)
+		if defn == nil || defn.Name != PluginMarker.Name {
+			continue
+		}
+		val, err := defn.Parse(rawOpt)
+		if err != nil {
+			return fmt.Errorf("unable to parse option %q: %w", rawOpt[1:], err)
+		}
+		if err := registerPlugin(optionsRegistry, val.(Plugin)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerPlugin registers the options for the given plugin's generator.
+func registerPlugin(optionsRegistry *markers.Registry, plugin Plugin) error {
+	if !pluginNameRE.MatchString(plugin.Name) {
+		return fmt.Errorf("invalid plugin name %q", plugin.Name)
+	}
+	path, err := plugin.executable()
+	if err != nil {
+		return err
+	}
+
+	if existing := optionsRegistry.Lookup("+"+plugin.Name, markers.DescribesPackage); existing != nil && existing.Name == plugin.Name {
+		if existingPath, isPlugin := pluginPathFor(existing.Output); isPlugin && existingPath == path {
+			return nil
+		}
+		return fmt.Errorf("plugin %q conflicts with an existing option of the same name", plugin.Name)
+	}
+
+	var desc PluginDescription
+	if err := callPlugin(context.Background(), plugin.Name, path, PluginRequest{Command: PluginDescribe}, &desc); err != nil {
+		return err
+	}
+	pluginDescriptions.mu.Lock()
+	pluginDescriptions.byPath[path] = desc
+	pluginDescriptions.mu.Unlock()
+
+	pathField := reflect.StructField{
+		Name: "Plugin",
+		Type: reflect.TypeOf(struct{}{}),
+		Tag:  reflect.StructTag(fmt.Sprintf(`%s:%q json:"-"`, pluginPathTag, path)),
+	}
+	defn, fieldHelp, err := pluginDefinition(plugin.Name, markers.DescribesPackage, desc.Options, &pathField)
+	if err != nil {
+		return fmt.Errorf("plugin %q has invalid options: %w", plugin.Name, err)
+	}
+	if err := optionsRegistry.Register(defn); err != nil {
+		return err
+	}
+	optionsRegistry.AddHelp(defn, &markers.DefinitionHelp{
+		DetailedHelp: markers.DetailedHelp{Summary: desc.Help},
+		FieldHelp:    fieldHelp,
+	})
+
+	// make per-generator versions of the default output rules
+	for _, ruleDefn := range optionsRegistry.AllDefinitions() {
+		if ruleDefn.Target != markers.DescribesPackage || strings.Count(ruleDefn.Name, ":") != 1 || !strings.HasPrefix(ruleDefn.Name, "output:") {
+			continue
+		}
+		ruleName, _ := splitOutputRuleOption(ruleDefn.Name)
+		genRuleDefn := *ruleDefn
+		genRuleDefn.Name = fmt.Sprintf("output:%s:%s", plugin.Name, ruleName)
+		if err := optionsRegistry.Register(&genRuleDefn); err != nil {
+			return err
+		}
+		if help := optionsRegistry.HelpFor(ruleDefn); help != nil {
+			optionsRegistry.AddHelp(&genRuleDefn, help)
+		}
+	}
+	return nil
+}
+
+// executable returns the absolute path to the plugin's executable.
+func (p Plugin) executable() (string, error) {
+	path := p.Path
+	if path == "" {
+		var err error
+		path, err = exec.LookPath("controller-gen-" + p.Name)
+		if err != nil {
+			return "", fmt.Errorf("unable to find plugin %q: %w", p.Name, err)
+		}
+	}
+	return filepath.Abs(path)
+}
+
+// pluginPathFor returns the plugin executable that the given options type
+// belongs to, if it's the options type for a plugin's generator.
+func pluginPathFor(optionsType reflect.Type) (string, bool) {
+	if optionsType.Kind() != reflect.Struct || optionsType.NumField() == 0 {
+		return "", false
+	}
+	return optionsType.Field(0).Tag.Lookup(pluginPathTag)
+}
+
+// callPlugin runs the given plugin executable, passing it the given request
+// and decoding its response into resp.
+func callPlugin(ctx context.Context, name, path string, req PluginRequest, resp interface{}) error {
+	req.ProtocolVersion = PluginProtocolVersion
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("unable to encode request for plugin %q: %w", name, err)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(reqJSON)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("plugin %q failed to %s: %w", name, req.Command, err)
+	}
+	if err := json.Unmarshal(out.Bytes(), resp); err != nil {
+		return fmt.Errorf("plugin %q returned an invalid response to %s: %w", name, req.Command, err)
+	}
+	return nil
+}
+
+// pluginDefinition constructs a marker definition with the given arguments,
+// whose values are parsed into a struct type constructed at runtime.  It
+// returns the definition, and the help for its fields.  If extraField is
+// non-nil, it's added as the first field of the struct (without being an
+// argument).
+func pluginDefinition(name string, target markers.TargetType, args []PluginArgument, extraField *reflect.StructField) (*markers.Definition, map[string]markers.DetailedHelp, error) {
+	defn := &markers.Definition{
+		Name:       name,
+		Target:     target,
+		Fields:     make(map[string]markers.Argument, len(args)),
+		FieldNames: make(map[string]string, len(args)),
+		Strict:     true,
+	}
+	fieldHelp := make(map[string]markers.DetailedHelp, len(args))
+
+	if len(args) == 1 && args[0].Name == "" && extraField == nil {
+		arg, argType, err := pluginArgument(args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		defn.Output = argType
+		defn.Fields[""] = arg
+		defn.FieldNames[""] = ""
+		fieldHelp[""] = markers.DetailedHelp{Summary: args[0].Help}
+		return defn, fieldHelp, nil
+	}
+
+	var fields []reflect.StructField
+	if extraField != nil {
+		fields = append(fields, *extraField)
+	}
+	for i, rawArg := range args {
+		if !token.IsIdentifier(rawArg.Name) {
+			return nil, nil, fmt.Errorf("invalid argument name %q", rawArg.Name)
+		}
+		if _, duplicate := defn.Fields[rawArg.Name]; duplicate {
+			return nil, nil, fmt.Errorf("duplicate argument %q", rawArg.Name)
+		}
+		arg, argType, err := pluginArgument(rawArg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fieldName := fmt.Sprintf("Arg%d", i)
+		fields = append(fields, reflect.StructField{
+			Name: fieldName,
+			Type: argType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%q`, rawArg.Name)),
+		})
+		defn.Fields[rawArg.Name] = arg
+		defn.FieldNames[rawArg.Name] = fieldName
+		fieldHelp[fieldName] = markers.DetailedHelp{Summary: rawArg.Help}
+	}
+	defn.Output = reflect.StructOf(fields)
+	return defn, fieldHelp, nil
+}
+
+// pluginArgument converts the given argument description into an Argument
+// and the type that it's parsed into.
+func pluginArgument(rawArg PluginArgument) (markers.Argument, reflect.Type, error) {
+	arg, argType, err := pluginArgumentType(rawArg.Type)
+	if err != nil {
+		return markers.Argument{}, nil, fmt.Errorf("argument %q: %w", rawArg.Name, err)
+	}
+	if rawArg.Optional {
+		arg.Optional = true
+		switch arg.Type {
+		case markers.SliceType, markers.MapType, markers.AnyType:
+			// already nil-able
+		default:
+			// use a pointer, so that we can tell if it was specified
+			arg.Pointer = true
+			argType = reflect.PtrTo(argType)
+		}
+	}
+	return arg, argType, nil
+}
+
+// pluginArgumentType parses the given type description.
+func pluginArgumentType(typ string) (markers.Argument, reflect.Type, error) {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		item, itemType, err := pluginArgumentType(typ[2:])
+		if err != nil {
+			return markers.Argument{}, nil, err
+		}
+		return markers.Argument{Type: markers.SliceType, ItemType: &item}, reflect.SliceOf(itemType), nil
+	case strings.HasPrefix(typ, "map[string]"):
+		item, itemType, err := pluginArgumentType(typ[len("map[string]"):])
+		if err != nil {
+			return markers.Argument{}, nil, err
+		}
+		return markers.Argument{Type: markers.MapType, ItemType: &item}, reflect.MapOf(reflect.TypeOf(""), itemType), nil
+	}
+
+	switch typ {
+	case "string":
+		return markers.Argument{Type: markers.StringType}, reflect.TypeOf(""), nil
+	case "int":
+		return markers.Argument{Type: markers.IntType}, reflect.TypeOf(int(0)), nil
+	case "float64":
+		return markers.Argument{Type: markers.NumberType}, reflect.TypeOf(float64(0)), nil
+	case "bool":
+		return markers.Argument{Type: markers.BoolType}, reflect.TypeOf(false), nil
+	case "any":
+		return markers.Argument{Type: markers.AnyType}, reflect.TypeOf((*interface{})(nil)).Elem(), nil
+	default:
+		return markers.Argument{}, nil, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+// pluginMarkerTargets maps the targets used in plugin marker definitions to
+// their marker target types.
+var pluginMarkerTargets = map[string]markers.TargetType{
+	"package": markers.DescribesPackage,
+	"type":    markers.DescribesType,
+	"field":   markers.DescribesField,
+}
+
+// pluginGenerator runs a plugin as a Generator.
+type pluginGenerator struct {
+	name    string
+	path    string
+	options map[string]interface{}
+	// markers are the markers that the plugin described when it was
+	// registered.
+	markers []PluginMarkerDefinition
+}
+
+// newPluginGenerator constructs the generator for the plugin that the given
+// option definition belongs to, or returns false if the definition isn't for
+// a plugin.
+func newPluginGenerator(defn *markers.Definition, val interface{}) (Generator, bool) {
+	path, isPlugin := pluginPathFor(defn.Output)
+	if !isPlugin {
+		return nil, false
+	}
+	pluginDescriptions.mu.Lock()
+	desc := pluginDescriptions.byPath[path]
+	pluginDescriptions.mu.Unlock()
+	return pluginGenerator{
+		name:    defn.Name,
+		path:    path,
+		options: argumentValues(defn, val).(map[string]interface{}),
+		markers: desc.Markers,
+	}, true
+}
+
+func (g pluginGenerator) RegisterMarkers(into *markers.Registry) error {
+	for _, rawDefn := range g.markers {
+		target, knownTarget := pluginMarkerTargets[rawDefn.Target]
+		if !knownTarget {
+			return fmt.Errorf("plugin %q: marker %q has unknown target %q (expected package, type, or field)", g.name, rawDefn.Name, rawDefn.Target)
+		}
+		if rawDefn.Name == "" || strings.ContainsAny(rawDefn.Name, "+= \t\n") {
+			return fmt.Errorf("plugin %q: invalid marker name %q", g.name, rawDefn.Name)
+		}
+		if existing := into.Lookup("+"+rawDefn.Name, target); existing != nil && existing.Name == rawDefn.Name {
+			continue
+		}
+
+		defn, fieldHelp, err := pluginDefinition(rawDefn.Name, target, rawDefn.Arguments, nil)
+		if err != nil {
+			return fmt.Errorf("plugin %q: marker %q: %w", g.name, rawDefn.Name, err)
+		}
+		if err := into.Register(defn); err != nil {
+			return err
+		}
+		category := rawDefn.Category
+		if category == "" {
+			category = g.name
+		}
+		into.AddHelp(defn, &markers.DefinitionHelp{
+			Category:     category,
+			DetailedHelp: markers.DetailedHelp{Summary: rawDefn.Help},
+			FieldHelp:    fieldHelp,
+		})
+	}
+	return nil
+}
+
+func (g pluginGenerator) Generate(ctx *GenerationContext) error {
+	return g.GenerateContext(context.Background(), ctx)
+}
+
+// GenerateContext runs the plugin, killing it if the given context is done
+// before it finishes.
+func (g pluginGenerator) GenerateContext(goCtx context.Context, ctx *GenerationContext) error {
+	req := PluginRequest{
+		Command: PluginGenerate,
+		Options: g.options,
+	}
+	rootsByID := make(map[string]*loader.Package, len(ctx.Roots))
+	for _, root := range ctx.Roots {
+		rootsByID[root.ID] = root
+		pkg, err := pluginPackageFor(ctx.Collector, root)
+		if err != nil {
+			root.AddError(err)
+			continue
+		}
+		req.Packages = append(req.Packages, pkg)
+	}
+
+	var resp PluginResponse
+	if err := callPlugin(goCtx, g.name, g.path, req, &resp); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, msg := range resp.Errors {
+		errs = append(errs, fmt.Errorf("%s: %s", g.name, msg))
+	}
+	for _, artifact := range resp.Artifacts {
+		var pkg *loader.Package
+		if artifact.Package != "" {
+			pkg = rootsByID[artifact.Package]
+			if pkg == nil {
+				errs = append(errs, fmt.Errorf("plugin %q returned artifact %q for unknown package %q", g.name, artifact.Path, artifact.Package))
+				continue
+			}
+		}
+		if err := writePluginArtifact(ctx, pkg, artifact); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return loader.MaybeErrList(errs)
+}
+
+// writePluginArtifact writes out a single artifact returned by a plugin.
+func writePluginArtifact(ctx *GenerationContext, pkg *loader.Package, artifact PluginArtifact) error {
+	out, err := ctx.Open(pkg, artifact.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(out, artifact.Contents); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// pluginPackageFor describes the given package, and all the markers in it.
+func pluginPackageFor(col *markers.Collector, pkg *loader.Package) (PluginPackage, error) {
+	pkgMarkers, err := markers.PackageMarkers(col, pkg)
+	if err != nil {
+		return PluginPackage{}, err
+	}
+	res := PluginPackage{
+		ID:      pkg.ID,
+		Name:    pkg.Name,
+		PkgPath: pkg.PkgPath,
+		GoFiles: pkg.GoFiles,
+		Markers: pluginMarkerValues(col.Registry, markers.DescribesPackage, pkgMarkers),
+	}
+
+	err = markers.EachType(col, pkg, func(info *markers.TypeInfo) {
+		typ := PluginType{
+			Name:    info.Name,
+			Doc:     info.Doc,
+			Markers: pluginMarkerValues(col.Registry, markers.DescribesType, info.Markers),
+		}
+		for _, field := range info.Fields {
+			typ.Fields = append(typ.Fields, PluginField{
+				Name:    field.Name,
+				Doc:     field.Doc,
+				Type:    types.ExprString(field.RawField.Type),
+				Tag:     string(field.Tag),
+				Markers: pluginMarkerValues(col.Registry, markers.DescribesField, field.Markers),
+			})
+		}
+		res.Types = append(res.Types, typ)
+	})
+	return res, err
+}
+
+// pluginMarkerValues converts the given marker values into their plugin
+// protocol form.
+func pluginMarkerValues(reg *markers.Registry, target markers.TargetType, values markers.MarkerValues) PluginMarkerValues {
+	if len(values) == 0 {
+		return nil
+	}
+	res := make(PluginMarkerValues, len(values))
+	for name, vals := range values {
+		defn := reg.Lookup("+"+name, target)
+		converted := make([]interface{}, len(vals))
+		for i, val := range vals {
+			if defn == nil {
+				converted[i] = val
+				continue
+			}
+			converted[i] = argumentValues(defn, val)
+		}
+		res[name] = converted
+	}
+	return res
+}
+
+// argumentValues converts a parsed marker value into either a map from
+// argument name to value (for markers with named arguments), or the value of
+// its single argument.  Unspecified optional arguments are left out of maps.
+func argumentValues(defn *markers.Definition, val interface{}) interface{} {
+	rawVal := reflect.ValueOf(val)
+	if defn.AnonymousField() {
+		if fieldName := defn.FieldNames[""]; fieldName != "" && rawVal.Kind() == reflect.Struct {
+			return rawVal.FieldByName(fieldName).Interface()
+		}
+		return val
+	}
+	if rawVal.Kind() != reflect.Struct {
+		return val
+	}
+
+	res := make(map[string]interface{}, len(defn.FieldNames))
+	for argName, fieldName := range defn.FieldNames {
+		field := rawVal.FieldByName(fieldName)
+		if !field.IsValid() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			if field.IsNil() {
+				continue
+			}
+		}
+		res[argName] = field.Interface()
+	}
+	return res
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// fakePluginEnv makes the test binary act as a plugin when set, so that it
+// can be used as the plugin executable.
+const fakePluginEnv = "GENALL_TEST_FAKE_PLUGIN"
+
+// fakePluginLogEnv names a file that the fake plugin appends each request's
+// command and options to, if set.
+const fakePluginLogEnv = "GENALL_TEST_FAKE_PLUGIN_LOG"
+
+func init() {
+	if os.Getenv(fakePluginEnv) == "" {
+		return
+	}
+	var req genall.PluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := logFakePluginRequest(req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var resp interface{}
+	switch req.Command {
+	case genall.PluginDescribe:
+		resp = genall.PluginDescription{
+			Help: "generates documentation.",
+			Options: []genall.PluginArgument{
+				{Name: "format", Type: "string", Help: "is the output format."},
+				{Name: "tags", Type: "[]string", Optional: true},
+				{Name: "fail", Type: "bool", Optional: true},
+			},
+			Markers: []genall.PluginMarkerDefinition{
+				{Name: "docs:title", Target: "package", Arguments: []genall.PluginArgument{{Type: "string"}}},
+				{Name: "docs:section", Target: "type", Help: "sets the section for a type.", Arguments: []genall.PluginArgument{{Type: "string"}}},
+				{Name: "docs:hidden", Target: "field"},
+			},
+		}
+	case genall.PluginGenerate:
+		resp = fakePluginGenerate(req)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// logFakePluginRequest appends the given request's command and options to
+// the file named by fakePluginLogEnv, if any.
+func logFakePluginRequest(req genall.PluginRequest) error {
+	logPath := os.Getenv(fakePluginLogEnv)
+	if logPath == "" {
+		return nil
+	}
+	options, err := json.Marshal(req.Options)
+	if err != nil {
+		return err
+	}
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(log, "%s %s\n", req.Command, options); err != nil {
+		log.Close()
+		return err
+	}
+	return log.Close()
+}
+
+// fakePluginGenerate writes out a summary of the request as a single
+// artifact per package.
+func fakePluginGenerate(req genall.PluginRequest) genall.PluginResponse {
+	if req.Options["fail"] == true {
+		return genall.PluginResponse{Errors: []string{"failed as requested"}}
+	}
+	var resp genall.PluginResponse
+	for _, pkg := range req.Packages {
+		var out strings.Builder
+		fmt.Fprintf(&out, "format: %v, tags: %v\n", req.Options["format"], req.Options["tags"])
+		fmt.Fprintf(&out, "# %v\n", pkg.Markers["docs:title"])
+		for _, typ := range pkg.Types {
+			fmt.Fprintf(&out, "## %s (%v): %s\n", typ.Name, typ.Markers["docs:section"], typ.Doc)
+			for _, field := range typ.Fields {
+				if _, hidden := field.Markers["docs:hidden"]; hidden {
+					continue
+				}
+				fmt.Fprintf(&out, "- %s %s `%s`: %s\n", field.Name, field.Type, field.Tag, field.Doc)
+			}
+		}
+		resp.Artifacts = append(resp.Artifacts, genall.PluginArtifact{
+			Package:  pkg.ID,
+			Path:     pkg.Name + ".md",
+			Contents: out.String(),
+		})
+	}
+	return resp
+}
+
+var _ = Describe("Generator plugins", func() {
+	var (
+		reg        *markers.Registry
+		pluginOpt  string
+		outDir     string
+		errOut     *bytes.Buffer
+		runtimeFor func(opts ...string) *genall.Runtime
+	)
+
+	BeforeEach(func() {
+		Expect(os.Setenv(fakePluginEnv, "1")).To(Succeed())
+		pluginPath, err := os.Executable()
+		Expect(err).NotTo(HaveOccurred())
+		pluginOpt = fmt.Sprintf("plugin:name=docs,path=%q", pluginPath)
+
+		reg = &markers.Registry{}
+		Expect(reg.Define("other", markers.DescribesPackage, fakeGenerator{})).To(Succeed())
+		Expect(reg.Define("output:dir", markers.DescribesPackage, genall.OutputToDirectory(""))).To(Succeed())
+		Expect(reg.Define("output:stdout", markers.DescribesPackage, genall.OutputToStdout)).To(Succeed())
+		Expect(genall.RegisterOptionsMarkers(reg)).To(Succeed())
+
+		outDir, err = ioutil.TempDir("", "controller-gen-plugin")
+		Expect(err).NotTo(HaveOccurred())
+		errOut = &bytes.Buffer{}
+		runtimeFor = func(opts ...string) *genall.Runtime {
+			opts = append(opts, pluginOpt, "paths=./testdata/plugin", fmt.Sprintf("output:docs:dir=%q", outDir))
+			rt, err := genall.FromOptions(reg, opts)
+			ExpectWithOffset(1, err).NotTo(HaveOccurred())
+			rt.ErrorWriter = errOut
+			return rt
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv(fakePluginEnv)).To(Succeed())
+		Expect(os.RemoveAll(outDir)).To(Succeed())
+	})
+
+	It("should register the plugin's generator and output rules, with help", func() {
+		Expect(genall.RegisterPlugins(reg, []string{pluginOpt})).To(Succeed())
+
+		defn := reg.Lookup("+docs", markers.DescribesPackage)
+		Expect(defn).NotTo(BeNil())
+		Expect(defn.Fields).To(HaveKey("format"))
+		Expect(defn.Fields["tags"].Optional).To(BeTrue())
+		help := reg.HelpFor(defn)
+		Expect(help).NotTo(BeNil())
+		Expect(help.Summary).To(Equal("generates documentation."))
+		Expect(help.FieldsHelp(defn)["format"].Summary).To(Equal("is the output format."))
+
+		Expect(reg.Lookup("+output:docs:dir", markers.DescribesPackage)).NotTo(BeNil())
+		Expect(reg.Lookup("+output:docs:stdout", markers.DescribesPackage)).NotTo(BeNil())
+
+		By("registering it again, which should be a no-op")
+		Expect(genall.RegisterPlugins(reg, []string{pluginOpt})).To(Succeed())
+		Expect(reg.Lookup("+docs", markers.DescribesPackage)).To(BeIdenticalTo(defn))
+	})
+
+	It("should refuse plugins that conflict with other options", func() {
+		err := genall.RegisterPlugins(reg, []string{strings.Replace(pluginOpt, "name=docs", "name=other", 1)})
+		Expect(err).To(MatchError(`plugin "other" conflicts with an existing option of the same name`))
+	})
+
+	It("should register the plugin's markers, with help", func() {
+		markerReg, err := genall.RegistryFromOptions(reg, []string{pluginOpt, "docs:format=html"})
+		Expect(err).NotTo(HaveOccurred())
+
+		defn := markerReg.Lookup("+docs:section", markers.DescribesType)
+		Expect(defn).NotTo(BeNil())
+		help := markerReg.HelpFor(defn)
+		Expect(help).NotTo(BeNil())
+		Expect(help.Category).To(Equal("docs"))
+		Expect(help.Summary).To(Equal("sets the section for a type."))
+		Expect(markerReg.Lookup("+docs:hidden", markers.DescribesField)).NotTo(BeNil())
+	})
+
+	It("should pass packages, markers, and options to the plugin, and write out its artifacts", func() {
+		Expect(runtimeFor(`docs:format=markdown,tags={a,b}`).Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+
+		contents, err := ioutil.ReadFile(filepath.Join(outDir, "plugin.md"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("format: markdown, tags: [a b]\n" +
+			"# [Widgets]\n" +
+			"## Widget ([Things]): Widget is a thing.\n" +
+			"- Size int `json:\"size\"`: Size is how big it is.\n"))
+	})
+
+	It("should only ask the plugin to describe itself once, and pass along zero-valued options", func() {
+		logPath := filepath.Join(outDir, "requests.log")
+		Expect(os.Setenv(fakePluginLogEnv, logPath)).To(Succeed())
+		defer os.Unsetenv(fakePluginLogEnv)
+
+		Expect(runtimeFor(`docs:format="",fail=false`).Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+
+		requests, err := ioutil.ReadFile(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(requests)).To(Equal("describe null\n" +
+			`generate {"fail":false,"format":""}` + "\n"))
+	})
+
+	It("should report errors from the plugin", func() {
+		Expect(runtimeFor(`docs:format=markdown,fail=true`).Run()).To(BeTrue())
+		Expect(errOut.String()).To(Equal("[docs: failed as requested]\n"))
+	})
+
+	It("should check the plugin's options", func() {
+		_, err := genall.FromOptions(reg, []string{pluginOpt, "docs:formatt=markdown", "paths=./testdata/plugin"})
+		Expect(err).To(MatchError(ContainSubstring(`unknown argument "formatt"`)))
+	})
+
+	It("should allow declaring plugins in configuration files", func() {
+		pluginPath, err := os.Executable()
+		Expect(err).NotTo(HaveOccurred())
+		opts, err := genall.OptionsFromConfig(reg, "cfg.yaml", []byte(fmt.Sprintf(`
+plugins:
+  docs:
+    path: %q
+generators:
+  docs:
+    format: markdown
+    colour: blue
+`, pluginPath)))
+		Expect(err).To(MatchError(`cfg.yaml:8:5: unknown argument "colour" to generator "docs"`))
+		Expect(opts).To(BeNil())
+	})
+})
@@ -0,0 +1,272 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// GeneratedAnnotation is the annotation that generators add to the objects
+// they generate, recording the version of controller-tools that generated
+// them.  Files containing it are considered to be generated, so that they
+// can be cleaned up once they're no longer produced.
+const GeneratedAnnotation = "controller-gen.kubebuilder.io/version"
+
+// trackingOutputRule is an OutputRule that records the paths on disk of the
+// artifacts opened through it, before passing them on to another rule.
+type trackingOutputRule struct {
+	rule    OutputRule
+	pather  artifactPather
+	written *writtenArtifacts
+}
+
+func (o trackingOutputRule) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	path, err := o.pather.artifactPath(pkg, itemPath)
+	if err != nil {
+		return nil, err
+	}
+	o.written.add(path)
+	return o.rule.Open(pkg, itemPath)
+}
+
+// writtenArtifacts is the set of paths that artifacts have been written to
+// (or, when verifying, would have been written to).
+type writtenArtifacts struct {
+	paths map[string]struct{}
+	mu    sync.Mutex
+}
+
+func (w *writtenArtifacts) add(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.paths == nil {
+		w.paths = make(map[string]struct{})
+	}
+	w.paths[absPath(path)] = struct{}{}
+}
+
+func (w *writtenArtifacts) has(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, written := w.paths[absPath(path)]
+	return written
+}
+
+// absPath makes the given path absolute, if possible, so that paths can be
+// compared no matter how they were specified.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// in returns the names (sorted) of the artifacts written directly into the
+// given directory.
+func (w *writtenArtifacts) in(dir string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dir = absPath(dir)
+	var names []string
+	for path := range w.paths {
+		if filepath.Dir(path) == dir {
+			names = append(names, filepath.Base(path))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ownedFiles records which files in an output directory were generated by
+// a particular set of Generators for a particular set of roots, so that
+// pruning never touches files generated by some other invocation that
+// shares the directory (like CRDs for another API group).
+//
+// They're kept in a manifest in the directory itself, named after a hash of
+// the generators and roots.
+type ownedFiles struct {
+	// path is the path to the manifest.
+	path string
+	// owner describes the generators and roots, for the manifest's header.
+	owner string
+}
+
+// ownedFilesIn returns the ownedFiles for the given generators (by name)
+// and roots in the given directory.
+func ownedFilesIn(dir string, generators []string, roots []*loader.Package) ownedFiles {
+	rootIDs := make([]string, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+	sort.Strings(rootIDs)
+	sort.Strings(generators)
+
+	owner := strings.Join(generators, ",") + " " + strings.Join(rootIDs, ",")
+	sum := sha256.Sum256([]byte(owner))
+	return ownedFiles{
+		path:  filepath.Join(dir, fmt.Sprintf(".controller-gen-%x.files", sum[:8])),
+		owner: owner,
+	}
+}
+
+// read returns the names of the files listed in the manifest, if it exists.
+func (o ownedFiles) read() ([]string, error) {
+	contents, err := ioutil.ReadFile(o.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// write replaces the files listed in the manifest with the given names.
+func (o ownedFiles) write(names []string) error {
+	var contents strings.Builder
+	fmt.Fprintf(&contents, "# files generated by controller-gen (%s), used to prune them once they're no longer generated\n", o.owner)
+	for _, name := range names {
+		contents.WriteString(name + "\n")
+	}
+	return writeFileIfChanged(o.path, []byte(contents.String()))
+}
+
+// outputDirs returns the directories that the given generators write
+// config artifacts to, along with the names of the generators writing to
+// each one.
+func (r *Runtime) outputDirs(gens Generators) (map[string][]string, error) {
+	dirs := make(map[string][]string)
+	for _, gen := range gens {
+		pather, onDisk := r.OutputRules.ForGenerator(gen).(artifactPather)
+		if !onDisk {
+			continue
+		}
+		// just ask where some config artifact would go to find the directory
+		path, err := pather.artifactPath(nil, "artifact")
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(path)
+		dirs[dir] = append(dirs[dir], r.generatorName(gen))
+	}
+	return dirs, nil
+}
+
+// staleGeneratedFiles returns (sorted) the files in the given directory
+// that were generated into it by an earlier run with the same generators
+// and roots (according to owned), that still look generated (because they
+// contain GeneratedAnnotation), but weren't written this time around.
+func staleGeneratedFiles(dir string, owned ownedFiles, written *writtenArtifacts) ([]string, error) {
+	names, err := owned.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if written.has(path) {
+			continue
+		}
+		contents, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(contents, []byte(GeneratedAnnotation)) {
+			stale = append(stale, path)
+		}
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// handleStaleFiles removes generated files that the Generators produced
+// on an earlier run (for the same roots), but no longer produce, and
+// returns diagnostics describing what was found (and done).  It does
+// nothing unless r.Prune is set, and when verifying, reports the files
+// that would be removed as errors, instead of removing them.
+//
+// Which files were generated by the earlier run is tracked in a manifest
+// in each output directory (see ownedFiles), so files are only pruned once
+// it's been written by a run with r.Prune set.
+func (r *Runtime) handleStaleFiles(written *writtenArtifacts) Diagnostics {
+	if !r.Prune {
+		return nil
+	}
+	dirs, err := r.outputDirs(r.Generators)
+	if err != nil {
+		return Diagnostics{{Severity: SeverityError, Message: "unable to look for stale generated files: " + err.Error()}}
+	}
+	dirNames := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		dirNames = append(dirNames, dir)
+	}
+	sort.Strings(dirNames)
+
+	var diags Diagnostics
+	for _, dir := range dirNames {
+		owned := ownedFilesIn(dir, dirs[dir], r.Roots)
+		stale, err := staleGeneratedFiles(dir, owned, written)
+		if err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityError, Message: "unable to look for stale generated files: " + err.Error()})
+			continue
+		}
+		// keep track of files we couldn't remove, so we can try again next time
+		stillOwned := written.in(dir)
+		for _, path := range stale {
+			diag := Diagnostic{File: path, Severity: SeverityError, Message: "no longer generated"}
+			if !r.Verify {
+				if err := os.Remove(path); err != nil {
+					diag.Message = "no longer generated, but unable to remove: " + err.Error()
+					stillOwned = append(stillOwned, filepath.Base(path))
+				} else {
+					diag.Severity = SeverityWarning
+					diag.Message = "no longer generated, removed"
+				}
+			}
+			diags = append(diags, diag)
+		}
+		if r.Verify {
+			continue
+		}
+		if err := owned.write(stillOwned); err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityError, Message: "unable to record generated files: " + err.Error()})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+const generatedContents = "kind: A\nmetadata:\n  annotations:\n    controller-gen.kubebuilder.io/version: (devel)\n"
+
+var _ = Describe("Cleaning up stale generated files", func() {
+	var (
+		outDir     string
+		errOut     *bytes.Buffer
+		rt         *genall.Runtime
+		stalePath  string
+		otherPath  string
+		manualPath string
+	)
+
+	runtimeFor := func(root string, artifacts map[string]string) *genall.Runtime {
+		var gen genall.Generator = fakeGenerator{artifacts: artifacts}
+		rt, err := genall.Generators{&gen}.ForRoots(root)
+		Expect(err).NotTo(HaveOccurred())
+		rt.OutputRules = genall.OutputRules{Default: genall.OutputToDirectory(outDir)}
+		rt.GeneratorNames = map[*genall.Generator]string{&gen: "fake"}
+		rt.ErrorWriter = errOut
+		return rt
+	}
+
+	BeforeEach(func() {
+		var err error
+		outDir, err = ioutil.TempDir("", "controller-gen-prune")
+		Expect(err).NotTo(HaveOccurred())
+		errOut = &bytes.Buffer{}
+
+		By("generating some files with pruning, including one that'll go stale")
+		stalePath = filepath.Join(outDir, "removed.yaml")
+		rt = runtimeFor("./testdata/diagnostics", map[string]string{
+			"a.yaml":       generatedContents,
+			"removed.yaml": generatedContents,
+		})
+		rt.Prune = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(stalePath).To(BeAnExistingFile())
+
+		By("generating a file into the same directory for other roots")
+		otherPath = filepath.Join(outDir, "other.yaml")
+		other := runtimeFor("./testdata/unknownmarkers", map[string]string{"other.yaml": generatedContents})
+		other.Prune = true
+		Expect(other.Run()).To(BeFalse())
+		Expect(otherPath).To(BeAnExistingFile())
+
+		manualPath = filepath.Join(outDir, "manual.yaml")
+		Expect(ioutil.WriteFile(manualPath, []byte("kind: Manual\n"), 0644)).To(Succeed())
+
+		errOut.Reset()
+		rt = runtimeFor("./testdata/diagnostics", map[string]string{"a.yaml": generatedContents})
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(outDir)).To(Succeed())
+	})
+
+	It("should leave generated files that are no longer produced alone when not pruning", func() {
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+		Expect(stalePath).To(BeAnExistingFile())
+	})
+
+	It("should not fail because of them when treating warnings as errors", func() {
+		rt.WarningsAsErrors = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("should not fail because of them when verifying", func() {
+		rt.Verify = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("should remove generated files that are no longer produced when pruning, leaving others alone", func() {
+		rt.Prune = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(Equal(stalePath + ": warning: no longer generated, removed\n"))
+		Expect(stalePath).NotTo(BeAnExistingFile())
+		Expect(manualPath).To(BeAnExistingFile())
+		Expect(filepath.Join(outDir, "a.yaml")).To(BeAnExistingFile())
+
+		By("checking that files generated for other roots are left alone")
+		Expect(otherPath).To(BeAnExistingFile())
+
+		By("checking that they're only removed once")
+		errOut.Reset()
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("should leave generated files alone if no earlier run recorded generating them", func() {
+		Expect(ioutil.WriteFile(stalePath, []byte(generatedContents), 0644)).To(Succeed())
+		other := runtimeFor("./testdata/diagnostics", map[string]string{"a.yaml": generatedContents})
+		var gen genall.Generator = fakeGenerator{}
+		other.Generators = append(other.Generators, &gen)
+		other.Prune = true
+		Expect(other.Run()).To(BeFalse())
+		Expect(stalePath).To(BeAnExistingFile())
+	})
+
+	It("should fail on generated files that would be pruned when verifying", func() {
+		rt.Verify = true
+		rt.Prune = true
+		Expect(rt.Run()).To(BeTrue())
+		Expect(errOut.String()).To(Equal(stalePath + ": no longer generated\n"))
+		Expect(stalePath).To(BeAnExistingFile())
+	})
+
+	It("should leave everything alone if generation failed", func() {
+		var failing genall.Generator = failingGenerator{}
+		rt.Generators = append(rt.Generators, &failing)
+		rt.Prune = true
+		Expect(rt.Run()).To(BeTrue())
+		Expect(errOut.String()).To(Equal("something went wrong\n"))
+		Expect(stalePath).To(BeAnExistingFile())
+	})
+
+	It("should report pruned files as warnings in diagnostics", func() {
+		rt.Prune = true
+		rt.DiagnosticsFormat = genall.DiagnosticsJSON
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(MatchJSON(fmt.Sprintf(`{"file": %q, "severity": "warning", "message": "no longer generated, removed"}`, stalePath)))
+	})
+})
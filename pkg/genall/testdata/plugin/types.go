@@ -0,0 +1,31 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +docs:title=Widgets
+
+// Package plugin contains types for testing generator plugins.
+package plugin
+
+// Widget is a thing.
+// +docs:section=Things
+type Widget struct {
+	// Size is how big it is.
+	Size int `json:"size"`
+
+	// Secret isn't documented.
+	// +docs:hidden
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// verifyingOutputRule is an OutputRule that, instead of writing artifacts,
+// renders them in memory so that they can later be compared against the
+// files that the wrapped rule would have written them to.
+type verifyingOutputRule struct {
+	rule     OutputRule
+	verifier *artifactVerifier
+}
+
+func (o verifyingOutputRule) Open(pkg *loader.Package, itemPath string) (io.WriteCloser, error) {
+	pather, onDisk := o.rule.(artifactPather)
+	if !onDisk {
+		// things sent to stdout or to nowhere can't be out of date
+		return nopCloser{ioutil.Discard}, nil
+	}
+	path, err := pather.artifactPath(pkg, itemPath)
+	if err != nil {
+		return nil, err
+	}
+	return &renderedArtifact{path: path, verifier: o.verifier}, nil
+}
+
+// renderedArtifact buffers an artifact, handing it off to an artifactVerifier
+// once closed.
+type renderedArtifact struct {
+	bytes.Buffer
+	path     string
+	verifier *artifactVerifier
+}
+
+func (a *renderedArtifact) Close() error {
+	a.verifier.rendered(a.path, a.Bytes())
+	return nil
+}
+
+// artifactVerifier keeps track of rendered artifacts, and knows how to
+// compare them to their existing versions on disk.
+type artifactVerifier struct {
+	// artifacts maps paths on disk to their rendered contents.
+	// Like with normal file output, the last artifact written to
+	// a given path wins.
+	artifacts map[string][]byte
+	mu        sync.Mutex
+}
+
+// rendered records the freshly-rendered contents of the given path.
+func (v *artifactVerifier) rendered(path string, contents []byte) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.artifacts == nil {
+		v.artifacts = make(map[string][]byte)
+	}
+	v.artifacts[path] = contents
+}
+
+// staleArtifact describes a rendered artifact that doesn't match the file
+// on disk.
+type staleArtifact struct {
+	// path is the path of the file on disk.
+	path string
+	// problem says what's wrong with the file.
+	problem string
+	// diff is a unified diff from the file on disk to the rendered artifact,
+	// if one could be produced.
+	diff string
+}
+
+// staleArtifacts compares each rendered artifact with the file on disk,
+// returning those that are out of date or missing, sorted by path.
+func (v *artifactVerifier) staleArtifacts() []staleArtifact {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	paths := make([]string, 0, len(v.artifacts))
+	for path := range v.artifacts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var stale []staleArtifact
+	for _, path := range paths {
+		expected := v.artifacts[path]
+		actual, err := ioutil.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			stale = append(stale, staleArtifact{
+				path:    path,
+				problem: "missing",
+				diff:    unifiedDiff("/dev/null", path+" (generated)", nil, expected),
+			})
+		case err != nil:
+			stale = append(stale, staleArtifact{
+				path:    path,
+				problem: fmt.Sprintf("unable to verify: %v", err),
+			})
+		case !bytes.Equal(actual, expected):
+			stale = append(stale, staleArtifact{
+				path:    path,
+				problem: "out of date",
+				diff:    unifiedDiff(path+" (on disk)", path+" (generated)", actual, expected),
+			})
+		}
+	}
+	return stale
+}
+
+// report writes a unified diff to the given writer for every rendered
+// artifact that is out of date or missing on disk.  It returns true if any
+// such files were found.
+func (v *artifactVerifier) report(out io.Writer) bool {
+	stale := v.staleArtifacts()
+	for _, artifact := range stale {
+		fmt.Fprintf(out, "%s: %s\n", artifact.path, artifact.problem)
+		fmt.Fprint(out, artifact.diff)
+	}
+	return len(stale) > 0
+}
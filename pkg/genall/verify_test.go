@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+var _ = Describe("Verifying generated artifacts", func() {
+	var (
+		outDir string
+		errOut *bytes.Buffer
+		rt     *genall.Runtime
+	)
+
+	BeforeEach(func() {
+		var err error
+		outDir, err = ioutil.TempDir("", "controller-gen-verify")
+		Expect(err).NotTo(HaveOccurred())
+
+		var gen genall.Generator = fakeGenerator{artifacts: map[string]string{
+			"a.yaml": "kind: A\nspec:\n  one: 1\n  two: 2\n",
+		}}
+		errOut = &bytes.Buffer{}
+		rt = &genall.Runtime{
+			Generators:  genall.Generators{&gen},
+			OutputRules: genall.OutputRules{Default: genall.OutputToDirectory(outDir)},
+			ErrorWriter: errOut,
+			Verify:      true,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(outDir)).To(Succeed())
+	})
+
+	It("should pass when artifacts on disk are up to date", func() {
+		Expect(ioutil.WriteFile(filepath.Join(outDir, "a.yaml"), []byte("kind: A\nspec:\n  one: 1\n  two: 2\n"), 0644)).To(Succeed())
+
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("should fail with a diff when artifacts on disk are out of date, without touching them", func() {
+		path := filepath.Join(outDir, "a.yaml")
+		Expect(ioutil.WriteFile(path, []byte("kind: A\nspec:\n  one: 1\n  two: 3\n"), 0644)).To(Succeed())
+
+		Expect(rt.Run()).To(BeTrue())
+		Expect(errOut.String()).To(Equal(path + ": out of date\n" +
+			"--- " + path + " (on disk)\n" +
+			"+++ " + path + " (generated)\n" +
+			"@@ -1,4 +1,4 @@\n" +
+			" kind: A\n" +
+			" spec:\n" +
+			"   one: 1\n" +
+			"-  two: 3\n" +
+			"+  two: 2\n"))
+
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("kind: A\nspec:\n  one: 1\n  two: 3\n"))
+	})
+
+	It("should fail when artifacts are missing from disk", func() {
+		Expect(rt.Run()).To(BeTrue())
+		Expect(errOut.String()).To(HavePrefix(filepath.Join(outDir, "a.yaml") + ": missing\n"))
+		Expect(errOut.String()).To(ContainSubstring("@@ -0,0 +1,4 @@\n+kind: A\n"))
+		Expect(filepath.Join(outDir, "a.yaml")).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"sync"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// AddWarning reports a problem that doesn't stop the Generator from
+// generating its output, but that the user should still know about.
+// Problems with particular packages (or positions in them) are better added
+// to those packages with loader.Package.AddWarning, so that they're
+// reported along with their position.
+//
+// Warnings are ignored if this context wasn't created by a Runtime.
+func (g GenerationContext) AddWarning(err error) {
+	if g.warnings == nil {
+		return
+	}
+	g.warnings.add(err)
+}
+
+// warningList collects the warnings reported through a GenerationContext.
+type warningList struct {
+	warnings []error
+	mu       sync.Mutex
+}
+
+func (l *warningList) add(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if errList, isList := err.(loader.ErrList); isList {
+		l.warnings = append(l.warnings, errList...)
+		return
+	}
+	l.warnings = append(l.warnings, err)
+}
+
+// list returns the warnings reported so far.
+func (l *warningList) list() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.warnings...)
+}
+
+// warningSeverity returns the severity that warnings should be reported
+// with: SeverityError if r.WarningsAsErrors is set, and SeverityWarning
+// otherwise.
+func (r *Runtime) warningSeverity() Severity {
+	if r.WarningsAsErrors {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// warningDiagnostics converts the warnings reported by the given generator
+// into Diagnostics.
+func (r *Runtime) warningDiagnostics(warnings []error, generator string) Diagnostics {
+	var diags Diagnostics
+	for _, warning := range warnings {
+		for _, diag := range r.diagnosticsFor(warning, generator) {
+			diag.Severity = r.warningSeverity()
+			diags = append(diags, diag)
+		}
+	}
+	return diags
+}
+
+// packageWarnings returns Diagnostics for the warnings recorded against the
+// root packages, attributing them to generators using owners, if given.
+//
+// Warnings recorded against the packages that the roots import (like uses of
+// deprecated markers in k8s.io/api) aren't reported: they're usually not the
+// user's to fix, and would otherwise make WarningsAsErrors unusable.
+func (r *Runtime) packageWarnings(owners errorOwners) Diagnostics {
+	var diags Diagnostics
+	for _, pkg := range r.Roots {
+		for _, warning := range pkg.WarningDetails() {
+			diags = append(diags, packageDiagnostic(pkg, warning, r.warningSeverity(), owners[warning.Error]))
+		}
+	}
+	return diags
+}
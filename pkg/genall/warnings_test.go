@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// warningGenerator reports a warning through its context, and another
+// against the first file of each root.
+type warningGenerator struct{}
+
+func (warningGenerator) RegisterMarkers(_ *markers.Registry) error { return nil }
+
+func (warningGenerator) Generate(ctx *genall.GenerationContext) error {
+	ctx.AddWarning(errors.New("something looks off"))
+	for _, root := range ctx.Roots {
+		root.NeedSyntax()
+		root.AddWarning(loader.ErrFromNode(errors.New("this file looks off"), root.Syntax[0]))
+	}
+	return nil
+}
+
+var oldCountMarker = markers.Must(markers.MakeDefinition("diag:old", markers.DescribesType, 0))
+
+// deprecatedMarkersGenerator collects markers from each root and the
+// packages it imports, like generators that follow references do.
+type deprecatedMarkersGenerator struct{}
+
+func (deprecatedMarkersGenerator) RegisterMarkers(reg *markers.Registry) error {
+	if err := reg.Register(oldCountMarker); err != nil {
+		return err
+	}
+	reg.AddHelp(oldCountMarker, markers.DeprecatedHelp("diag:count", "diagnostics", "is a count."))
+	return nil
+}
+
+func (deprecatedMarkersGenerator) Generate(ctx *genall.GenerationContext) error {
+	for _, root := range ctx.Roots {
+		pkgs := []*loader.Package{root}
+		for _, imported := range root.Imports() {
+			pkgs = append(pkgs, imported)
+		}
+		for _, pkg := range pkgs {
+			if _, err := ctx.Collector.MarkersInPackage(pkg); err != nil {
+				pkg.AddError(err)
+			}
+		}
+	}
+	return nil
+}
+
+var _ = Describe("Warnings", func() {
+	var (
+		rt     *genall.Runtime
+		errOut *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var warningGen genall.Generator = warningGenerator{}
+		var err error
+		rt, err = genall.Generators{&warningGen}.ForRoots("./testdata/diagnostics")
+		Expect(err).NotTo(HaveOccurred())
+		rt.GeneratorNames = map[*genall.Generator]string{&warningGen: "warnings"}
+		errOut = &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+	})
+
+	It("should be printed, without failing the run", func() {
+		Expect(rt.Run()).To(BeFalse())
+		Expect(filepath.ToSlash(errOut.String())).To(MatchRegexp(
+			`^warning: something looks off\n\S*testdata/diagnostics/types.go:18:1: warning: this file looks off\n$`))
+	})
+
+	It("should be printed the same way when generators run in parallel", func() {
+		rt.Parallel = true
+		Expect(rt.Run()).To(BeFalse())
+		Expect(errOut.String()).To(ContainSubstring("warning: something looks off\n"))
+		Expect(errOut.String()).To(ContainSubstring("warning: this file looks off\n"))
+	})
+
+	It("should fail the run when they're treated as errors", func() {
+		rt.WarningsAsErrors = true
+		Expect(rt.Run()).To(BeTrue())
+		Expect(errOut.String()).To(ContainSubstring("something looks off\n"))
+		Expect(errOut.String()).NotTo(ContainSubstring("warning:"))
+	})
+
+	It("should be reported as warning diagnostics, attributed to their generator", func() {
+		res, err := rt.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Diagnostics).To(HaveLen(2))
+
+		By("reporting warnings from the generator itself first")
+		Expect(res.Diagnostics[0]).To(Equal(genall.Diagnostic{
+			Severity:  genall.SeverityWarning,
+			Generator: "warnings",
+			Message:   "something looks off",
+		}))
+
+		By("reporting warnings recorded against packages, with their position")
+		pkgDiag := res.Diagnostics[1]
+		Expect(filepath.ToSlash(pkgDiag.File)).To(HaveSuffix("testdata/diagnostics/types.go"))
+		Expect(pkgDiag.Line).To(Equal(18))
+		Expect(pkgDiag.Severity).To(Equal(genall.SeverityWarning))
+		Expect(pkgDiag.Generator).To(Equal("warnings"))
+		Expect(pkgDiag.Message).To(Equal("this file looks off"))
+	})
+})
+
+var _ = Describe("Warnings about deprecated markers", func() {
+	It("should only be reported for the roots, not the packages they import", func() {
+		var deprecatedGen genall.Generator = deprecatedMarkersGenerator{}
+		rt, err := genall.Generators{&deprecatedGen}.ForRoots("./testdata/deprecatedmarkers")
+		Expect(err).NotTo(HaveOccurred())
+		errOut := &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+		rt.WarningsAsErrors = true
+
+		Expect(rt.Run()).To(BeTrue())
+		Expect(filepath.ToSlash(errOut.String())).To(MatchRegexp(
+			`^\S*testdata/deprecatedmarkers/types.go:\d+:\d+: marker "\+diag:old" is deprecated, use "\+diag:count" instead\n$`))
+	})
+})
@@ -0,0 +1,400 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+// watchSettleTime is how long to wait for things to quiet down after a
+// change before regenerating, since editors and tools often make several
+// changes in quick succession.
+const watchSettleTime = 100 * time.Millisecond
+
+// Watch runs the Generators, and then watches the source files of the root
+// packages, re-running them after each change until stop is closed.
+//
+// Only changed packages (and the packages that import them) are re-loaded,
+// and their cached information is discarded from the Collector, Checker,
+// and each Generator's GenerationCache.  Generators that implement
+// DependsOnlyOnMarkers are only re-run if marker values in the changed
+// packages have changed.  If files are added to or removed from a package,
+// or a package's imports change, all roots are re-loaded from scratch (which
+// requires the Runtime to have been created with ForRoots).
+//
+// Errors from generators are printed, as with Run, and don't stop watching.
+func (r *Runtime) Watch(stop <-chan struct{}) error {
+	if r.ErrorWriter == nil {
+		r.ErrorWriter = os.Stderr
+	}
+	r.caches = make(map[*Generator]*GenerationCache)
+	defer func() { r.caches = nil }()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// start watching first, so that we notice changes made while we're running
+	files, err := r.watchRoots(watcher, nil)
+	if err != nil {
+		return err
+	}
+	r.Run()
+	fingerprints := r.markerFingerprints(r.Roots)
+
+	for {
+		changedPaths, err := waitForChanges(watcher, stop)
+		if err != nil {
+			return err
+		}
+		if changedPaths == nil {
+			// stopped
+			return nil
+		}
+
+		changedPkgs, needsReload := classifyChanges(files, changedPaths)
+		if len(changedPkgs) == 0 && !needsReload {
+			continue
+		}
+
+		if needsReload {
+			fmt.Fprintln(r.ErrorWriter, "package files or imports changed, reloading all packages")
+			if err := r.reload(); err != nil {
+				// maybe the user is in the middle of something, so just keep going
+				fmt.Fprintln(r.ErrorWriter, err)
+				continue
+			}
+			files, err = r.watchRoots(watcher, files)
+			if err != nil {
+				return err
+			}
+			r.Run()
+			fingerprints = r.markerFingerprints(r.Roots)
+			continue
+		}
+
+		invalidated := loader.Invalidate(r.Roots, changedPkgs...)
+		r.invalidate(invalidated)
+
+		markersChanged := false
+		for pkg, fingerprint := range r.markerFingerprints(changedPkgs) {
+			if fingerprints[pkg] != fingerprint {
+				markersChanged = true
+			}
+			fingerprints[pkg] = fingerprint
+		}
+
+		var toRun Generators
+		for _, gen := range r.Generators {
+			if _, onlyMarkers := (*gen).(DependsOnlyOnMarkers); onlyMarkers && !markersChanged {
+				continue
+			}
+			toRun = append(toRun, gen)
+		}
+
+		ids := make([]string, len(changedPkgs))
+		for i, pkg := range changedPkgs {
+			ids[i] = pkg.ID
+		}
+		if len(toRun) == 0 {
+			fmt.Fprintf(r.ErrorWriter, "no generators affected by changes to %s\n", strings.Join(ids, ", "))
+			continue
+		}
+		fmt.Fprintf(r.ErrorWriter, "regenerating after changes to %s\n", strings.Join(ids, ", "))
+		partial := *r // make a shallow copy
+		partial.Generators = toRun
+		partial.Run()
+	}
+}
+
+// invalidate discards cached information about the given packages from
+// everything that might be holding on to it.
+func (r *Runtime) invalidate(pkgs []*loader.Package) {
+	var invalidators []Invalidator
+	if r.Collector != nil {
+		invalidators = append(invalidators, r.Collector)
+	}
+	if r.Checker != nil {
+		invalidators = append(invalidators, r.Checker)
+	}
+	for _, cache := range r.caches {
+		if invalidator, canInvalidate := cache.Value.(Invalidator); canInvalidate {
+			invalidators = append(invalidators, invalidator)
+		}
+	}
+	for _, invalidator := range invalidators {
+		invalidator.Invalidate(pkgs...)
+	}
+}
+
+// reload re-loads the roots from scratch, discarding everything known
+// about them.
+func (r *Runtime) reload() error {
+	if r.rootPaths == nil {
+		return fmt.Errorf("unable to reload packages that weren't loaded with ForRoots")
+	}
+	roots, err := loader.LoadRoots(r.rootPaths...)
+	if err != nil {
+		return err
+	}
+
+	r.Roots = roots
+	if r.Collector != nil {
+		r.Collector = &markers.Collector{Registry: r.Collector.Registry}
+	}
+	if r.Checker != nil {
+		r.Checker = &loader.TypeChecker{NodeFilters: r.Checker.NodeFilters}
+	}
+	r.caches = make(map[*Generator]*GenerationCache)
+	return nil
+}
+
+// watchedFiles keeps track of the Go files in the directories of the root
+// packages.
+type watchedFiles struct {
+	// pkgs maps the files that make up the root packages to their packages.
+	pkgs map[string]*loader.Package
+	// hashes holds hashes of the contents of the files in pkgs, to tell if
+	// they've actually changed.
+	hashes map[string][sha256.Size]byte
+	// others holds the other Go files in the same directories (e.g. those
+	// excluded by build constraints, like generated code).
+	others map[string]struct{}
+}
+
+// dirs returns all the watched directories.
+func (w *watchedFiles) dirs() map[string]struct{} {
+	res := make(map[string]struct{})
+	if w == nil {
+		return res
+	}
+	for file := range w.pkgs {
+		res[filepath.Dir(file)] = struct{}{}
+	}
+	return res
+}
+
+// watchRoots watches the directories containing the files of the root
+// packages, returning the files in those directories.  Directories that were
+// watched previously, but no longer contain root package files, are no longer
+// watched.
+func (r *Runtime) watchRoots(watcher *fsnotify.Watcher, oldFiles *watchedFiles) (*watchedFiles, error) {
+	files := &watchedFiles{
+		pkgs:   make(map[string]*loader.Package),
+		hashes: make(map[string][sha256.Size]byte),
+		others: make(map[string]struct{}),
+	}
+	for _, root := range r.Roots {
+		for _, file := range root.CompiledGoFiles {
+			file, err := filepath.Abs(file)
+			if err != nil {
+				return nil, err
+			}
+			contents, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			files.pkgs[file] = root
+			files.hashes[file] = sha256.Sum256(contents)
+		}
+	}
+
+	oldDirs := oldFiles.dirs()
+	for dir := range files.dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if _, known := files.pkgs[path]; !known && isLoadableSource(path) {
+				files.others[path] = struct{}{}
+			}
+		}
+
+		if _, watched := oldDirs[dir]; watched {
+			delete(oldDirs, dir)
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return nil, err
+		}
+	}
+	for dir := range oldDirs {
+		// the directory might have been removed, in which case it's no longer watched anyway
+		_ = watcher.Remove(dir)
+	}
+	return files, nil
+}
+
+// isLoadableSource checks if the given file could be part of a loaded package
+// (ignoring build constraints).
+func isLoadableSource(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") &&
+		!strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_")
+}
+
+// waitForChanges waits for files to change, returning the (absolute) paths
+// to all files that changed once things settle down.  It returns nil if
+// stop is closed first.
+func waitForChanges(watcher *fsnotify.Watcher, stop <-chan struct{}) ([]string, error) {
+	changed := make(map[string]struct{})
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil, nil
+		case err := <-watcher.Errors:
+			return nil, err
+		case event := <-watcher.Events:
+			path, err := filepath.Abs(event.Name)
+			if err != nil {
+				return nil, err
+			}
+			changed[path] = struct{}{}
+			settled = time.After(watchSettleTime)
+		case <-settled:
+			res := make([]string, 0, len(changed))
+			for path := range changed {
+				res = append(res, path)
+			}
+			sort.Strings(res)
+			return res, nil
+		}
+	}
+}
+
+// classifyChanges figures out which packages have actually changed based on
+// the given changed paths, returning them sorted by ID.  It also figures out
+// if the changes are such that everything needs to be reloaded from scratch
+// (files added or removed, or imports changed).
+func classifyChanges(files *watchedFiles, changedPaths []string) (changed []*loader.Package, needsReload bool) {
+	changedSet := make(map[*loader.Package]struct{})
+	for _, path := range changedPaths {
+		if !isLoadableSource(path) {
+			continue
+		}
+		contents, err := ioutil.ReadFile(path)
+		exists := err == nil
+
+		pkg, known := files.pkgs[path]
+		_, isOther := files.others[path]
+		switch {
+		case known && !exists:
+			// a file was removed from a package
+			return nil, true
+		case known:
+			hash := sha256.Sum256(contents)
+			if hash == files.hashes[path] {
+				// just touched, or saved without changes
+				continue
+			}
+			files.hashes[path] = hash
+			changedSet[pkg] = struct{}{}
+		case isOther && !exists:
+			delete(files.others, path)
+		case !isOther && exists:
+			// a new file, which might be part of a package
+			return nil, true
+		}
+	}
+
+	for pkg := range changedSet {
+		if importsChanged(pkg) {
+			return nil, true
+		}
+		changed = append(changed, pkg)
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].ID < changed[j].ID
+	})
+	return changed, false
+}
+
+// importsChanged checks if the files of the given package on disk import
+// any packages that weren't loaded.  Files that fail to parse are ignored,
+// since they'll produce errors later anyway.
+func importsChanged(pkg *loader.Package) bool {
+	fset := token.NewFileSet()
+	for _, filename := range pkg.CompiledGoFiles {
+		file, err := parser.ParseFile(fset, filename, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, importSpec := range file.Imports {
+			path, err := strconv.Unquote(importSpec.Path.Value)
+			if err != nil || path == "C" {
+				continue
+			}
+			if _, loaded := pkg.Package.Imports[path]; !loaded {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markerFingerprints summarizes all the marker values in each of the given
+// packages, so that we can tell if they've changed.
+func (r *Runtime) markerFingerprints(pkgs []*loader.Package) map[*loader.Package]string {
+	res := make(map[*loader.Package]string, len(pkgs))
+	if r.Collector == nil {
+		return res
+	}
+	for _, pkg := range pkgs {
+		nodeMarkers, err := r.Collector.MarkersInPackage(pkg)
+		if err != nil {
+			res[pkg] = err.Error()
+			continue
+		}
+
+		var values []string
+		for _, markerValues := range nodeMarkers {
+			for name, vals := range markerValues {
+				for _, val := range vals {
+					valJSON, err := json.Marshal(val)
+					if err != nil {
+						valJSON = []byte(fmt.Sprintf("%#v", val))
+					}
+					values = append(values, name+"="+string(valJSON))
+				}
+			}
+		}
+		sort.Strings(values)
+		res[pkg] = strings.Join(values, "\n")
+	}
+	return res
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genall_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var watchMarker = markers.Must(markers.MakeDefinition("watch:value", markers.DescribesPackage, ""))
+
+// typesGenerator records the value of the `Value` constant in each root.
+type typesGenerator struct {
+	runs chan<- string
+}
+
+func (typesGenerator) RegisterMarkers(reg *markers.Registry) error {
+	return reg.Register(watchMarker)
+}
+
+func (typesGenerator) CheckFilter() loader.NodeFilter {
+	return func(ast.Node) bool { return true }
+}
+
+func (g typesGenerator) Generate(ctx *genall.GenerationContext) error {
+	var values []string
+	for _, root := range ctx.Roots {
+		ctx.Checker.Check(root)
+		if root.Types == nil {
+			continue
+		}
+		if value, isConst := root.Types.Scope().Lookup("Value").(*types.Const); isConst {
+			values = append(values, root.Name+"="+value.Val().String())
+		}
+	}
+	sort.Strings(values)
+	g.runs <- "types: " + strings.Join(values, ",")
+	return nil
+}
+
+// markersGenerator records the value of the watch:value marker in each root.
+type markersGenerator struct {
+	runs chan<- string
+}
+
+func (markersGenerator) RegisterMarkers(reg *markers.Registry) error {
+	return reg.Register(watchMarker)
+}
+
+func (markersGenerator) DependsOnlyOnMarkers() {}
+
+func (g markersGenerator) Generate(ctx *genall.GenerationContext) error {
+	var values []string
+	for _, root := range ctx.Roots {
+		pkgMarkers, err := markers.PackageMarkers(ctx.Collector, root)
+		if err != nil {
+			return err
+		}
+		if value := pkgMarkers.Get(watchMarker.Name); value != nil {
+			values = append(values, root.Name+"="+value.(string))
+		}
+	}
+	sort.Strings(values)
+	g.runs <- "markers: " + strings.Join(values, ",")
+	return nil
+}
+
+var _ = Describe("Watching for changes", func() {
+	var (
+		modDir, prevDir string
+		runs            chan string
+		stop            chan struct{}
+		watchErr        chan error
+	)
+
+	writeFile := func(path, contents string) {
+		ExpectWithOffset(1, ioutil.WriteFile(filepath.Join(modDir, path), []byte(contents), 0644)).To(Succeed())
+	}
+	// nextRuns waits for the runs triggered by a single change.
+	nextRuns := func(count int) []string {
+		var res []string
+		for i := 0; i < count; i++ {
+			select {
+			case run := <-runs:
+				res = append(res, run)
+			case <-time.After(10 * time.Second):
+				Fail(fmt.Sprintf("timed out waiting for run %d of %d", i+1, count))
+			}
+		}
+		sort.Strings(res)
+		Consistently(runs, 500*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+		modDir, err = ioutil.TempDir("", "controller-gen-watch")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(modDir, "a"), 0755)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(modDir, "b"), 0755)).To(Succeed())
+		writeFile("go.mod", "module example.com/watched\n\ngo 1.17\n")
+		writeFile("a/a.go", "// +watch:value=one\npackage a\n\nimport \"example.com/watched/b\"\n\nconst Value = b.Value + 1\n")
+		writeFile("b/b.go", "package b\n\nconst Value = 1\n")
+
+		prevDir, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(modDir)).To(Succeed())
+
+		runs = make(chan string, 10)
+		var typesGen genall.Generator = typesGenerator{runs: runs}
+		var markersGen genall.Generator = markersGenerator{runs: runs}
+		rt, err := genall.Generators{&typesGen, &markersGen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		rt.ErrorWriter = &bytes.Buffer{}
+
+		stop = make(chan struct{})
+		watchErr = make(chan error, 1)
+		go func() { watchErr <- rt.Watch(stop) }()
+
+		Expect(nextRuns(2)).To(Equal([]string{"markers: a=one", "types: a=2,b=1"}))
+	})
+
+	AfterEach(func() {
+		close(stop)
+		Eventually(watchErr, 5*time.Second).Should(Receive(BeNil()))
+		Expect(os.Chdir(prevDir)).To(Succeed())
+		Expect(os.RemoveAll(modDir)).To(Succeed())
+	})
+
+	It("should re-check changed packages and the packages that import them", func() {
+		writeFile("b/b.go", "package b\n\nconst Value = 5\n")
+		Expect(nextRuns(1)).To(Equal([]string{"types: a=6,b=5"}))
+	})
+
+	It("should only re-run marker-based generators when markers change", func() {
+		writeFile("a/a.go", "// +watch:value=two\npackage a\n\nimport \"example.com/watched/b\"\n\nconst Value = b.Value + 1\n")
+		Expect(nextRuns(2)).To(Equal([]string{"markers: a=two", "types: a=2,b=1"}))
+	})
+
+	It("should ignore files that were saved without changes", func() {
+		writeFile("b/b.go", "package b\n\nconst Value = 1\n")
+		Consistently(runs, time.Second).ShouldNot(Receive())
+	})
+
+	It("should reload everything when files are added", func() {
+		writeFile("b/c.go", "package b\n\nconst Other = 2\n")
+		Expect(nextRuns(2)).To(Equal([]string{"markers: a=one", "types: a=2,b=1"}))
+		writeFile("b/c.go", "package b\n\nconst Other = 3\n")
+		Expect(nextRuns(1)).To(Equal([]string{"types: a=2,b=1"}))
+	})
+})
@@ -67,6 +67,26 @@ func (OutputToDirectory) Help() *markers.DefinitionHelp {
 	}
 }
 
+func (Plugin) Help() *markers.DefinitionHelp {
+	return &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "declares an out-of-process generator plugin. ",
+			Details: "Once declared, a plugin's generator is used like any other, as `<name>[:<options>]`, with its output configured by `output:<name>:...`.",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Name": {
+				Summary: "is the name of the plugin's generator.",
+				Details: "",
+			},
+			"Path": {
+				Summary: "is the plugin executable to run. ",
+				Details: "If not specified, `controller-gen-<name>` is looked up on the PATH.",
+			},
+		},
+	}
+}
+
 func (outputToNothing) Help() *markers.DefinitionHelp {
 	return &markers.DefinitionHelp{
 		Category: "",
@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// converter converts (structural) CRD schemata to JSON Schema.
+type converter struct {
+	// draft is the version of JSON Schema to convert to.
+	draft Draft
+}
+
+// convert converts the given schema node, and everything below it.
+func (c converter) convert(schema *apiext.JSONSchemaProps) map[string]interface{} {
+	out := make(map[string]interface{})
+	if schema.Title != "" {
+		out["title"] = schema.Title
+	}
+	if schema.Description != "" {
+		out["description"] = schema.Description
+	}
+
+	typ := schema.Type
+	if schema.XIntOrString {
+		// structural schemata may already spell this out
+		if len(schema.AnyOf) == 0 {
+			out["anyOf"] = []interface{}{
+				map[string]interface{}{"type": "integer"},
+				map[string]interface{}{"type": "string"},
+			}
+		}
+		typ = ""
+	}
+	switch {
+	case typ != "" && schema.Nullable:
+		out["type"] = []string{typ, "null"}
+	case typ != "":
+		out["type"] = typ
+	}
+	if schema.Format != "" {
+		out["format"] = schema.Format
+	}
+
+	if len(schema.Enum) > 0 {
+		enum := make([]interface{}, 0, len(schema.Enum)+1)
+		for _, val := range schema.Enum {
+			enum = append(enum, rawJSON(val))
+		}
+		if schema.Nullable {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+	if schema.Default != nil {
+		out["default"] = rawJSON(*schema.Default)
+	}
+	if schema.Example != nil {
+		out["examples"] = []interface{}{rawJSON(*schema.Example)}
+	}
+
+	c.convertBounds(schema, out)
+
+	if len(schema.Required) > 0 {
+		out["required"] = append([]string(nil), schema.Required...)
+	}
+	if len(schema.Properties) > 0 {
+		props := make(map[string]interface{}, len(schema.Properties))
+		for name := range schema.Properties {
+			prop := schema.Properties[name]
+			props[name] = c.convert(&prop)
+		}
+		out["properties"] = props
+	}
+	if schema.XEmbeddedResource {
+		requireTypeMeta(out)
+	}
+	switch {
+	case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		out["additionalProperties"] = c.convert(schema.AdditionalProperties.Schema)
+	case schema.AdditionalProperties != nil:
+		out["additionalProperties"] = schema.AdditionalProperties.Allows
+	case schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields:
+		// unknown fields are allowed by default
+	case out["properties"] != nil && len(schema.AllOf) == 0 && len(schema.AnyOf) == 0 && len(schema.OneOf) == 0:
+		// the apiserver would drop (or reject) unknown fields, so point them
+		// out (unless other schemata might declare them)
+		out["additionalProperties"] = false
+	}
+
+	if schema.Items != nil {
+		switch {
+		case schema.Items.Schema != nil:
+			out["items"] = c.convert(schema.Items.Schema)
+		case len(schema.Items.JSONSchemas) > 0 && c.draft == Draft202012:
+			out["prefixItems"] = c.convertAll(schema.Items.JSONSchemas)
+		case len(schema.Items.JSONSchemas) > 0:
+			out["items"] = c.convertAll(schema.Items.JSONSchemas)
+		}
+	}
+
+	if len(schema.AllOf) > 0 {
+		out["allOf"] = c.convertAll(schema.AllOf)
+	}
+	if len(schema.AnyOf) > 0 {
+		out["anyOf"] = c.convertAll(schema.AnyOf)
+	}
+	if len(schema.OneOf) > 0 {
+		out["oneOf"] = c.convertAll(schema.OneOf)
+	}
+	if schema.Not != nil {
+		out["not"] = c.convert(schema.Not)
+	}
+
+	return out
+}
+
+// convertBounds converts the numeric, length, and size bounds of the given
+// schema node.
+func (c converter) convertBounds(schema *apiext.JSONSchemaProps, out map[string]interface{}) {
+	// OpenAPI v3.0 marks bounds as exclusive, while JSON Schema has separate
+	// exclusive bounds
+	if schema.Maximum != nil && schema.ExclusiveMaximum {
+		out["exclusiveMaximum"] = *schema.Maximum
+	} else if schema.Maximum != nil {
+		out["maximum"] = *schema.Maximum
+	}
+	if schema.Minimum != nil && schema.ExclusiveMinimum {
+		out["exclusiveMinimum"] = *schema.Minimum
+	} else if schema.Minimum != nil {
+		out["minimum"] = *schema.Minimum
+	}
+	if schema.MultipleOf != nil {
+		out["multipleOf"] = *schema.MultipleOf
+	}
+	if schema.Pattern != "" {
+		out["pattern"] = schema.Pattern
+	}
+
+	for name, limit := range map[string]*int64{
+		"maxLength":     schema.MaxLength,
+		"minLength":     schema.MinLength,
+		"maxItems":      schema.MaxItems,
+		"minItems":      schema.MinItems,
+		"maxProperties": schema.MaxProperties,
+		"minProperties": schema.MinProperties,
+	} {
+		if limit != nil {
+			out[name] = *limit
+		}
+	}
+	if schema.UniqueItems {
+		out["uniqueItems"] = true
+	}
+}
+
+// convertAll converts each of the given schema nodes.
+func (c converter) convertAll(schemata []apiext.JSONSchemaProps) []interface{} {
+	res := make([]interface{}, len(schemata))
+	for i := range schemata {
+		res[i] = c.convert(&schemata[i])
+	}
+	return res
+}
+
+// requireTypeMeta makes sure the given (converted) object schema has string
+// apiVersion and kind fields, and that they're required, as the apiserver
+// does for embedded resources.
+func requireTypeMeta(out map[string]interface{}) {
+	props, _ := out["properties"].(map[string]interface{})
+	if props == nil {
+		props = make(map[string]interface{})
+		out["properties"] = props
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if _, exists := props[name]; !exists {
+			props[name] = map[string]interface{}{"type": "string"}
+		}
+	}
+	if _, exists := props["metadata"]; !exists {
+		props["metadata"] = map[string]interface{}{"type": "object"}
+	}
+	addRequired(out, "apiVersion", "kind")
+}
+
+// injectTypeMeta constrains the apiVersion and kind of the given (converted)
+// root schema to the given values, and requires them.
+func injectTypeMeta(doc map[string]interface{}, apiVersion, kind string) {
+	requireTypeMeta(doc)
+	props := doc["properties"].(map[string]interface{})
+	for name, val := range map[string]string{"apiVersion": apiVersion, "kind": kind} {
+		prop, _ := props[name].(map[string]interface{})
+		if prop == nil {
+			prop = map[string]interface{}{"type": "string"}
+			props[name] = prop
+		}
+		prop["const"] = val
+	}
+}
+
+// addRequired marks the given fields as required in the given (converted)
+// object schema, if they aren't already.
+func addRequired(out map[string]interface{}, names ...string) {
+	required, _ := out["required"].([]string)
+	for _, name := range names {
+		alreadyRequired := false
+		for _, existing := range required {
+			if existing == name {
+				alreadyRequired = true
+				break
+			}
+		}
+		if !alreadyRequired {
+			required = append(required, name)
+		}
+	}
+	out["required"] = required
+}
+
+// rawJSON returns the given JSON value so that it's written out as-is.
+func rawJSON(val apiext.JSON) json.RawMessage {
+	return json.RawMessage(val.Raw)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonschema exports the schemata of CRDs generated from Go types as
+// standalone JSON Schema documents, for validating custom resources in
+// editors (e.g. with yaml-language-server) and other tools that don't
+// understand CRDs.
+//
+// A document is written for each served version of each kind, named
+// <group>_<version>_<kind>.json.  OpenAPI and Kubernetes-specific parts of
+// the schema are translated into their JSON Schema equivalents:
+//
+// - nullable fields also accept null.
+//
+// - x-kubernetes-int-or-string fields accept either an integer or a string.
+//
+// - objects accept unknown fields only if they'd be preserved by the
+// apiserver (x-kubernetes-preserve-unknown-fields), since others would be
+// dropped (or rejected).
+//
+// - embedded resources (x-kubernetes-embedded-resource) must have an
+// apiVersion and kind.
+//
+// The apiVersion and kind at the root are constrained to the ones for the
+// document, so that a schema catalog can tell which document applies to a
+// given custom resource.
+package jsonschema
+
+import (
+	"fmt"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+)
+
+// Draft is a version of the JSON Schema specification.
+type Draft string
+
+const (
+	// Draft07 is draft-07 of JSON Schema.  It's the default, since it's the
+	// most widely supported.
+	Draft07 Draft = "draft-07"
+	// Draft202012 is the 2020-12 version of JSON Schema.
+	Draft202012 Draft = "2020-12"
+)
+
+// metaSchema returns the URI of the meta-schema for this draft.
+func (d Draft) metaSchema() (string, error) {
+	switch d {
+	case "", Draft07:
+		return "http://json-schema.org/draft-07/schema#", nil
+	case Draft202012:
+		return "https://json-schema.org/draft/2020-12/schema", nil
+	default:
+		return "", fmt.Errorf("unknown JSON Schema draft %q (expected %s or %s)", d, Draft07, Draft202012)
+	}
+}
+
+// +controllertools:marker:generateHelp
+
+// Generator exports the schema for each served version of each kind as a
+// standalone JSON Schema document.
+type Generator struct {
+	// Draft is the version of JSON Schema to write: draft-07 (the default)
+	// or 2020-12.
+	Draft Draft `marker:",optional"`
+
+
+	// ParserOptions are the options of the crd generator that affect the
+	// schemata that documents are made from.
+	crdgen.ParserOptions
+}
+
+var _ genall.Generator = &Generator{}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	metaSchema, err := g.Draft.metaSchema()
+	if err != nil {
+		return err
+	}
+
+	parser := g.ParserForRoots(ctx)
+	for _, groupKind := range crdgen.FindKubeKindsInRoots(parser, ctx.Roots) {
+		parser.NeedCRDFor(groupKind, nil)
+		crd, wasGenerated := parser.CustomResourceDefinitions[groupKind]
+		if !wasGenerated {
+			continue
+		}
+
+		for _, ver := range crd.Spec.Versions {
+			if !ver.Served || ver.Schema == nil || ver.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			conv := converter{draft: g.Draft}
+			doc := conv.convert(ver.Schema.OpenAPIV3Schema)
+			doc["$schema"] = metaSchema
+			doc["title"] = fmt.Sprintf("%s (%s/%s)", crd.Spec.Names.Kind, crd.Spec.Group, ver.Name)
+			injectTypeMeta(doc, crd.Spec.Group+"/"+ver.Name, crd.Spec.Names.Kind)
+
+			fileName := fmt.Sprintf("%s_%s_%s.json", crd.Spec.Group, ver.Name, crd.Spec.Names.Singular)
+			if err := ctx.WriteYAML(fileName, []interface{}{doc}, genall.WithFormat(genall.FormatJSON)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
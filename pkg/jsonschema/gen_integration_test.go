@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	. "sigs.k8s.io/controller-tools/pkg/jsonschema"
+)
+
+const widgetFile = "jsonschema.testdata.kubebuilder.io_v1_widget.json"
+
+var _ = Describe("JSON Schema Export From Parsing to Writing", func() {
+	It("should export a draft-07 document for each version of each kind", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var schemaGen genall.Generator = &Generator{}
+		rt, err := genall.Generators{&schemaGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+		By("checking that the expected and actual documents are identical")
+		actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, widgetFile))
+		Expect(err).NotTo(HaveOccurred())
+		expectedContents, err := ioutil.ReadFile(filepath.Join("expected", widgetFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actualContents)).To(Equal(string(expectedContents)), "contents not as expected, check pkg/jsonschema/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(string(actualContents), string(expectedContents)))
+	})
+
+	It("should export 2020-12 documents when asked", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var schemaGen genall.Generator = &Generator{Draft: Draft202012}
+		rt, err := genall.Generators{&schemaGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+		By("checking the meta-schema of the document")
+		contents, err := ioutil.ReadFile(filepath.Join(outputDir, widgetFile))
+		Expect(err).NotTo(HaveOccurred())
+		var doc map[string]interface{}
+		Expect(json.Unmarshal(contents, &doc)).To(Succeed())
+		Expect(doc).To(HaveKeyWithValue("$schema", "https://json-schema.org/draft/2020-12/schema"))
+	})
+
+	It("should reject unknown drafts", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var schemaGen genall.Generator = &Generator{Draft: "draft-04"}
+		rt, err := genall.Generators{&schemaGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeTrue(), "unexpectedly succeeded")
+	})
+})
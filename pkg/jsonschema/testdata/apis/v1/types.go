@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +kubebuilder:object:root=true
+
+// Widget is a kind to export the schema of.
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WidgetSpec `json:"spec"`
+}
+
+// WidgetSpec is the spec for a Widget.
+type WidgetSpec struct {
+	// Size is the size of the widget.
+	// +kubebuilder:validation:Enum=small;large
+	// +kubebuilder:default=small
+	Size string `json:"size"`
+
+	// Replicas is the number of widgets.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:validation:ExclusiveMaximum=true
+	Replicas int32 `json:"replicas"`
+
+	// MaxUnavailable is the number or percentage of widgets that can be
+	// unavailable.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// Owner is who owns the widget, if anyone.
+	// +nullable
+	// +optional
+	Owner *string `json:"owner,omitempty"`
+
+	// Template is the object to create for each widget.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	// +optional
+	Template runtime.RawExtension `json:"template,omitempty"`
+
+	// Config is free-form configuration.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	// +optional
+	Config runtime.RawExtension `json:"config,omitempty"`
+
+	// Tags are tags for the widget.
+	// +kubebuilder:validation:MaxItems=5
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// Labels are extra labels.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WidgetList contains a list of Widget.
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
@@ -28,6 +28,11 @@ type PositionedError struct {
 	error
 }
 
+// Unwrap returns the underlying error.
+func (e PositionedError) Unwrap() error {
+	return e.error
+}
+
 // Node is the intersection of go/ast.Node and go/types.Var.
 type Node interface {
 	Pos() token.Pos // position of first character belonging to the node
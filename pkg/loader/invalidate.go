@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Invalidate marks the given packages as having changed on disk, discarding
+// their parsed syntax, type-checking information, and any errors added since
+// they were loaded, so that they're re-read the next time they're needed.
+//
+// Type-checking information for packages that import a changed package
+// refers to its old types, so every package that (transitively) imports one
+// of the changed packages, as found by traversing the import graph from the
+// given roots, is invalidated as well.  All invalidated packages are
+// returned, sorted by ID.
+//
+// Invalidate assumes that the set of files in each package, and the set of
+// packages that they import, haven't changed -- if they have, the packages
+// need to be loaded from scratch with LoadRoots.
+func Invalidate(roots []*Package, changed ...*Package) []*Package {
+	// true if invalidated, false if not (or still being visited)
+	visited := make(map[*Package]bool)
+	for _, pkg := range changed {
+		visited[pkg] = true
+	}
+
+	var visit func(pkg *Package) bool
+	visit = func(pkg *Package) bool {
+		if invalid, seen := visited[pkg]; seen {
+			return invalid
+		}
+		visited[pkg] = false
+		invalid := false
+		for _, imported := range pkg.Imports() {
+			if visit(imported) {
+				invalid = true
+			}
+		}
+		visited[pkg] = invalid
+		return invalid
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	var res []*Package
+	for pkg, invalid := range visited {
+		if !invalid {
+			continue
+		}
+		pkg.invalidate()
+		res = append(res, pkg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// invalidate discards all lazily-loaded information about this package.
+func (p *Package) invalidate() {
+	p.syntaxMu.Lock()
+	p.Syntax = nil
+	p.syntaxMu.Unlock()
+
+	p.typesMu.Lock()
+	p.Types = nil
+	p.TypesInfo = nil
+	p.IllTyped = false
+	p.typesMu.Unlock()
+
+	p.errorsMu.Lock()
+	p.Errors = append([]packages.Error(nil), p.Errors[:p.loadErrors]...)
+	p.errorCauses = nil
+	p.warnings = nil
+	p.warningCauses = nil
+	p.errorsMu.Unlock()
+}
+
+// Invalidate discards the record of the given packages having been checked
+// (as after calling the package-level Invalidate), so that they'll be
+// checked again by the next call to Check.
+func (c *TypeChecker) Invalidate(pkgs ...*Package) {
+	c.init()
+	c.Lock()
+	defer c.Unlock()
+	for _, pkg := range pkgs {
+		delete(c.checkedPackages, pkg)
+	}
+}
@@ -27,6 +27,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"golang.org/x/tools/go/packages"
@@ -53,7 +56,7 @@ func PrintErrors(pkgs []*Package, filterKinds ...packages.ErrorKind) bool {
 	}
 	hadErrors := false
 	packages.Visit(pkgsRaw, nil, func(pkgRaw *packages.Package) {
-		for _, err := range pkgRaw.Errors {
+		for _, err := range sortedErrors(pkgRaw.Errors) {
 			if _, skip := toSkip[err.Kind]; skip {
 				continue
 			}
@@ -64,12 +67,74 @@ func PrintErrors(pkgs []*Package, filterKinds ...packages.ErrorKind) bool {
 	return hadErrors
 }
 
+// sortedErrors returns a copy of the given errors sorted by position (and
+// then message), so that errors are reported in the same order no matter
+// what order they were found in.  Errors that were recorded more than once
+// (e.g. when several generators, or several runs in watch mode, run into the
+// same problem) are only returned once.
+func sortedErrors(errs []packages.Error) []packages.Error {
+	res := make([]packages.Error, 0, len(errs))
+	seen := make(map[packages.Error]struct{}, len(errs))
+	for _, err := range errs {
+		if _, isDup := seen[err]; isDup {
+			continue
+		}
+		seen[err] = struct{}{}
+		res = append(res, err)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		iFile, iLine, iCol := splitErrorPos(res[i].Pos)
+		jFile, jLine, jCol := splitErrorPos(res[j].Pos)
+		switch {
+		case iFile != jFile:
+			return iFile < jFile
+		case iLine != jLine:
+			return iLine < jLine
+		case iCol != jCol:
+			return iCol < jCol
+		default:
+			return res[i].Msg < res[j].Msg
+		}
+	})
+	return res
+}
+
+// splitErrorPos splits a packages.Error position of the form file:line:col
+// (where line and col are optional) into its component parts.
+func splitErrorPos(pos string) (file string, line, col int) {
+	file = pos
+	// pull numbers off the end, column first if both are present
+	var nums []int
+	for len(nums) < 2 {
+		sep := strings.LastIndex(file, ":")
+		if sep < 0 {
+			break
+		}
+		num, err := strconv.Atoi(file[sep+1:])
+		if err != nil {
+			break
+		}
+		nums = append(nums, num)
+		file = file[:sep]
+	}
+	switch len(nums) {
+	case 1:
+		line = nums[0]
+	case 2:
+		line, col = nums[1], nums[0]
+	}
+	return file, line, col
+}
+
 // Package is a single, unique Go package that can be
 // lazily parsed and type-checked.  Packages should not
 // be constructed directly -- instead, use LoadRoots.
 // For a given call to LoadRoots, only a single instance
 // of each package exists, and thus they may be used as keys
 // and for comparison.
+//
+// Loading syntax and type information, and adding errors and warnings,
+// are safe to do concurrently.
 type Package struct {
 	*packages.Package
 
@@ -77,11 +142,32 @@ type Package struct {
 
 	loader *loader
 	sync.Mutex
+
+	// importsMu guards lazy initialization of imports.
+	importsMu sync.Mutex
+	// syntaxMu guards loading Syntax.
+	syntaxMu sync.Mutex
+	// typesMu guards type-checking (Types, TypesInfo, and IllTyped).
+	typesMu sync.Mutex
+	// errorsMu guards Errors, errorCauses, warnings, and warningCauses.
+	errorsMu sync.Mutex
+	// loadErrors is the number of errors that were present when the
+	// package was loaded (as opposed to added by AddError).
+	loadErrors int
+	// errorCauses maps errors recorded by AddError to the errors that they
+	// were recorded from.
+	errorCauses map[packages.Error]error
+	// warnings are the warnings recorded by AddWarning, and warningCauses
+	// the errors that they were recorded from.
+	warnings      []packages.Error
+	warningCauses map[packages.Error]error
 }
 
 // Imports returns the imports for the given package, indexed by
 // package path (*not* name in any particular file).
 func (p *Package) Imports() map[string]*Package {
+	p.importsMu.Lock()
+	defer p.importsMu.Unlock()
 	if p.imports == nil {
 		p.imports = p.loader.packagesFor(p.Package.Imports)
 	}
@@ -92,6 +178,8 @@ func (p *Package) Imports() map[string]*Package {
 // NeedTypesInfo indicates that type-checking information is needed for this package.
 // Actual type-checking information can be accessed via the Types and TypesInfo fields.
 func (p *Package) NeedTypesInfo() {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
 	if p.TypesInfo != nil {
 		return
 	}
@@ -99,9 +187,19 @@ func (p *Package) NeedTypesInfo() {
 	p.loader.typeCheck(p)
 }
 
+// FileSet returns the file set used for this package's syntax, which
+// positions in its ASTs (and PositionedErrors about them) refer to.  Unlike
+// the Fset field, it's available even if the package hasn't been
+// type-checked.
+func (p *Package) FileSet() *token.FileSet {
+	return p.loader.cfg.Fset
+}
+
 // NeedSyntax indicates that a parsed AST is needed for this package.
 // Actual ASTs can be accessed via the Syntax field.
 func (p *Package) NeedSyntax() {
+	p.syntaxMu.Lock()
+	defer p.syntaxMu.Unlock()
 	if p.Syntax != nil {
 		return
 	}
@@ -134,50 +232,151 @@ func (p *Package) NeedSyntax() {
 
 // AddError adds an error to the errors associated with the given package.
 func (p *Package) AddError(err error) {
+	if errList, isList := err.(ErrList); isList {
+		// don't hold the lock while recursing
+		for _, subErr := range errList {
+			p.AddError(subErr)
+		}
+		return
+	}
+
+	p.errorsMu.Lock()
+	defer p.errorsMu.Unlock()
+
+	p.convertError(err, p.recordError)
+}
+
+// AddWarning adds a warning to the warnings associated with the given
+// package.  Warnings describe problems that don't stop anything from being
+// generated, like the use of deprecated markers, and are converted just like
+// errors passed to AddError.
+func (p *Package) AddWarning(err error) {
+	if errList, isList := err.(ErrList); isList {
+		// don't hold the lock while recursing
+		for _, subErr := range errList {
+			p.AddWarning(subErr)
+		}
+		return
+	}
+
+	p.errorsMu.Lock()
+	defer p.errorsMu.Unlock()
+
+	p.convertError(err, p.recordWarning)
+}
+
+// convertError converts the given (non-list) error into packages.Errors,
+// passing each one to record along with the error it was converted from.
+func (p *Package) convertError(err error, record func(err packages.Error, cause error)) {
 	switch typedErr := err.(type) {
 	case *os.PathError:
 		// file-reading errors
-		p.Errors = append(p.Errors, packages.Error{
+		record(packages.Error{
 			Pos:  typedErr.Path + ":1",
 			Msg:  typedErr.Err.Error(),
 			Kind: packages.ParseError,
-		})
+		}, typedErr)
 	case scanner.ErrorList:
 		// parsing/scanning errors
 		for _, subErr := range typedErr {
-			p.Errors = append(p.Errors, packages.Error{
+			record(packages.Error{
 				Pos:  subErr.Pos.String(),
 				Msg:  subErr.Msg,
 				Kind: packages.ParseError,
-			})
+			}, subErr)
 		}
 	case types.Error:
 		// type-checking errors
-		p.Errors = append(p.Errors, packages.Error{
+		record(packages.Error{
 			Pos:  typedErr.Fset.Position(typedErr.Pos).String(),
 			Msg:  typedErr.Msg,
 			Kind: packages.TypeError,
-		})
-	case ErrList:
-		for _, subErr := range typedErr {
-			p.AddError(subErr)
-		}
+		}, typedErr)
 	case PositionedError:
-		p.Errors = append(p.Errors, packages.Error{
+		record(packages.Error{
 			Pos:  p.loader.cfg.Fset.Position(typedErr.Pos).String(),
 			Msg:  typedErr.Error(),
 			Kind: packages.UnknownError,
-		})
+		}, typedErr)
 	default:
 		// should only happen for external errors, like ref checking
-		p.Errors = append(p.Errors, packages.Error{
+		record(packages.Error{
 			Pos:  p.ID + ":-",
 			Msg:  err.Error(),
 			Kind: packages.UnknownError,
-		})
+		}, err)
+	}
+}
+
+// recordError appends the given error, remembering the error that it was
+// recorded from (unless the same error was already recorded from something
+// else).  errorsMu must be held.
+func (p *Package) recordError(err packages.Error, cause error) {
+	p.Errors = append(p.Errors, err)
+	if p.errorCauses == nil {
+		p.errorCauses = make(map[packages.Error]error)
+	}
+	if _, known := p.errorCauses[err]; !known {
+		p.errorCauses[err] = cause
 	}
 }
 
+// recordWarning is like recordError, but for warnings.
+func (p *Package) recordWarning(err packages.Error, cause error) {
+	p.warnings = append(p.warnings, err)
+	if p.warningCauses == nil {
+		p.warningCauses = make(map[packages.Error]error)
+	}
+	if _, known := p.warningCauses[err]; !known {
+		p.warningCauses[err] = cause
+	}
+}
+
+// PackageError is an error recorded against a package, in a more structured
+// form than packages.Error.
+type PackageError struct {
+	packages.Error
+	// Position is the position parsed from Pos.  The filename is empty for
+	// errors that aren't associated with a particular file.
+	Position token.Position
+	// Cause is the error that was passed to AddError to record this error,
+	// if any (errors from loading the package have none).
+	Cause error
+}
+
+// ErrorDetails returns all the errors recorded against this package (as in
+// Errors), sorted by position.
+func (p *Package) ErrorDetails() []PackageError {
+	p.errorsMu.Lock()
+	defer p.errorsMu.Unlock()
+	return errorDetails(p.Errors, p.errorCauses)
+}
+
+// WarningDetails returns all the warnings recorded against this package
+// (with AddWarning), sorted by position.
+func (p *Package) WarningDetails() []PackageError {
+	p.errorsMu.Lock()
+	defer p.errorsMu.Unlock()
+	return errorDetails(p.warnings, p.warningCauses)
+}
+
+// errorDetails sorts the given errors, converting them to PackageErrors
+// with the given causes.
+func errorDetails(errs []packages.Error, causes map[packages.Error]error) []PackageError {
+	errs = sortedErrors(errs)
+	res := make([]PackageError, len(errs))
+	for i, err := range errs {
+		res[i] = PackageError{Error: err, Cause: causes[err]}
+		if err.Pos == "" || err.Pos == "-" || strings.HasSuffix(err.Pos, ":-") {
+			// not associated with a file
+			continue
+		}
+		file, line, col := splitErrorPos(err.Pos)
+		res[i].Position = token.Position{Filename: file, Line: line, Column: col}
+	}
+	return res
+}
+
 // loader loads packages and their imports.  Loaded packages will have
 // type size, imports, and exports file information populated.  Additional
 // information, like ASTs and type-checking information, can be accessed
@@ -202,8 +401,9 @@ type loader struct {
 func (l *loader) packageFor(pkgRaw *packages.Package) *Package {
 	if l.packages[pkgRaw] == nil {
 		l.packages[pkgRaw] = &Package{
-			Package: pkgRaw,
-			loader:  l,
+			Package:    pkgRaw,
+			loader:     l,
+			loadErrors: len(pkgRaw.Errors),
 		}
 	}
 	return l.packages[pkgRaw]
@@ -223,6 +423,15 @@ func (l *loader) packagesFor(pkgsRaw map[string]*packages.Package) map[string]*P
 	return out
 }
 
+// contextErr returns the error of the given config's context, if it has one
+// and it's done.
+func contextErr(cfg *packages.Config) error {
+	if cfg.Context == nil {
+		return nil
+	}
+	return cfg.Context.Err()
+}
+
 // typeCheck type-checks the given package.
 func (l *loader) typeCheck(pkg *Package) {
 	// don't conflict with typeCheckFromExportData
@@ -255,11 +464,11 @@ func (l *loader) typeCheck(pkg *Package) {
 		// but another doesn't (so one wants a "placeholder" package here, and another
 		// wants the full check).
 		//
-		// Thus, we need to lock here (at least for the time being) to avoid
-		// races between the above write to `pkg.Types` and this checking of
-		// importedPkg.Types.
-		importedPkg.Lock()
-		defer importedPkg.Unlock()
+		// Thus, we need to lock here to avoid races between the above write to
+		// `pkg.Types` and this checking of importedPkg.Types.  The import graph
+		// is acyclic, so this can't deadlock.
+		importedPkg.typesMu.Lock()
+		defer importedPkg.typesMu.Unlock()
 
 		if importedPkg.Types != nil && importedPkg.Types.Complete() {
 			return importedPkg.Types, nil
@@ -291,7 +500,10 @@ func (l *loader) typeCheck(pkg *Package) {
 	illTyped := len(errs) > 0
 	if !illTyped {
 		for _, importedPkg := range pkg.Imports() {
-			if importedPkg.IllTyped {
+			importedPkg.typesMu.Lock()
+			importIllTyped := importedPkg.IllTyped
+			importedPkg.typesMu.Unlock()
+			if importIllTyped {
 				illTyped = true
 				break
 			}
@@ -331,7 +543,9 @@ func LoadRoots(roots ...string) ([]*Package, error) {
 // the loader.
 //
 // This is generally only useful for use in testing when you need to modify
-// loading settings to load from a fake location.
+// loading settings to load from a fake location, or to set cfg.Context, in
+// which case loading stops early (returning the context's error) once the
+// context is done.
 //
 // This function will traverse Go module boundaries for roots that are file-
 // system paths and end with "...". Please note this feature currently only
@@ -402,7 +616,14 @@ func LoadRootsWithConfig(cfg *packages.Config, roots ...string) ([]*Package, err
 	// otherwise the package is only returned if the result of
 	// validatePkgFn(pkg.ID) is truthy
 	loadPackages := func(roots ...string) ([]*Package, error) {
+		if err := contextErr(l.cfg); err != nil {
+			return nil, err
+		}
 		rawPkgs, err := packages.Load(l.cfg, roots...)
+		if err := contextErr(l.cfg); err != nil {
+			// report cancellation as such, and not as whatever it caused
+			return nil, err
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -490,6 +711,9 @@ func LoadRootsWithConfig(cfg *packages.Config, roots ...string) ([]*Package, err
 		if e != nil {
 			return e
 		}
+		if err := contextErr(cfg); err != nil {
+			return err
+		}
 		if !d.IsDir() && filepath.Base(p) == "go.mod" {
 			fspRoots = append(fspRoots, filepath.Join(filepath.Dir(p), "..."))
 		}
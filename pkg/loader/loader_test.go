@@ -17,10 +17,13 @@ limitations under the License.
 package loader_test
 
 import (
+	"context"
+	"errors"
 	"os"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
 
 	"sigs.k8s.io/controller-tools/pkg/loader"
 )
@@ -189,3 +192,46 @@ var _ = Describe("Loader parsing root module", func() {
 		})
 	})
 })
+
+var _ = Describe("Loader with a context", func() {
+	It("should stop loading once the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		pkgs, err := loader.LoadRootsWithConfig(&packages.Config{Context: ctx}, "./testmod/...")
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(pkgs).To(BeNil())
+	})
+
+	It("should stop type-checking once the context is canceled, checking again later", func() {
+		pkgs, err := loader.LoadRoots("./testmod/submod1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+
+		checker := &loader.TypeChecker{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(checker.CheckContext(ctx, pkgs[0])).To(MatchError(context.Canceled))
+		Expect(pkgs[0].TypesInfo).To(BeNil())
+
+		Expect(checker.CheckContext(context.Background(), pkgs[0])).To(Succeed())
+		Expect(pkgs[0].TypesInfo).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Package errors", func() {
+	It("should record every error, but only report each one once", func() {
+		pkgs, err := loader.LoadRoots("./testmod/submod1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkgs).To(HaveLen(1))
+
+		pkgs[0].AddError(errors.New("something went wrong"))
+		pkgs[0].AddError(errors.New("something else went wrong"))
+		pkgs[0].AddError(errors.New("something went wrong"))
+		Expect(pkgs[0].Errors).To(HaveLen(3))
+
+		details := pkgs[0].ErrorDetails()
+		Expect(details).To(HaveLen(2))
+		Expect(details[0].Msg).To(Equal("something else went wrong"))
+		Expect(details[1].Msg).To(Equal("something went wrong"))
+	})
+})
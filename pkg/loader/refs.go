@@ -17,6 +17,7 @@ limitations under the License.
 package loader
 
 import (
+	"context"
 	"fmt"
 
 	"go/ast"
@@ -188,7 +189,7 @@ func allReferencedPackages(pkg *Package, filterNodes NodeFilter) []*Package {
 
 // TypeChecker performs type-checking on a limitted subset of packages by
 // checking each package's types' externally-referenced types, and only
-// type-checking those packages.
+// type-checking those packages.  It's safe for concurrent use.
 type TypeChecker struct {
 	// NodeFilters are used to filter the set of references that are followed
 	// when typechecking.  If any of the filters returns true for a given node,
@@ -207,13 +208,17 @@ type TypeChecker struct {
 // Check type-checks the given package and all packages referenced by types
 // that pass through (have true returned by) any of the NodeFilters.
 func (c *TypeChecker) Check(root *Package) {
-	c.init()
+	// can't fail without a context to cancel
+	_ = c.CheckContext(context.Background(), root)
+}
 
-	// use a sub-checker with the appropriate settings
-	(&TypeChecker{
-		NodeFilters:     c.NodeFilters,
-		checkedPackages: c.checkedPackages,
-	}).check(root)
+// CheckContext is like Check, but stops early once the given context is
+// done, returning its error.  Packages that were in the middle of being
+// checked will be checked again by later calls.
+func (c *TypeChecker) CheckContext(ctx context.Context, root *Package) error {
+	c.init()
+	c.check(ctx, root)
+	return ctx.Err()
 }
 
 func (c *TypeChecker) isNodeInteresting(node ast.Node) bool {
@@ -232,6 +237,8 @@ func (c *TypeChecker) isNodeInteresting(node ast.Node) bool {
 }
 
 func (c *TypeChecker) init() {
+	c.Lock()
+	defer c.Unlock()
 	if c.checkedPackages == nil {
 		c.checkedPackages = make(map[*Package]struct{})
 	}
@@ -240,14 +247,14 @@ func (c *TypeChecker) init() {
 // check recursively type-checks the given package, only loading packages that
 // are actually referenced by our types (it's the actual implementation of Check,
 // without initialization).
-func (c *TypeChecker) check(root *Package) {
+func (c *TypeChecker) check(ctx context.Context, root *Package) {
 	root.Lock()
 	defer root.Unlock()
 
 	c.Lock()
 	_, ok := c.checkedPackages[root]
 	c.Unlock()
-	if ok {
+	if ok || ctx.Err() != nil {
 		return
 	}
 
@@ -259,12 +266,16 @@ func (c *TypeChecker) check(root *Package) {
 		wg.Add(1)
 		go func(pkg *Package) {
 			defer wg.Done()
-			c.check(pkg)
+			c.check(ctx, pkg)
 		}(pkg)
 	}
 	wg.Wait()
 
-	// ...then, we can safely type-check ourself
+	// ...then, we can safely type-check ourself (unless we've been
+	// canceled, in which case some of our imports might not be checked)
+	if ctx.Err() != nil {
+		return
+	}
 	root.NeedTypesInfo()
 
 	c.Lock()
@@ -17,8 +17,10 @@ limitations under the License.
 package markers
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
+	"sort"
 	"strings"
 	"sync"
 
@@ -28,13 +30,33 @@ import (
 // Collector collects and parses marker comments defined in the registry
 // from package source code.  If no registry is provided, an empty one will
 // be initialized on the first call to MarkersInPackage.
+//
+// It's safe for concurrent use, and markers for each package are only ever
+// collected once.
+//
+// Marker comments that look like they're meant to be one of the registry's
+// markers, but don't match any of its definitions (see
+// Registry.CheckUnknown), are added to the package as warnings, unless
+// FailOnUnknownMarkers is set, in which case they're errors.  So are uses of
+// markers whose help marks them as deprecated.
 type Collector struct {
 	*Registry
 
-	byPackage map[string]map[ast.Node]MarkerValues
+	// FailOnUnknownMarkers makes unknown (e.g. misspelled) markers errors,
+	// instead of warnings.
+	FailOnUnknownMarkers bool
+
+	byPackage map[string]*packageMarkers
 	mu        sync.Mutex
 }
 
+// packageMarkers holds the result of collecting markers from a package.
+type packageMarkers struct {
+	once    sync.Once
+	markers map[ast.Node]MarkerValues
+	err     error
+}
+
 // MarkerValues are all the values for some set of markers.
 type MarkerValues map[string][]interface{}
 
@@ -53,7 +75,7 @@ func (c *Collector) init() {
 		c.Registry = &Registry{}
 	}
 	if c.byPackage == nil {
-		c.byPackage = make(map[string]map[ast.Node]MarkerValues)
+		c.byPackage = make(map[string]*packageMarkers)
 	}
 }
 
@@ -75,30 +97,47 @@ func (c *Collector) init() {
 func (c *Collector) MarkersInPackage(pkg *loader.Package) (map[ast.Node]MarkerValues, error) {
 	c.mu.Lock()
 	c.init()
-	if markers, exist := c.byPackage[pkg.ID]; exist {
-		c.mu.Unlock()
-		return markers, nil
+	res, exist := c.byPackage[pkg.ID]
+	if !exist {
+		res = &packageMarkers{}
+		c.byPackage[pkg.ID] = res
 	}
-	// unlock early, it's ok if we do a bit extra work rather than locking while we're working
+	// unlock early, so that different packages can be processed concurrently
 	c.mu.Unlock()
 
-	pkg.NeedSyntax()
-	nodeMarkersRaw := c.associatePkgMarkers(pkg)
-	markers, err := c.parseMarkersInPackage(nodeMarkersRaw)
-	if err != nil {
-		return nil, err
+	// concurrent callers for the same package wait for the first one to finish
+	res.once.Do(func() {
+		pkg.NeedSyntax()
+		nodeMarkersRaw := c.associatePkgMarkers(pkg)
+		var warnings []error
+		res.markers, warnings, res.err = c.parseMarkersInPackage(nodeMarkersRaw)
+		for _, warning := range warnings {
+			pkg.AddWarning(warning)
+		}
+	})
+	if res.err != nil {
+		return nil, res.err
 	}
 
+	return res.markers, nil
+}
+
+// Invalidate discards the collected markers for the given packages, so that
+// they'll be collected again (e.g. from updated syntax after calling
+// loader.Invalidate) the next time they're requested.
+func (c *Collector) Invalidate(pkgs ...*loader.Package) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.byPackage[pkg.ID] = markers
-
-	return markers, nil
+	c.init()
+	for _, pkg := range pkgs {
+		delete(c.byPackage, pkg.ID)
+	}
 }
 
-// parseMarkersInPackage parses the given raw marker comments into output values using the registry.
-func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, error) {
-	var errors []error
+// parseMarkersInPackage parses the given raw marker comments into output values using the registry,
+// returning any warnings found along the way (sorted by position).
+func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, []error, error) {
+	var errors, warnings []error
 	nodeMarkerValues := make(map[ast.Node]MarkerValues)
 	for node, markersRaw := range nodeMarkersRaw {
 		var target TargetType
@@ -115,6 +154,13 @@ func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerCo
 			markerText := markerRaw.Text()
 			def := c.Registry.Lookup(markerText, target)
 			if def == nil {
+				if err := c.Registry.CheckUnknown(markerText); err != nil {
+					if c.FailOnUnknownMarkers {
+						errors = append(errors, loader.ErrFromNode(err, markerRaw))
+					} else {
+						warnings = append(warnings, loader.ErrFromNode(err, markerRaw))
+					}
+				}
 				continue
 			}
 			val, err := def.Parse(markerText)
@@ -122,12 +168,27 @@ func (c *Collector) parseMarkersInPackage(nodeMarkersRaw map[ast.Node][]markerCo
 				errors = append(errors, loader.ErrFromNode(err, markerRaw))
 				continue
 			}
+			if help := c.Registry.HelpFor(def); help != nil && help.DeprecatedInFavorOf != nil {
+				warnings = append(warnings, loader.ErrFromNode(deprecatedMarkerError(def.Name, *help.DeprecatedInFavorOf), markerRaw))
+			}
 			markerVals[def.Name] = append(markerVals[def.Name], val)
 		}
 		nodeMarkerValues[node] = markerVals
 	}
 
-	return nodeMarkerValues, loader.MaybeErrList(errors)
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].(loader.PositionedError).Pos < warnings[j].(loader.PositionedError).Pos
+	})
+	return nodeMarkerValues, warnings, loader.MaybeErrList(errors)
+}
+
+// deprecatedMarkerError describes the use of a deprecated marker, and what to
+// use instead (if anything).
+func deprecatedMarkerError(name, inFavorOf string) error {
+	if inFavorOf == "" {
+		return fmt.Errorf("marker %q is deprecated", "+"+name)
+	}
+	return fmt.Errorf("marker %q is deprecated, use %q instead", "+"+name, "+"+inFavorOf)
 }
 
 // associatePkgMarkers associates markers with AST nodes in the given package.
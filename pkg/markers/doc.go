@@ -55,7 +55,8 @@ limitations under the License.
 // first argument is a name override.  If it's left blank (or the tag isn't
 // present), the camelCase version of the name will be used.  The only
 // additional argument defined is `optional`, which marks a field as optional
-// without using a pointer.
+// without using a pointer.  The fields of embedded structs are treated as
+// fields of the outer struct.
 //
 // All parsed values are unmarshalled into the output type.  If any
 // non-optional fields aren't mentioned, an error will be raised unless
@@ -82,6 +83,12 @@ limitations under the License.
 // block" may also be considered package level if registered as such and no
 // identical type-level definition exists.
 //
+// Comments that look like they're meant to be markers from the registry, but
+// don't match any definition (like `+kubebuilder:validation:Maxlength`), are
+// otherwise ignored, so the Collector records them as warnings, suggesting
+// similarly named markers.  Registry.CheckUnknown decides which ones those
+// are.
+//
 // Like loader.Package, Collector's methods are idempotent and will not
 // reperform work.
 //
@@ -764,8 +764,22 @@ func (d *Definition) loadFields() error {
 		return nil
 	}
 
-	for i := 0; i < d.Output.NumField(); i++ {
-		field := d.Output.Field(i)
+	return d.loadStructFields(d.Output)
+}
+
+// loadStructFields populates argument information from the fields of the
+// given struct type, including the ones promoted from embedded structs.
+func (d *Definition) loadStructFields(typ reflect.Type) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// embedded structs contribute their fields as arguments of their
+			// own, which are set through the promoted fields
+			if err := d.loadStructFields(field.Type); err != nil {
+				return err
+			}
+			continue
+		}
 		if field.PkgPath != "" {
 			// as per the reflect package docs, pkgpath is empty for exported fields,
 			// so non-empty package path means a private field, which we should skip
@@ -817,7 +831,7 @@ func (d *Definition) Parse(rawMarker string) (interface{}, error) {
 
 	var errs []error
 	scanner := parserScanner(fields, func(scanner *sc.Scanner, msg string) {
-		errs = append(errs, &ScannerError{Msg: msg, Pos: scanner.Position})
+		errs = append(errs, &ScannerError{Msg: msg, Pos: scanner.Position, Marker: d.Name})
 	})
 
 	// TODO(directxman12): strict parsing where we error out if certain fields aren't optional
@@ -953,9 +967,14 @@ func splitMarker(raw string) (name string, anonymousName string, restFields stri
 	return name, anonymousName, restFields
 }
 
+// ScannerError is an error encountered while parsing a marker.
 type ScannerError struct {
 	Msg string
+	// Pos is the position in the marker's arguments that the error
+	// occurred at.
 	Pos sc.Position
+	// Marker is the name of the marker being parsed.
+	Marker string
 }
 
 func (e *ScannerError) Error() string {
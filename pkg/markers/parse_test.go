@@ -51,6 +51,11 @@ type allOptionalStruct struct {
 	OptInt *int
 }
 
+type embeddingStruct struct {
+	allOptionalStruct
+	Str string
+}
+
 type CustomType struct {
 	Value interface{}
 }
@@ -70,6 +75,7 @@ var _ = Describe("Parsing", func() {
 			mustDefine(reg, "testing:allOptional", DescribesPackage, allOptionalStruct{})
 			mustDefine(reg, "testing:anonymousOptional", DescribesPackage, (*int)(nil))
 			mustDefine(reg, "testing:multi:segment", DescribesPackage, 0)
+			mustDefine(reg, "testing:embedding", DescribesPackage, embeddingStruct{})
 			mustDefine(reg, "testing:parent", DescribesPackage, allOptionalStruct{})
 			mustDefine(reg, "testing:parent:nested", DescribesPackage, "")
 			mustDefine(reg, "testing:tripleDefined", DescribesPackage, 0)
@@ -154,6 +160,12 @@ var _ = Describe("Parsing", func() {
 			It("shouldn't require any arguments to an optional-valued marker", parseTestCase{reg: &reg, raw: "+testing:allOptional", output: allOptionalStruct{}}.Run)
 		})
 
+		It("should treat the fields of embedded structs as fields of their own", parseTestCase{
+			reg:    &reg,
+			raw:    `+testing:embedding:str=some str,optStr="other string"`,
+			output: embeddingStruct{Str: "some str", allOptionalStruct: allOptionalStruct{OptStr: "other string"}},
+		}.Run)
+
 		It("should support markers with multiple segments in the name", parseTestCase{reg: &reg, raw: "+testing:multi:segment=42", output: 42}.Run)
 
 		Context("when dealing with disambiguating anonymous markers", func() {
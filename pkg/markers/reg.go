@@ -123,6 +123,11 @@ func (r *Registry) HelpFor(def *Definition) *DefinitionHelp {
 
 // AllDefinitions returns all marker definitions known to this registry.
 func (r *Registry) AllDefinitions() []*Definition {
+	r.init()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	res := make([]*Definition, 0, len(r.forPkg)+len(r.forType)+len(r.forField))
 	for _, def := range r.forPkg {
 		res = append(res, def)
@@ -0,0 +1,211 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the most suggestions given for an unknown marker.
+const maxSuggestions = 3
+
+// UnknownMarkerError describes a marker comment that looks like it's meant
+// to be one of a registry's markers, but doesn't match any of them.
+type UnknownMarkerError struct {
+	// Marker is the name of the marker, as written.
+	Marker string
+	// Suggestions are the names of similar markers in the registry, closest
+	// first.
+	Suggestions []string
+}
+
+func (e *UnknownMarkerError) Error() string {
+	msg := fmt.Sprintf("unknown marker %q", "+"+e.Marker)
+	if len(e.Suggestions) == 0 {
+		return msg
+	}
+	quoted := make([]string, len(e.Suggestions))
+	for i, suggestion := range e.Suggestions {
+		quoted[i] = fmt.Sprintf("%q", "+"+suggestion)
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", msg, strings.Join(quoted, " or "))
+}
+
+// CheckUnknown checks if the given raw marker (including the leading '+')
+// looks like it's meant to be one of this registry's markers without
+// matching any of them, for any target, returning an *UnknownMarkerError if
+// so, and nil otherwise.
+//
+// Markers are considered to be meant for this registry if their names are
+// close to (e.g. misspellings of, or differ only in case from) the name of a
+// registered marker, or if they share everything but the last part of their
+// name with one, like an unknown `+kubebuilder:validation:Foo`.  Markers for
+// other tools, like `+genclient` or `+k8s:openapi-gen`, are left alone.
+func (r *Registry) CheckUnknown(rawMarker string) error {
+	if len(rawMarker) < 2 || rawMarker[0] != '+' {
+		return nil
+	}
+	for _, target := range []TargetType{DescribesPackage, DescribesType, DescribesField} {
+		if r.Lookup(rawMarker, target) != nil {
+			return nil
+		}
+	}
+	name, anonName, _ := splitMarker(rawMarker)
+	if anonName == "" || strings.ContainsAny(anonName, " \t") {
+		// not actually a marker, just a comment that starts with '+'
+		return nil
+	}
+
+	suggestions, sharesPrefix := r.similarNames(name, anonName)
+	if len(suggestions) == 0 && !sharesPrefix {
+		return nil
+	}
+	return &UnknownMarkerError{Marker: anonName, Suggestions: suggestions}
+}
+
+// similarNames returns the names of registered markers that are close to
+// either of the given names, closest first, and whether any registered
+// marker shares everything but the last part of its name with anonName.
+//
+// For markers that share a prefix with a registered one, un-prefixed
+// markers matching just the last part of the name are suggested too (e.g.
+// `+nullable` for `+kubebuilder:validation:nullable`).
+func (r *Registry) similarNames(name, anonName string) ([]string, bool) {
+	parent := parentName(anonName)
+	// only consider "namespaced" prefixes, so that we don't claim everything
+	// under a prefix like "k8s:", which other tools use too
+	checkParent := strings.Contains(parent, ":")
+	lastPart := strings.ToLower(strings.TrimPrefix(anonName, parent+":"))
+
+	var candidates, lastPartCandidates []nameCandidate
+	seen := make(map[string]struct{})
+	sharesPrefix := false
+	for _, def := range r.AllDefinitions() {
+		if _, done := seen[def.Name]; done {
+			continue
+		}
+		seen[def.Name] = struct{}{}
+		maxDistance := maxDistanceFor(def.Name)
+		defName := strings.ToLower(def.Name)
+
+		if checkParent && parentName(def.Name) == parent {
+			sharesPrefix = true
+		}
+		distance := editDistance(strings.ToLower(anonName), defName)
+		if name != anonName {
+			if nameDistance := editDistance(strings.ToLower(name), defName); nameDistance < distance {
+				distance = nameDistance
+			}
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, nameCandidate{name: def.Name, distance: distance})
+			continue
+		}
+		if checkParent && !strings.Contains(defName, ":") {
+			if distance := editDistance(lastPart, defName); distance <= maxDistance {
+				lastPartCandidates = append(lastPartCandidates, nameCandidate{name: def.Name, distance: distance})
+			}
+		}
+	}
+	if sharesPrefix {
+		candidates = append(candidates, lastPartCandidates...)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	var suggestions []string
+	for _, cand := range candidates {
+		suggestions = append(suggestions, cand.name)
+	}
+	return suggestions, sharesPrefix
+}
+
+// nameCandidate is a possible suggestion for an unknown marker, along with
+// how different its name is from the unknown marker's.
+type nameCandidate struct {
+	name     string
+	distance int
+}
+
+// parentName returns everything but the last part of the given marker name
+// (e.g. `kubebuilder:validation` for `kubebuilder:validation:MaxLength`).
+func parentName(name string) string {
+	lastSep := strings.LastIndex(name, ":")
+	if lastSep < 0 {
+		return ""
+	}
+	return name[:lastSep]
+}
+
+// maxDistanceFor returns how different (in edits, ignoring case) a name can
+// be from the given marker name while still probably being a misspelling of
+// it.  Short names need to be closer, so that they don't match everything.
+func maxDistanceFor(name string) int {
+	switch {
+	case len(name) >= 10:
+		return 2
+	case len(name) >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance computes the Damerau-Levenshtein (optimal string alignment)
+// distance between the two strings: the number of insertions, deletions,
+// substitutions, and transpositions of adjacent bytes needed to turn one
+// into the other.
+func editDistance(a, b string) int {
+	// rows i-2, i-1, and i of the usual dynamic programming table
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = minInt(cur[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
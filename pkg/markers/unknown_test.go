@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markers_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	. "sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+var _ = Describe("Checking for unknown markers", func() {
+	var reg *Registry
+
+	BeforeEach(func() {
+		reg = &Registry{}
+		mustDefine(reg, "kubebuilder:validation:MaxLength", DescribesField, 0)
+		mustDefine(reg, "kubebuilder:validation:MinLength", DescribesField, 0)
+		mustDefine(reg, "kubebuilder:validation:Optional", DescribesField, struct{}{})
+		mustDefine(reg, "kubebuilder:printcolumn", DescribesType, struct{ Name, Type string }{})
+		mustDefine(reg, "k8s:deepcopy-gen", DescribesPackage, "")
+		mustDefine(reg, "groupName", DescribesPackage, "")
+		mustDefine(reg, "nullable", DescribesField, struct{}{})
+	})
+
+	unknown := func(marker string) *UnknownMarkerError {
+		err := reg.CheckUnknown(marker)
+		if err == nil {
+			return nil
+		}
+		var unknownErr *UnknownMarkerError
+		ExpectWithOffset(1, errors.As(err, &unknownErr)).To(BeTrue())
+		return unknownErr
+	}
+
+	It("should accept registered markers, for any target", func() {
+		Expect(reg.CheckUnknown("+kubebuilder:validation:MaxLength=10")).To(Succeed())
+		Expect(reg.CheckUnknown("+groupName=foo.io")).To(Succeed())
+		Expect(reg.CheckUnknown("+kubebuilder:printcolumn:name=Age,type=date")).To(Succeed())
+	})
+
+	It("should leave markers for other tools alone", func() {
+		Expect(reg.CheckUnknown("+genclient")).To(Succeed())
+		Expect(reg.CheckUnknown("+k8s:openapi-gen=true")).To(Succeed())
+		Expect(reg.CheckUnknown("+kubebuilder:scaffold:imports")).To(Succeed())
+		Expect(reg.CheckUnknown("+ not a marker, just a comment")).To(Succeed())
+	})
+
+	It("should suggest markers that differ only in case", func() {
+		Expect(unknown("+kubebuilder:validation:Maxlength=10")).To(Equal(&UnknownMarkerError{
+			Marker:      "kubebuilder:validation:Maxlength",
+			Suggestions: []string{"kubebuilder:validation:MaxLength", "kubebuilder:validation:MinLength"},
+		}))
+		Expect(unknown("+groupname=foo.io").Suggestions).To(Equal([]string{"groupName"}))
+	})
+
+	It("should suggest markers for misspellings", func() {
+		Expect(unknown("+kubebuilder:valdiation:MaxLength=10").Suggestions).To(Equal([]string{"kubebuilder:validation:MaxLength"}))
+		Expect(unknown("+kubebuilder:printcolum:name=Age,type=date").Suggestions).To(Equal([]string{"kubebuilder:printcolumn"}))
+	})
+
+	It("should report unknown markers that share a prefix with registered ones", func() {
+		Expect(unknown("+kubebuilder:validation:Frobnicate")).To(Equal(&UnknownMarkerError{Marker: "kubebuilder:validation:Frobnicate"}))
+		Expect(unknown("+kubebuilder:validation:nullable").Suggestions).To(Equal([]string{"nullable"}))
+	})
+
+	It("should describe the problem and suggestions in its error message", func() {
+		Expect(reg.CheckUnknown("+kubebuilder:validation:Maxlength=10")).To(MatchError(
+			`unknown marker "+kubebuilder:validation:Maxlength" (did you mean "+kubebuilder:validation:MaxLength" or "+kubebuilder:validation:MinLength"?)`))
+		Expect(reg.CheckUnknown("+kubebuilder:validation:Frobnicate")).To(MatchError(`unknown marker "+kubebuilder:validation:Frobnicate"`))
+	})
+})
+
+var _ = Describe("Collecting unknown and deprecated markers", func() {
+	var reg *Registry
+
+	BeforeEach(func() {
+		reg = &Registry{}
+		mustDefine(reg, "testing:pkglevel", DescribesPackage, "")
+		mustDefine(reg, "testing:fieldlvl", DescribesField, "")
+		mustDefine(reg, "testing:eitherlvl", DescribesType, "")
+		typeLvl, err := MakeDefinition("testing:typelvl", DescribesType, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reg.Register(typeLvl)).To(Succeed())
+		reg.AddHelp(typeLvl, DeprecatedHelp("testing:typelevel", "testing", "is a type-level marker."))
+	})
+
+	warningsMatching := func(msg string) []loader.PackageError {
+		var res []loader.PackageError
+		for _, warning := range fakePkg.WarningDetails() {
+			if warning.Msg == msg {
+				res = append(res, warning)
+			}
+		}
+		return res
+	}
+
+	It("should add them to the package as positioned warnings", func() {
+		col := &Collector{Registry: reg}
+		_, err := col.MarkersInPackage(fakePkg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakePkg.Errors).To(HaveLen(0))
+
+		By("checking for unknown markers, with suggestions")
+		unknown := warningsMatching(`unknown marker "+testing:pkglvl" (did you mean "+testing:pkglevel"?)`)
+		Expect(unknown).NotTo(BeEmpty())
+		for _, warning := range unknown {
+			Expect(warning.Position.Line).To(BeNumerically(">", 0))
+			Expect(warning.Cause).To(BeAssignableToTypeOf(loader.PositionedError{}))
+		}
+
+		By("checking for deprecated markers, with replacements")
+		Expect(warningsMatching(`marker "+testing:typelvl" is deprecated, use "+testing:typelevel" instead`)).NotTo(BeEmpty())
+	})
+
+	It("should return unknown markers as errors when asked to fail on them", func() {
+		col := &Collector{Registry: reg, FailOnUnknownMarkers: true}
+		_, err := col.MarkersInPackage(fakePkg)
+		Expect(err).To(MatchError(ContainSubstring(`unknown marker "+testing:pkglvl"`)))
+	})
+})
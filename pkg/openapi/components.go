@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/kube-openapi/pkg/util"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// componentPrefix is the prefix used to link to schemata in the document.
+const componentPrefix = "#/components/schemas/"
+
+// componentName returns the key of the given type in components/schemas,
+// named like the ones in the apiserver's documents (e.g.
+// io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta), which only contain the
+// characters that OpenAPI allows in keys.
+func componentName(typ crdgen.TypeIdent) string {
+	return util.ToRESTFriendlyName(typ.Package.PkgPath + "." + typ.Name)
+}
+
+// componentLink returns a link to the given type in components/schemas.
+func componentLink(typ crdgen.TypeIdent) string {
+	return componentPrefix + componentName(typ)
+}
+
+// schemaCollector collects the (unflattened) schemata for a set of types,
+// along with the ones for every type they reference, linking references
+// between them to their entries in the document.
+type schemaCollector struct {
+	parser   *crdgen.Parser
+	schemata map[crdgen.TypeIdent]apiext.JSONSchemaProps
+}
+
+// need collects the schema for the given type, and for the types it
+// references.
+func (c *schemaCollector) need(typ crdgen.TypeIdent) {
+	if _, known := c.schemata[typ]; known {
+		return
+	}
+	c.parser.NeedSchemaFor(typ)
+	typSchema, known := c.parser.Schemata[typ]
+	if !known {
+		// NeedSchemaFor will have reported why
+		return
+	}
+
+	// avoid tripping recursive types, like JSONSchemaProps, by adding a
+	// placeholder first
+	c.schemata[typ] = apiext.JSONSchemaProps{}
+
+	// the parser's copy is shared with anything else that uses it, so don't
+	// rewrite it in place
+	linked := *typSchema.DeepCopy()
+	linker := &refLinker{pkg: typ.Package}
+	crdgen.EditSchema(&linked, linker)
+	c.schemata[typ] = linked
+
+	for _, ref := range linker.refs {
+		c.need(ref)
+	}
+}
+
+// refLinker rewrites the references in a schema produced by the parser
+// (which are relative to the package of the type it's for) into links to
+// entries in the document, noting the types they refer to.
+type refLinker struct {
+	pkg  *loader.Package
+	refs []crdgen.TypeIdent
+}
+
+func (l *refLinker) Visit(schema *apiext.JSONSchemaProps) crdgen.SchemaVisitor {
+	if schema == nil || schema.Ref == nil || strings.HasPrefix(*schema.Ref, componentPrefix) {
+		return l
+	}
+
+	// OpenAPI v3.0 ignores anything next to a reference, so references with
+	// siblings (like a field's description) are wrapped in an allOf instead,
+	// and linked when that's visited.
+	siblings := *schema
+	siblings.Ref = nil
+	if !reflect.DeepEqual(siblings, apiext.JSONSchemaProps{}) {
+		schema.AllOf = append([]apiext.JSONSchemaProps{{Ref: schema.Ref}}, schema.AllOf...)
+		schema.Ref = nil
+		return l
+	}
+
+	typName, pkgPath, err := crdgen.RefParts(*schema.Ref)
+	if err != nil {
+		l.pkg.AddError(err)
+		return l
+	}
+	typ := crdgen.TypeIdent{Package: l.pkg, Name: typName}
+	if pkgPath != "" {
+		// references to other packages are relative to the current one
+		typ.Package = l.pkg.Imports()[pkgPath]
+		if typ.Package == nil {
+			l.pkg.AddError(fmt.Errorf("unable to find package %s for reference %s", pkgPath, *schema.Ref))
+			return l
+		}
+	}
+
+	link := componentLink(typ)
+	schema.Ref = &link
+	l.refs = append(l.refs, typ)
+	return l
+}
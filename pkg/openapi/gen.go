@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package openapi generates an OpenAPI v3 document containing the schemata
+// for all the types in the roots, for use by aggregated API servers and
+// client generators.
+//
+// Unlike the schemata in CRDs, the schemata in the document aren't
+// flattened: each type gets its own entry in components/schemas, and
+// references between types are kept as $ref links.  Types from other
+// packages (like metav1.ObjectMeta) that are referenced by the types in the
+// roots are included as well.
+//
+// Entries are keyed by the package path and name of their type, with the
+// domain reversed and slashes replaced by dots, like the ones in the OpenAPI
+// documents served by the apiserver (e.g.
+// `io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta`), and linked to as
+// `#/components/schemas/<key>`.
+//
+// Every exported type in the roots that can be serialized gets an entry,
+// except those marked `+kubebuilder:object:generate=false`, which is how
+// helper types that aren't part of the API are usually marked already.
+//
+// Entries for kinds are marked with the group, version, and kind they're
+// for (x-kubernetes-group-version-kind), like the ones in the OpenAPI
+// documents served by the apiserver.
+package openapi
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/deepcopy"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+const (
+	// openAPIVersion is the version of the OpenAPI specification that the
+	// document is written in.
+	openAPIVersion = "3.0.0"
+	// fileName is the name of the document.
+	fileName = "openapi.json"
+	// objectGenerateMarker is the marker that turns off deepcopy generation
+	// for a type, which usually means that it isn't part of the API.
+	objectGenerateMarker = "kubebuilder:object:generate"
+)
+
+// +controllertools:marker:generateHelp
+
+// Generator generates an OpenAPI v3 document with the schemata for all the
+// types in the roots.
+type Generator struct {
+	// Title is the title of the API, for the document's info section.
+	//
+	// Left unspecified, the default is "Kubernetes".
+	Title string `marker:",optional"`
+
+	// Version is the version of the API, for the document's info section.
+	//
+	// Left unspecified, the default is "unversioned".
+	Version string `marker:",optional"`
+
+
+	// ParserOptions are the options of the crd generator that affect the
+	// schemata in the document.
+	crdgen.ParserOptions
+}
+
+var _ genall.Generator = &Generator{}
+
+func (g Generator) RegisterMarkers(into *markers.Registry) error {
+	if err := g.ParserOptions.RegisterMarkers(into); err != nil {
+		return err
+	}
+	// for +kubebuilder:object:generate, which rootTypes honors
+	return deepcopy.Generator{}.RegisterMarkers(into)
+}
+
+// document is an OpenAPI v3 document, with just the parts that we fill in.
+type document struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       info                   `json:"info"`
+	Paths      map[string]interface{} `json:"paths"`
+	Components components             `json:"components"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type components struct {
+	Schemas map[string]interface{} `json:"schemas"`
+}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	parser := g.ParserForRoots(ctx)
+
+	// find the kinds before pulling in other packages, so that we only
+	// consider the ones from the roots
+	kinds := groupVersionKinds(parser, ctx.Roots)
+
+	collector := &schemaCollector{
+		parser:   parser,
+		schemata: make(map[crdgen.TypeIdent]apiext.JSONSchemaProps),
+	}
+	for _, root := range ctx.Roots {
+		for _, typ := range rootTypes(parser, root) {
+			collector.need(typ)
+		}
+	}
+
+	doc := document{
+		OpenAPI: openAPIVersion,
+		Info: info{
+			Title:   g.Title,
+			Version: g.Version,
+		},
+		Paths:      map[string]interface{}{},
+		Components: components{Schemas: make(map[string]interface{}, len(collector.schemata))},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "Kubernetes"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "unversioned"
+	}
+	for typ, typSchema := range collector.schemata {
+		gvk, isKind := kinds[typ]
+		if !isKind {
+			doc.Components.Schemas[componentName(typ)] = typSchema
+			continue
+		}
+		marked, err := withGroupVersionKind(typSchema, gvk)
+		if err != nil {
+			return err
+		}
+		doc.Components.Schemas[componentName(typ)] = marked
+	}
+
+	return ctx.WriteYAML(fileName, []interface{}{doc}, genall.WithFormat(genall.FormatJSON))
+}
+
+// rootTypes returns the exported types in the given root package that can
+// be serialized (i.e. all but interfaces, functions, and channels), and
+// aren't marked +kubebuilder:object:generate=false, sorted by name.
+func rootTypes(parser *crdgen.Parser, root *loader.Package) []crdgen.TypeIdent {
+	root.NeedTypesInfo()
+
+	var res []crdgen.TypeIdent
+	for typ, info := range parser.Types {
+		if typ.Package != root || !ast.IsExported(typ.Name) {
+			continue
+		}
+		if generate, isSet := info.Markers.Get(objectGenerateMarker).(bool); isSet && !generate {
+			continue
+		}
+		obj := root.Types.Scope().Lookup(typ.Name)
+		if obj == nil {
+			continue
+		}
+		switch obj.Type().Underlying().(type) {
+		case *types.Interface, *types.Signature, *types.Chan:
+			continue
+		}
+		res = append(res, typ)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// groupVersionKinds finds the types for all the kinds in the given roots,
+// along with the group-version-kind each is for.
+func groupVersionKinds(parser *crdgen.Parser, roots []*loader.Package) map[crdgen.TypeIdent]schema.GroupVersionKind {
+	res := make(map[crdgen.TypeIdent]schema.GroupVersionKind)
+	for _, groupKind := range crdgen.FindKubeKindsInRoots(parser, roots) {
+		for pkg, gv := range parser.GroupVersions {
+			if gv.Group != groupKind.Group {
+				continue
+			}
+			typ := crdgen.TypeIdent{Package: pkg, Name: groupKind.Kind}
+			if _, known := parser.Types[typ]; known {
+				res[typ] = gv.WithKind(groupKind.Kind)
+			}
+		}
+	}
+	return res
+}
+
+// withGroupVersionKind converts the given schema into its JSON form, marking
+// it with the given group-version-kind.
+func withGroupVersionKind(typSchema apiext.JSONSchemaProps, gvk schema.GroupVersionKind) (map[string]interface{}, error) {
+	raw, err := json.Marshal(typSchema)
+	if err != nil {
+		return nil, err
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	res["x-kubernetes-group-version-kind"] = []map[string]string{{
+		"group":   gvk.Group,
+		"version": gvk.Version,
+		"kind":    gvk.Kind,
+	}}
+	return res, nil
+}
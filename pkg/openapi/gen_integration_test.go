@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/kube-openapi/pkg/spec3"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	. "sigs.k8s.io/controller-tools/pkg/openapi"
+)
+
+var _ = Describe("OpenAPI Document Generation From Parsing to Writing", func() {
+	It("should generate a document with the schemata for all the types in the roots", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var openAPIGen genall.Generator = &Generator{}
+		rt, err := genall.Generators{&openAPIGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+		actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, "openapi.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking that the expected and actual documents are identical")
+		expectedContents, err := ioutil.ReadFile(filepath.Join("expected", "openapi.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actualContents)).To(Equal(string(expectedContents)), "contents not as expected, check pkg/openapi/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(string(actualContents), string(expectedContents)))
+
+		By("checking that the document is valid OpenAPI")
+		var doc spec3.OpenAPI
+		Expect(json.Unmarshal(actualContents, &doc)).To(Succeed())
+		Expect(doc.Version).To(HavePrefix("3.0."))
+		Expect(doc.Info).NotTo(BeNil())
+		Expect(doc.Paths).NotTo(BeNil())
+		Expect(doc.Components).NotTo(BeNil())
+		var refs []string
+		for name, schema := range doc.Components.Schemas {
+			Expect(name).To(MatchRegexp(`^[a-zA-Z0-9\.\-_]+$`), "invalid component key")
+			raw, err := json.Marshal(schema)
+			Expect(err).NotTo(HaveOccurred())
+			refs = append(refs, findRefs(raw)...)
+		}
+		Expect(refs).NotTo(BeEmpty())
+		for _, ref := range refs {
+			Expect(ref).To(HavePrefix("#/components/schemas/"))
+			Expect(doc.Components.Schemas).To(HaveKey(strings.TrimPrefix(ref, "#/components/schemas/")), "dangling reference %s", ref)
+		}
+
+		By("checking that types that aren't part of the API were left out")
+		Expect(doc.Components.Schemas).NotTo(HaveKey("io.kubebuilder.testdata.openapi.apis.v1.WidgetBuilder"))
+	})
+
+	It("should use the given title and version", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var openAPIGen genall.Generator = &Generator{Title: "widgets", Version: "v1"}
+		rt, err := genall.Generators{&openAPIGen}.ForRoots("./apis/...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+		actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, "openapi.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		By("checking the info section of the document")
+		var doc struct {
+			Info map[string]string `json:"info"`
+		}
+		Expect(json.Unmarshal(actualContents, &doc)).To(Succeed())
+		Expect(doc.Info).To(Equal(map[string]string{"title": "widgets", "version": "v1"}))
+	})
+})
+
+// findRefs finds all the references in the given JSON.
+func findRefs(raw json.RawMessage) []string {
+	var node interface{}
+	Expect(json.Unmarshal(raw, &node)).To(Succeed())
+
+	var refs []string
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			for key, val := range node {
+				if ref, isString := val.(string); isString && key == "$ref" {
+					refs = append(refs, ref)
+					continue
+				}
+				walk(val)
+			}
+		case []interface{}:
+			for _, val := range node {
+				walk(val)
+			}
+		}
+	}
+	walk(node)
+	return refs
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"testdata.kubebuilder.io/openapi/apis/common"
+)
+
+// +kubebuilder:object:root=true
+
+// Widget is a kind whose schema ends up in the document, along with the
+// ones for the types it references.
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WidgetSpec   `json:"spec"`
+	Status WidgetStatus `json:"status,omitempty"`
+}
+
+// WidgetSpec is the spec for a Widget.
+type WidgetSpec struct {
+	// Size is the size of the widget.
+	Size Size `json:"size"`
+
+	// Owner refers to the object that owns the widget.
+	// +optional
+	Owner *common.Reference `json:"owner,omitempty"`
+
+	// Parts are the parts the widget is made of.
+	// +optional
+	Parts []Part `json:"parts,omitempty"`
+}
+
+// Size is the size of a widget.
+// +kubebuilder:validation:Enum=small;large
+type Size string
+
+// Part is a part of a widget, which may be made of further parts.
+type Part struct {
+	// Name is the name of the part.
+	Name string `json:"name"`
+
+	// Parts are the parts this part is made of.
+	// +optional
+	Parts []Part `json:"parts,omitempty"`
+}
+
+// WidgetStatus is the status of a Widget.
+type WidgetStatus struct {
+	// LastUpdated is when the widget was last updated.
+	// +optional
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WidgetList contains a list of Widget.
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
+
+// Selector selects widgets by size.  It isn't used by any kind, but is part
+// of the API all the same.
+type Selector struct {
+	// Sizes are the sizes to select.
+	Sizes []Size `json:"sizes"`
+}
+
+// WidgetBuilder builds widgets.  It's a helper for clients, rather than part
+// of the API, and couldn't be serialized anyway.
+// +kubebuilder:object:generate=false
+type WidgetBuilder struct {
+	Build func(Size) Widget
+}
+
+// Sizer is implemented by things that have a size, and isn't serializable.
+type Sizer interface {
+	Size() Size
+}
+
+// sizeOrder isn't exported, so it isn't part of the API.
+type sizeOrder []Size
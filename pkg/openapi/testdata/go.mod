@@ -0,0 +1,5 @@
+module testdata.kubebuilder.io/openapi
+
+go 1.15
+
+require k8s.io/apimachinery v0.19.2
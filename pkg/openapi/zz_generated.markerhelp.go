@@ -0,0 +1,53 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by helpgen. DO NOT EDIT.
+
+package openapi
+
+import (
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+func (Generator) Help() *markers.DefinitionHelp {
+	help := &markers.DefinitionHelp{
+		Category: "",
+		DetailedHelp: markers.DetailedHelp{
+			Summary: "generates an OpenAPI v3 document with the schemata for all the types in the roots.",
+			Details: "",
+		},
+		FieldHelp: map[string]markers.DetailedHelp{
+			"Title": {
+				Summary: "is the title of the API, for the document's info section. ",
+				Details: "Left unspecified, the default is \"Kubernetes\".",
+			},
+			"Version": {
+				Summary: "is the version of the API, for the document's info section. ",
+				Details: "Left unspecified, the default is \"unversioned\".",
+			},
+		},
+	}
+
+	for field, fieldHelp := range (crdgen.ParserOptions{}).Help().FieldHelp {
+		help.FieldHelp[field] = fieldHelp
+	}
+
+	return help
+}
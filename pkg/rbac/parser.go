@@ -149,6 +149,17 @@ func (r *Rule) ToRule() rbacv1.PolicyRule {
 type Generator struct {
 	// RoleName sets the name of the generated ClusterRole.
 	RoleName string
+
+	// Format is the format to write manifests in: yaml (the default), json,
+	// jsonl (compact JSON, one object per line), or list (a JSON v1 List
+	// holding all the objects in a file).
+	Format genall.ManifestFormat `marker:",optional"`
+
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files,
+	// as a YAML comment.
+	HeaderFile string `marker:",optional"`
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
 }
 
 func (Generator) RegisterMarkers(into *markers.Registry) error {
@@ -159,6 +170,9 @@ func (Generator) RegisterMarkers(into *markers.Registry) error {
 	return nil
 }
 
+// DependsOnlyOnMarkers indicates that roles are built purely from package-level markers.
+func (Generator) DependsOnlyOnMarkers() {}
+
 // GenerateRoles generate a slice of objs representing either a ClusterRole or a Role object
 // The order of the objs in the returned slice is stable and determined by their namespaces.
 func GenerateRoles(ctx *genall.GenerationContext, roleName string) ([]interface{}, error) {
@@ -254,6 +268,14 @@ func GenerateRoles(ctx *genall.GenerationContext, roleName string) ([]interface{
 }
 
 func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	if err := g.Format.Validate(); err != nil {
+		return err
+	}
+	header, err := ctx.ReadHeader(g.HeaderFile, g.Year)
+	if err != nil {
+		return err
+	}
+
 	objs, err := GenerateRoles(ctx, g.RoleName)
 	if err != nil {
 		return err
@@ -263,5 +285,5 @@ func (g Generator) Generate(ctx *genall.GenerationContext) error {
 		return nil
 	}
 
-	return ctx.WriteYAML("role.yaml", objs)
+	return ctx.WriteYAML("role.yaml", objs, genall.WithFormat(g.Format), genall.WithHeader(header))
 }
@@ -71,3 +71,42 @@ var _ = Describe("ClusterRole generated by the RBAC Generator", func() {
 		})
 	}
 })
+
+var _ = Describe("The RBAC Generator", func() {
+	It("should write roles in the requested format", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the roots")
+		pkgs, err := loader.LoadRoots(".")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("generating a List of roles")
+		reg := &markers.Registry{}
+		Expect(reg.Register(rbac.RuleDefinition)).To(Succeed())
+		out := &genall.OutputToMemory{}
+		ctx := &genall.GenerationContext{
+			Collector:  &markers.Collector{Registry: reg},
+			Roots:      pkgs,
+			OutputRule: out,
+		}
+		Expect(rbac.Generator{RoleName: "manager-role", Format: genall.FormatList}.Generate(ctx)).To(Succeed())
+
+		By("checking that a JSON List was written")
+		Expect(out.Artifacts()).To(HaveKey("role.json"))
+		var list struct {
+			Kind  string
+			Items []map[string]interface{}
+		}
+		Expect(yaml.Unmarshal(out.Artifacts()["role.json"].Contents, &list)).To(Succeed())
+		Expect(list.Kind).To(Equal("List"))
+		Expect(list.Items).NotTo(BeEmpty())
+		Expect(list.Items[0]).To(HaveKeyWithValue("kind", "ClusterRole"))
+
+		By("refusing unknown formats")
+		Expect(rbac.Generator{Format: "xml"}.Generate(ctx)).To(MatchError(ContainSubstring(`unknown format "xml"`)))
+	})
+})
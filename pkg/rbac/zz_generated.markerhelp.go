@@ -37,6 +37,18 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "sets the name of the generated ClusterRole.",
 				Details: "",
 			},
+			"Format": {
+				Summary: "is the format to write manifests in: yaml (the default), json, jsonl (compact JSON, one object per line), or list (a JSON v1 List holding all the objects in a file).",
+				Details: "",
+			},
+			"HeaderFile": {
+				Summary: "specifies the header text (e.g. license) to prepend to generated files, as a YAML comment.",
+				Details: "",
+			},
+			"Year": {
+				Summary: "specifies the year to substitute for \" YEAR\" in the header file.",
+				Details: "",
+			},
 		},
 	}
 }
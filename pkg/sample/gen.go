@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sample generates sample custom resources for the CRDs generated
+// from Go types.
+//
+// For each served version of each kind, it writes a minimal sample, with
+// just the required fields filled in, to <group>_<version>_<kind>.yaml.
+// Values are taken from defaults (+kubebuilder:default) and enums where
+// possible, and otherwise chosen to respect the formats, patterns and bounds
+// in the schema.  Samples that still don't validate against the schema (e.g.
+// because of a pattern that's too complex to satisfy) are reported as
+// warnings, so that they can be fixed up by hand.
+//
+// It can optionally write a full sample too, with every field filled in and
+// documented with its description, to <group>_<version>_<kind>.full.yaml.
+package sample
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	crdgen "sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+// +controllertools:marker:generateHelp
+
+// Generator generates sample custom resources for each served version of
+// each kind in the roots.
+type Generator struct {
+	// Full additionally writes a sample with every field filled in, and
+	// documented with its description as a comment.
+	//
+	// Left unspecified, the default is false.
+	Full *bool `marker:",optional"`
+
+	// ParserOptions are the options of the crd generator that affect the
+	// schemata that samples are made from.
+	crdgen.ParserOptions
+
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files,
+	// as a YAML comment.
+	HeaderFile string `marker:",optional"`
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
+}
+
+var _ genall.Generator = &Generator{}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	header, err := ctx.ReadHeader(g.HeaderFile, g.Year)
+	if err != nil {
+		return err
+	}
+
+	parser := g.ParserForRoots(ctx)
+	for _, groupKind := range crdgen.FindKubeKindsInRoots(parser, ctx.Roots) {
+		parser.NeedCRDFor(groupKind, nil)
+		crd, wasGenerated := parser.CustomResourceDefinitions[groupKind]
+		if !wasGenerated {
+			continue
+		}
+
+		for _, ver := range crd.Spec.Versions {
+			if !ver.Served {
+				continue
+			}
+			pkg, typeIdent := kindInVersion(parser, groupKind, ver.Name)
+			if pkg == nil {
+				continue
+			}
+			flattened := parser.FlattenedSchemata[typeIdent]
+			gvk := schema.GroupVersionKind{Group: groupKind.Group, Version: ver.Name, Kind: groupKind.Kind}
+			baseName := fmt.Sprintf("%s_%s_%s", crd.Spec.Group, ver.Name, crd.Spec.Names.Singular)
+			node := parser.Types[typeIdent].RawSpec
+
+			minimal := sampleFor(gvk, crd.Spec.Names.Singular, &flattened, false)
+			if err := validateSample(minimal, ver.Schema); err != nil {
+				pkg.AddWarning(loader.ErrFromNode(fmt.Errorf("sample for %s (version %s) does not validate against its schema, and will need to be fixed by hand: %w", groupKind.Kind, ver.Name, err), node))
+			}
+			if err := writeSample(ctx, baseName+".yaml", header, minimal); err != nil {
+				return err
+			}
+
+			if g.Full != nil && *g.Full {
+				full := sampleFor(gvk, crd.Spec.Names.Singular, &flattened, true)
+				if err := writeSample(ctx, baseName+".full.yaml", header, full); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// kindInVersion finds the type for the given kind in the given version,
+// returning it along with the package it's in.
+func kindInVersion(parser *crdgen.Parser, groupKind schema.GroupKind, version string) (*loader.Package, crdgen.TypeIdent) {
+	for pkg, gv := range parser.GroupVersions {
+		if gv.Group != groupKind.Group || gv.Version != version {
+			continue
+		}
+		typeIdent := crdgen.TypeIdent{Package: pkg, Name: groupKind.Kind}
+		if parser.Types[typeIdent] != nil {
+			return pkg, typeIdent
+		}
+	}
+	return nil, crdgen.TypeIdent{}
+}
+
+// writeSample writes the given sample out as a YAML document, using the
+// context's OutputRule.
+func writeSample(ctx *genall.GenerationContext, fileName, header string, sample *yaml.Node) error {
+	out, err := ctx.Open(nil, fileName)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.WriteString(out, genall.YAMLComment(header)+"---\n"); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(out)
+	// yaml.v3 defaults to indent=4, so be compatible with everything else in
+	// k8s and choose 2.
+	enc.SetIndent(2)
+	if err := enc.Encode(sample); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// commentFor turns the given description into a YAML comment.
+func commentFor(description string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return ""
+	}
+	lines := strings.Split(description, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace("# " + strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n")
+}
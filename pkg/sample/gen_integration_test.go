@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sample_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	. "sigs.k8s.io/controller-tools/pkg/sample"
+)
+
+var _ = Describe("Sample Generation From Parsing to Writing", func() {
+	It("should generate minimal and full samples for each kind", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		full := true
+		var sampleGen genall.Generator = &Generator{Full: &full}
+		rt, err := genall.Generators{&sampleGen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		errOut := &bytes.Buffer{}
+		rt.ErrorWriter = errOut
+		rt.DiagnosticsFormat = genall.DiagnosticsJSON
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+		By("checking that only the sample that can't be satisfied was reported")
+		lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+		Expect(lines).To(HaveLen(1))
+		var diag genall.Diagnostic
+		Expect(json.Unmarshal([]byte(lines[0]), &diag)).To(Succeed())
+		Expect(diag.Severity).To(Equal(genall.SeverityWarning))
+		Expect(diag.Message).To(HavePrefix("sample for Gadget (version v1) does not validate against its schema"))
+		Expect(diag.Message).To(ContainSubstring("spec.code in body must be of type email"))
+
+		By("loading the output files")
+		expectedFiles, err := ioutil.ReadDir("expected")
+		Expect(err).NotTo(HaveOccurred())
+		actualFiles, err := ioutil.ReadDir(outputDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualFiles).To(HaveLen(len(expectedFiles)))
+
+		for _, expectedFile := range expectedFiles {
+			By("checking that the expected and actual files for " + expectedFile.Name() + " are identical")
+			actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, expectedFile.Name()))
+			Expect(err).NotTo(HaveOccurred())
+			expectedContents, err := ioutil.ReadFile(filepath.Join("expected", expectedFile.Name()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualContents).To(Equal(expectedContents), "contents not as expected, check pkg/sample/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(string(actualContents), string(expectedContents)))
+		}
+	})
+
+	It("should only generate minimal samples by default", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		By("loading the generation runtime")
+		var sampleGen genall.Generator = &Generator{}
+		rt, err := genall.Generators{&sampleGen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+		By("checking the output files")
+		actualFiles, err := ioutil.ReadDir(outputDir)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, file := range actualFiles {
+			names = append(names, file.Name())
+		}
+		Expect(names).To(ConsistOf("sample.testdata.kubebuilder.io_v1_gadget.yaml", "sample.testdata.kubebuilder.io_v1_widget.yaml"))
+	})
+})
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sample
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// matchingString produces a (short) string that should match the given
+// pattern, returning false if it can't.  Assertions like word boundaries
+// are ignored, so callers should double check the result.
+func matchingString(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	return matching(re)
+}
+
+// matching produces a string that matches the given regular expression,
+// taking the shortest path through it.
+func matching(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary, syntax.OpStar, syntax.OpQuest:
+		return "", true
+	case syntax.OpLiteral:
+		return string(re.Rune), true
+	case syntax.OpCharClass:
+		return charInClass(re.Rune)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return "a", true
+	case syntax.OpCapture, syntax.OpPlus:
+		return matching(re.Sub[0])
+	case syntax.OpRepeat:
+		sub, ok := matching(re.Sub[0])
+		return strings.Repeat(sub, re.Min), ok
+	case syntax.OpConcat:
+		var out strings.Builder
+		for _, sub := range re.Sub {
+			val, ok := matching(sub)
+			if !ok {
+				return "", false
+			}
+			out.WriteString(val)
+		}
+		return out.String(), true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if val, ok := matching(sub); ok {
+				return val, true
+			}
+		}
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+// charInClass picks a character from the given character class (a list of
+// inclusive rune ranges), preferring letters and digits over punctuation.
+func charInClass(ranges []rune) (string, bool) {
+	if len(ranges) == 0 {
+		return "", false
+	}
+	for _, preferred := range "a0A" {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= preferred && preferred <= ranges[i+1] {
+				return string(preferred), true
+			}
+		}
+	}
+	return string(ranges[0]), true
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sample
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kyaml "sigs.k8s.io/yaml"
+)
+
+// formatSamples are sample values for the string formats the apiserver
+// knows about, keyed by their normalized names (without dashes).
+var formatSamples = map[string]string{
+	"bsonobjectid": "507f1f77bcf86cd799439011",
+	"byte":         "ZXhhbXBsZQ==",
+	"cidr":         "192.0.2.0/24",
+	"creditcard":   "4111111111111111",
+	"date":         "2006-01-02",
+	"datetime":     "2006-01-02T15:04:05Z",
+	"duration":     "1h",
+	"email":        "user@example.com",
+	"hexcolor":     "#ffffff",
+	"hostname":     "example.com",
+	"ipv4":         "192.0.2.1",
+	"ipv6":         "2001:db8::1",
+	"isbn":         "0321751043",
+	"isbn10":       "0321751043",
+	"isbn13":       "978-0321751041",
+	"mac":          "00:00:5e:00:53:01",
+	"password":     "example",
+	"rgbcolor":     "rgb(255,255,255)",
+	"ssn":          "123-45-6789",
+	"uri":          "https://example.com",
+	"uuid":         "01234567-89ab-cdef-0123-456789abcdef",
+	"uuid3":        "a3bb189e-8bf9-3888-9912-ace4e6543002",
+	"uuid4":        "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+	"uuid5":        "886313e1-3b8a-5372-9b90-0c9aee199e5d",
+}
+
+// sampleFor produces a sample object of the given kind from the given
+// (flattened) schema for it.  A full sample has every field filled in, with
+// its description as a comment, while a minimal one has only the fields that
+// are required (along with the spec, if any).
+func sampleFor(gvk schema.GroupVersionKind, singular string, root *apiext.JSONSchemaProps, full bool) *yaml.Node {
+	s := sampler{full: full}
+	obj := &yaml.Node{Kind: yaml.MappingNode}
+	s.addField(obj, "apiVersion", "", scalar("!!str", gvk.GroupVersion().String()))
+	s.addField(obj, "kind", "", scalar("!!str", gvk.Kind))
+	metadata := &yaml.Node{Kind: yaml.MappingNode}
+	s.addField(metadata, "name", "", scalar("!!str", singular+"-sample"))
+	s.addField(obj, "metadata", "", metadata)
+
+	required := stringSet(root.Required)
+	for _, name := range sortedKeys(root.Properties) {
+		switch name {
+		case "apiVersion", "kind", "metadata", "status":
+			// already filled in, or not something users set
+			continue
+		}
+		if _, isRequired := required[name]; !isRequired && !full && name != "spec" {
+			continue
+		}
+		prop := root.Properties[name]
+		s.addField(obj, name, prop.Description, s.value(&prop))
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{obj}}
+	if full {
+		doc.HeadComment = commentFor(root.Description)
+	}
+	return doc
+}
+
+// sampler produces sample values for schema nodes.
+type sampler struct {
+	// full indicates that optional fields should be filled in too.
+	full bool
+}
+
+// addField adds the given field to the given mapping node, with the given
+// description as a comment if this is a full sample.
+func (s sampler) addField(obj *yaml.Node, name, description string, val *yaml.Node) {
+	key := scalar("!!str", name)
+	if s.full {
+		key.HeadComment = commentFor(description)
+	}
+	obj.Content = append(obj.Content, key, val)
+}
+
+// value produces a sample value for the given schema node.
+func (s sampler) value(schema *apiext.JSONSchemaProps) *yaml.Node {
+	if schema.Default != nil {
+		return jsonValue(schema.Default)
+	}
+	if len(schema.Enum) > 0 {
+		return jsonValue(&schema.Enum[0])
+	}
+
+	switch {
+	case schema.Type == "object":
+		return s.objectValue(schema)
+	case schema.Type == "array":
+		return s.arrayValue(schema)
+	case schema.Type == "string":
+		return scalar("!!str", stringValue(schema))
+	case schema.Type == "integer" || schema.XIntOrString:
+		return scalar("!!int", strconv.FormatFloat(numberValue(schema, true), 'f', -1, 64))
+	case schema.Type == "number":
+		return scalar("!!float", strconv.FormatFloat(numberValue(schema, false), 'f', -1, 64))
+	case schema.Type == "boolean":
+		return scalar("!!bool", "false")
+	default:
+		// anything goes, so start with nothing
+		return &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+	}
+}
+
+// objectValue produces a sample value for an object (or map) schema node.
+func (s sampler) objectValue(schema *apiext.JSONSchemaProps) *yaml.Node {
+	obj := &yaml.Node{Kind: yaml.MappingNode}
+
+	required := stringSet(schema.Required)
+	for _, name := range sortedKeys(schema.Properties) {
+		if _, isRequired := required[name]; !isRequired && !s.full {
+			continue
+		}
+		prop := schema.Properties[name]
+		s.addField(obj, name, prop.Description, s.value(&prop))
+	}
+
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		for i := 0; i < s.count(schema.MinProperties, schema.MaxProperties); i++ {
+			s.addField(obj, fmt.Sprintf("key%d", i+1), "", s.value(schema.AdditionalProperties.Schema))
+		}
+	}
+
+	if len(obj.Content) == 0 {
+		obj.Style = yaml.FlowStyle
+	}
+	return obj
+}
+
+// arrayValue produces a sample value for an array schema node.
+func (s sampler) arrayValue(schema *apiext.JSONSchemaProps) *yaml.Node {
+	arr := &yaml.Node{Kind: yaml.SequenceNode}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		for i := 0; i < s.count(schema.MinItems, schema.MaxItems); i++ {
+			item := s.value(schema.Items.Schema)
+			if item.Kind == yaml.MappingNode && len(item.Content) > 0 {
+				// a comment on the first field would end up after the dash,
+				// so put it before the item instead
+				item.HeadComment, item.Content[0].HeadComment = item.Content[0].HeadComment, ""
+			}
+			arr.Content = append(arr.Content, item)
+		}
+	}
+	if len(arr.Content) == 0 {
+		arr.Style = yaml.FlowStyle
+	}
+	return arr
+}
+
+// count returns the number of items (or map entries) to put in a sample
+// with the given bounds: as few as possible in a minimal sample, and at least
+// one in a full sample.
+func (s sampler) count(min, max *int64) int {
+	n := 0
+	if min != nil {
+		n = int(*min)
+	}
+	if s.full && n == 0 {
+		n = 1
+	}
+	if max != nil && int64(n) > *max {
+		n = int(*max)
+	}
+	return n
+}
+
+// stringValue produces a sample string that matches the format, pattern and
+// length bounds of the given schema node, as far as possible.
+func stringValue(schema *apiext.JSONSchemaProps) string {
+	var candidates []string
+	if val, known := formatSamples[strings.ReplaceAll(schema.Format, "-", "")]; known {
+		candidates = append(candidates, val)
+	}
+	if schema.Pattern != "" {
+		if val, ok := matchingString(schema.Pattern); ok {
+			candidates = append(candidates, val)
+		}
+	}
+	val := "example"
+	if schema.MinLength != nil && int64(len(val)) < *schema.MinLength {
+		val += strings.Repeat("x", int(*schema.MinLength)-len(val))
+	}
+	if schema.MaxLength != nil && int64(len(val)) > *schema.MaxLength {
+		val = val[:*schema.MaxLength]
+	}
+	candidates = append(candidates, val)
+
+	for _, candidate := range candidates {
+		if matchesString(candidate, schema) {
+			return candidate
+		}
+	}
+	// nothing fits, so go with the most specific
+	return candidates[0]
+}
+
+// matchesString checks that the given string fits the pattern and length
+// bounds of the given schema node.
+func matchesString(val string, schema *apiext.JSONSchemaProps) bool {
+	length := int64(len([]rune(val)))
+	if (schema.MinLength != nil && length < *schema.MinLength) || (schema.MaxLength != nil && length > *schema.MaxLength) {
+		return false
+	}
+	if schema.Pattern == "" {
+		return true
+	}
+	matched, err := regexp.MatchString(schema.Pattern, val)
+	return err == nil && matched
+}
+
+// numberValue produces a sample number that respects the bounds of the given
+// schema node: zero if allowed, otherwise as close to it as possible.
+func numberValue(schema *apiext.JSONSchemaProps, integer bool) float64 {
+	step := 1.0
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		step = *schema.MultipleOf
+	}
+
+	val := 0.0
+	if schema.Minimum != nil && (val < *schema.Minimum || (val == *schema.Minimum && schema.ExclusiveMinimum)) {
+		val = math.Ceil(*schema.Minimum/step) * step
+		if val == *schema.Minimum && schema.ExclusiveMinimum {
+			val += step
+		}
+	}
+	if schema.Maximum != nil && (val > *schema.Maximum || (val == *schema.Maximum && schema.ExclusiveMaximum)) {
+		val = math.Floor(*schema.Maximum/step) * step
+		if val == *schema.Maximum && schema.ExclusiveMaximum {
+			val -= step
+		}
+	}
+	if integer {
+		val = math.Ceil(val)
+	}
+	return val
+}
+
+// validateSample validates the given sample against the given schema, the
+// same way the apiserver validates custom resources (apart from validation
+// rules).
+func validateSample(sample *yaml.Node, validation *apiext.CustomResourceValidation) error {
+	if validation == nil || validation.OpenAPIV3Schema == nil {
+		return nil
+	}
+	internal := &apiextensions.CustomResourceValidation{}
+	if err := apiext.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(validation, internal, nil); err != nil {
+		return err
+	}
+	validator, _, err := apiservervalidation.NewSchemaValidator(internal)
+	if err != nil {
+		return err
+	}
+
+	rawYAML, err := yaml.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	rawJSON, err := kyaml.YAMLToJSON(rawYAML)
+	if err != nil {
+		return err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(rawJSON, &obj); err != nil {
+		return err
+	}
+	return apiservervalidation.ValidateCustomResource(nil, obj, validator).ToAggregate()
+}
+
+// jsonValue converts the given JSON value (like a default) to a YAML node.
+func jsonValue(val *apiext.JSON) *yaml.Node {
+	var parsed interface{}
+	if err := json.Unmarshal(val.Raw, &parsed); err != nil {
+		return scalar("!!str", string(val.Raw))
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(parsed); err != nil {
+		return scalar("!!str", string(val.Raw))
+	}
+	return node
+}
+
+func scalar(tag, val string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: val}
+}
+
+func stringSet(vals []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(vals))
+	for _, val := range vals {
+		res[val] = struct{}{}
+	}
+	return res
+}
+
+func sortedKeys(props map[string]apiext.JSONSchemaProps) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
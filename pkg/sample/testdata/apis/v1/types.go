@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Widget is a kind to generate samples for.
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WidgetSpec   `json:"spec,omitempty"`
+	Status WidgetStatus `json:"status,omitempty"`
+}
+
+// WidgetSpec is the spec for a Widget.
+type WidgetSpec struct {
+	// Size is the size of the widget.
+	// +kubebuilder:validation:Enum=small;medium;large
+	Size string `json:"size"`
+
+	// Replicas is the number of widgets.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	Replicas int32 `json:"replicas"`
+
+	// BatchSize is the number of widgets to update at once.
+	// +kubebuilder:validation:Minimum=3
+	// +kubebuilder:validation:MultipleOf=4
+	BatchSize int32 `json:"batchSize"`
+
+	// Name is the name of the widget.
+	// +kubebuilder:validation:Pattern=^[a-z]([-a-z0-9]*[a-z0-9])?$
+	// +kubebuilder:validation:MinLength=3
+	Name string `json:"name"`
+
+	// Contact is the email address to contact about the widget.
+	// +kubebuilder:validation:Format=email
+	Contact string `json:"contact"`
+
+	// Mode is the mode the widget runs in.
+	// +kubebuilder:default=fast
+	Mode string `json:"mode"`
+
+	// Paused pauses the widget.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Parts are the parts of the widget.
+	// +kubebuilder:validation:MinItems=1
+	Parts []Part `json:"parts"`
+
+	// Labels are extra labels for the widget.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Part is a part of a widget.
+type Part struct {
+	// ID identifies the part.
+	//
+	// It must be unique within the widget.
+	ID string `json:"id"`
+
+	// Weight is the weight of the part, in grams.
+	// +optional
+	Weight int64 `json:"weight,omitempty"`
+}
+
+// WidgetStatus is the status of a Widget.
+type WidgetStatus struct {
+	// Ready indicates that the widget is ready.
+	Ready bool `json:"ready"`
+}
+
+// +kubebuilder:object:root=true
+
+// WidgetList contains a list of Widget.
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+
+// Gadget is a kind whose schema can't be satisfied automatically.
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetSpec `json:"spec"`
+}
+
+// GadgetSpec is the spec for a Gadget.
+type GadgetSpec struct {
+	// Code is a number that's also meant to be an email address.
+	// +kubebuilder:validation:Pattern=^[0-9]+$
+	// +kubebuilder:validation:Format=email
+	Code string `json:"code"`
+}
+
+// +kubebuilder:object:root=true
+
+// GadgetList contains a list of Gadget.
+type GadgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Gadget `json:"items"`
+}
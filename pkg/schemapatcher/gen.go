@@ -17,7 +17,9 @@ limitations under the License.
 package schemapatcher
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 
@@ -75,6 +77,14 @@ type Generator struct {
 
 	// GenerateEmbeddedObjectMeta specifies if any embedded ObjectMeta in the CRD should be generated
 	GenerateEmbeddedObjectMeta *bool `marker:",optional"`
+
+	// HeaderFile specifies the header text (e.g. license) to put at the top of patched files,
+	// as a YAML comment.
+	//
+	// Left unspecified, the comment (if any) already at the top of each file is kept.
+	HeaderFile string `marker:",optional"`
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
 }
 
 var _ genall.Generator = &Generator{}
@@ -88,6 +98,12 @@ func (Generator) RegisterMarkers(into *markers.Registry) error {
 }
 
 func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
+	header, err := ctx.ReadHeader(g.HeaderFile, g.Year)
+	if err != nil {
+		return err
+	}
+	header = genall.YAMLComment(header)
+
 	parser := &crdgen.Parser{
 		Collector: ctx.Collector,
 		Checker:   ctx.Checker,
@@ -197,6 +213,16 @@ func (g Generator) Generate(ctx *genall.GenerationContext) (result error) {
 				}
 				defer outWriter.Close()
 
+				crdHeader := crd.Header
+				if header != "" {
+					crdHeader = header
+				}
+				if crdHeader != "" {
+					if _, err := io.WriteString(outWriter, crdHeader+"---\n"); err != nil {
+						return err
+					}
+				}
+
 				enc := yaml.NewEncoder(outWriter)
 				// yaml.v2 defaults to indent=2, yaml.v3 defaults to indent=4,
 				// so be compatible with everything else in k8s and choose 2.
@@ -241,6 +267,9 @@ type partialCRD struct {
 	// stored in the same file (like controller-tools does by default) or in
 	// different files.
 	FileName string
+	// Header is the comment at the top of the file that this was read from,
+	// if any (like a license), which is written back out along with it.
+	Header string
 
 	// CRDVersion is the version of the CRD object itself, from
 	// apiextensions (currently apiextensions/v1 or apiextensions/v1beta1).
@@ -376,9 +405,11 @@ func crdsFromDirectory(ctx *genall.GenerationContext, dir string) (map[schema.Gr
 			versions[ver.Name] = struct{}{}
 		}
 
-		// then actually unmarshal in a manner that preserves ordering, etc
+		// then actually unmarshal in a manner that preserves ordering, etc,
+		// keeping any header to one side so that it stays at the top
+		header := leadingComment(rawContent)
 		var yamlNodeTree yaml.Node
-		if err := yaml.Unmarshal(rawContent, &yamlNodeTree); err != nil {
+		if err := yaml.Unmarshal(rawContent[len(header):], &yamlNodeTree); err != nil {
 			continue
 		}
 
@@ -396,12 +427,35 @@ func crdsFromDirectory(ctx *genall.GenerationContext, dir string) (map[schema.Gr
 		res[groupKind].CRDVersions = append(res[groupKind].CRDVersions, &partialCRD{
 			Yaml:       &yamlNodeTree,
 			FileName:   fileInfo.Name(),
+			Header:     string(header),
 			CRDVersion: typeMeta.APIVersion,
 		})
 	}
 	return res, nil
 }
 
+// leadingComment returns the comment lines (and any blank lines between them)
+// at the start of the given YAML content, or nothing if it doesn't start with
+// a comment.
+func leadingComment(content []byte) []byte {
+	end := 0
+	for rest := content; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			break
+		}
+		rest = rest[len(line):]
+		if len(trimmed) > 0 {
+			end = len(content) - len(rest)
+		}
+	}
+	return content[:end]
+}
+
 // isSupportedAPIExtGroupVer checks if the given string-form group-version
 // is one of the known apiextensions versions (v1).
 func isSupportedAPIExtGroupVer(groupVer string) bool {
@@ -95,4 +95,41 @@ var _ = Describe("CRD Patching From Parsing to Editing", func() {
 			Expect(actualContents).To(Equal(expectedContents), "contents not as expected, check pkg/schemapatcher/testdata/README.md for more details.\n\nDiff:\n\n%s", cmp.Diff(string(actualContents), string(expectedContents)))
 		}
 	})
+
+	It("should replace the headers of patched files when given a header file", func() {
+		By("switching into testdata to appease go modules")
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir("./testdata")).To(Succeed()) // go modules are directory-sensitive
+		defer func() { Expect(os.Chdir(cwd)).To(Succeed()) }()
+
+		outputDir, err := ioutil.TempDir("", "controller-tools-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+
+		By("writing a header file")
+		headerFile := filepath.Join(outputDir, "boilerplate.go.txt")
+		Expect(ioutil.WriteFile(headerFile, []byte("/*\nCopyright YEAR The Authors.\n*/\n"), 0644)).To(Succeed())
+
+		By("loading the generation runtime")
+		var crdSchemaGen genall.Generator = &Generator{
+			ManifestsPath: "./valid",
+			HeaderFile:    headerFile,
+			Year:          "2022",
+		}
+		rt, err := genall.Generators{&crdSchemaGen}.ForRoots("./...")
+		Expect(err).NotTo(HaveOccurred())
+		rt.OutputRules.Default = genall.OutputToDirectory(outputDir)
+		rt.ErrorWriter = GinkgoWriter
+
+		By("running the generator")
+		Expect(rt.Run()).To(BeFalse(), "unexpectedly had errors")
+
+		By("checking that each file starts with the new header")
+		for _, name := range []string{"kubebuilder-example-crd.v1.yaml", "kubebuilder-unchanged-crd.yaml"} {
+			actualContents, err := ioutil.ReadFile(filepath.Join(outputDir, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actualContents)).To(HavePrefix("# Copyright 2022 The Authors.\n---\napiVersion: apiextensions.k8s.io/v1\n"), name)
+		}
+	})
 })
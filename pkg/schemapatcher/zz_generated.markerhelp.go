@@ -45,6 +45,14 @@ func (Generator) Help() *markers.DefinitionHelp {
 				Summary: "specifies if any embedded ObjectMeta in the CRD should be generated",
 				Details: "",
 			},
+			"HeaderFile": {
+				Summary: "specifies the header text (e.g. license) to put at the top of patched files, as a YAML comment. ",
+				Details: "Left unspecified, the comment (if any) already at the top of each file is kept.",
+			},
+			"Year": {
+				Summary: "specifies the year to substitute for \" YEAR\" in the header file.",
+				Details: "",
+			},
 		},
 	}
 }
@@ -281,7 +281,18 @@ func (c Config) webhookVersions() ([]string, error) {
 // +controllertools:marker:generateHelp
 
 // Generator generates (partial) {Mutating,Validating}WebhookConfiguration objects.
-type Generator struct{}
+type Generator struct {
+	// Format is the format to write manifests in: yaml (the default), json,
+	// jsonl (compact JSON, one object per line), or list (a JSON v1 List
+	// holding all the objects in a file).
+	Format genall.ManifestFormat `marker:",optional"`
+
+	// HeaderFile specifies the header text (e.g. license) to prepend to generated files,
+	// as a YAML comment.
+	HeaderFile string `marker:",optional"`
+	// Year specifies the year to substitute for " YEAR" in the header file.
+	Year string `marker:",optional"`
+}
 
 func (Generator) RegisterMarkers(into *markers.Registry) error {
 	if err := into.Register(ConfigDefinition); err != nil {
@@ -291,7 +302,18 @@ func (Generator) RegisterMarkers(into *markers.Registry) error {
 	return nil
 }
 
-func (Generator) Generate(ctx *genall.GenerationContext) error {
+// DependsOnlyOnMarkers indicates that webhook configurations are built purely from package-level markers.
+func (Generator) DependsOnlyOnMarkers() {}
+
+func (g Generator) Generate(ctx *genall.GenerationContext) error {
+	if err := g.Format.Validate(); err != nil {
+		return err
+	}
+	header, err := ctx.ReadHeader(g.HeaderFile, g.Year)
+	if err != nil {
+		return err
+	}
+
 	supportedWebhookVersions := supportedWebhookVersions()
 	mutatingCfgs := make(map[string][]admissionregv1.MutatingWebhook, len(supportedWebhookVersions))
 	validatingCfgs := make(map[string][]admissionregv1.ValidatingWebhook, len(supportedWebhookVersions))
@@ -389,7 +411,7 @@ func (Generator) Generate(ctx *genall.GenerationContext) error {
 		} else {
 			fileName = fmt.Sprintf("manifests.%s.yaml", k)
 		}
-		if err := ctx.WriteYAML(fileName, v); err != nil {
+		if err := ctx.WriteYAML(fileName, v, genall.WithFormat(g.Format), genall.WithHeader(header)); err != nil {
 			return err
 		}
 	}